@@ -17,7 +17,12 @@
 package commands
 
 import (
+	"strings"
+	"time"
+
 	"github.com/spf13/cobra"
+
+	tvguide "go-tvguide/pkg/tvguide"
 )
 
 var rootCommand = &cobra.Command{
@@ -29,19 +34,148 @@ var rootCommand = &cobra.Command{
 	},
 }
 
+// DatabasePath - path of the database keeping the playlists and tv guides between launches
+var DatabasePath string
+
+// RetentionDays - number of days the ended programmes are kept in the database, negative means forever
+var RetentionDays int
+
+// Vacuum - compact the database after pruning
+var Vacuum bool
+
 // PlaylistPath - path or URL of the playlist
 var PlaylistPath string
 
+// PlaylistFormat - the format of the playlist, detected by its content if it is not set
+var PlaylistFormat string
+
+// GuideFormat - the format of the tv guide, detected by its content if it is not set
+var GuideFormat string
+
+// GuidePath - path or URL of the tv guide overriding the one of the playlist
+var GuidePath string
+
+// Headers - the headers of the HTTP requests in the form "Name: value"
+var Headers []string
+
+// Keys - the keys of the viewer actions, e.g. quit=Ctrl+X
+var Keys map[string]string
+
+// StrictGuide - stop reading of the tv guide on the first malformed element
+var StrictGuide bool
+
+// AllChannels - read the tv guide for all channels, not only for the playlist ones
+var AllChannels bool
+
+// DaysBack - number of past days of the tv guide to read
+var DaysBack int
+
+// DaysAhead - number of future days of the tv guide to read
+var DaysAhead int
+
+// GuideLanguages - preferred languages of the tv guide texts in the order of preference
+var GuideLanguages string
+
+// FillStop - fill the missing stop times of the programmes from the start of the next ones
+var FillStop bool
+
+// OverlapRepair - the repair of the overlapping programmes: keep, trim or split
+var OverlapRepair string
+
+// DropDuplicates - drop the programmes of the channel with the same start time
+var DropDuplicates bool
+
+// MaxDuration - the longer programmes are cut to this duration, zero means no limit
+var MaxDuration time.Duration
+
+// PersonRoles - credit roles of the person to search for
+var PersonRoles []string
+
+// ReportThreshold - the gaps and overlaps of the tv guide not longer than the threshold are not reported
+var ReportThreshold time.Duration
+
+// addGuideFlags adds the flags of the playlist and tv guide loading to the command
+func addGuideFlags(cmd *cobra.Command) {
+
+	cmd.Flags().StringVarP(&PlaylistPath, "playlist", "p", "", "path or URL of the playlist (required)")
+	cmd.MarkFlagRequired("playlist")
+	cmd.Flags().StringVar(&GuidePath, "guide", "", "path or URL of the tv guide (default - the one of the playlist)")
+	cmd.Flags().StringArrayVar(&Headers, "header", nil, `header of the HTTP requests, e.g. "User-Agent: Kodi/18.0"`)
+	cmd.Flags().StringVar(&PlaylistFormat, "playlist-format", "", "format of the playlist: "+strings.Join(tvguide.PlaylistFormats(), ", ")+" (default - detected by the content)")
+	cmd.Flags().StringVar(&GuideFormat, "guide-format", "", "format of the tv guide: "+strings.Join(tvguide.GuideFormats(), ", ")+" (default - detected by the content)")
+	cmd.Flags().BoolVar(&StrictGuide, "strict", false, "fail on malformed or truncated tv guide instead of skipping bad elements")
+	cmd.Flags().BoolVar(&AllChannels, "all-channels", false, "read the tv guide for all channels, not only for the playlist ones")
+	cmd.Flags().IntVar(&DaysBack, "days-back", -1, "number of past days of the tv guide to read (-1 - no limit)")
+	cmd.Flags().IntVar(&DaysAhead, "days-ahead", -1, "number of future days of the tv guide to read (-1 - no limit)")
+	cmd.Flags().StringVar(&GuideLanguages, "lang", "", `preferred languages of the tv guide texts in the order of preference, e.g. ru,en,"" (default - the most common language of the guide)`)
+	cmd.Flags().BoolVar(&FillStop, "fill-stop", false, "fill the missing stop times of the programmes from the start of the next ones")
+	cmd.Flags().StringVar(&OverlapRepair, "overlaps", "keep", "repair of the overlapping programmes: keep, trim or split")
+	cmd.Flags().BoolVar(&DropDuplicates, "drop-duplicates", false, "drop the programmes of the channel with the same start time")
+	cmd.Flags().DurationVar(&MaxDuration, "max-duration", 0, "cut the programmes longer than the duration (0 - no limit)")
+	cmd.Flags().StringVar(&Stats, "stats", "", "print the counters of the playlist and tv guide loading: text or json")
+	cmd.Flags().Lookup("stats").NoOptDefVal = "text"
+}
+
 func init() {
 
-	cmdView.Flags().StringVarP(&PlaylistPath, "playlist", "p", "", "path or URL of the playlist (required)")
-	cmdView.MarkFlagRequired("playlist")
+	rootCommand.PersistentFlags().StringVar(&DatabasePath, "database", "", "path of the database to keep playlists and tv guides between launches")
+	rootCommand.PersistentFlags().IntVar(&RetentionDays, "retention-days", -1, "number of days the ended programmes are kept in the database (-1 - forever)")
+	rootCommand.PersistentFlags().StringVar(&LogLevel, "log-level", "warn", "minimal level of the logged records: debug, info, warn or error")
+	rootCommand.PersistentFlags().StringVar(&LogFile, "log-file", "", "path of the log (default - the standard error, the view command does not log without the file)")
+	rootCommand.PersistentFlags().StringVar(&ConfigPath, "config", "", "path of the configuration file (default $XDG_CONFIG_HOME/tvguide/config.yaml)")
+	rootCommand.PersistentFlags().StringVar(&Profile, "profile", "", "profile of the configuration file (default - the profile set by the file)")
+	rootCommand.PersistentFlags().StringVarP(&Output, "output", "o", "text", "format of the results: "+strings.Join(outputFormats, ", "))
+	rootCommand.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+
+		if err := applyConfig(cmd); err != nil {
+			return err
+		}
+
+		if err := setupOutput(); err != nil {
+			return err
+		}
+
+		return setupLogging(cmd)
+	}
+
+	addGuideFlags(cmdView)
+	addGuideFlags(cmdPerson)
+	addGuideFlags(cmdGuideReport)
+	addGuideFlags(cmdNow)
+
+	cmdView.Flags().StringToStringVar(&Keys, "key", nil, "keys of the viewer actions, e.g. quit=Ctrl+X,help=h")
 
-	rootCommand.AddCommand(cmdView, cmdVersion)
+	cmdPerson.Flags().StringSliceVar(&PersonRoles, "role", nil,
+		"credit roles of the person: "+strings.Join(tvguide.CreditRoles, ", ")+" (default - any role)")
+
+	cmdGuideReport.Flags().DurationVar(&ReportThreshold, "threshold", 5*time.Minute, "minimal reported gap or overlap of the programmes")
+
+	cmdNow.Flags().StringVar(&NowGroup, "group", "", "print the channels of the group only")
+	cmdNow.Flags().StringVar(&NowChannel, "channel", "", "print the channel with the identifier or a part of the name only")
+	cmdNow.Flags().StringVar(&NowAt, "at", "", `print the programmes at the time, e.g. "2018-10-20 21:00" or "21:00" (default - now)`)
+
+	cmdDBPrune.Flags().BoolVar(&Vacuum, "vacuum", false, "compact the database after pruning")
+	cmdDB.AddCommand(cmdDBPrune)
+	cmdConfig.AddCommand(cmdConfigShow)
+
+	rootCommand.AddCommand(cmdView, cmdNow, cmdPerson, cmdGuideReport, cmdDB, cmdConfig, cmdVersion)
 }
 
-// Execute is a enter point into application commands
+// Execute is a enter point into application commands. The errors of the command line are
+// reported as the usage errors, see ExitCode
 func Execute() error {
-	err := rootCommand.Execute()
-	return err
+
+	markStarted(rootCommand)
+	defer closeLogging()
+
+	if err := rootCommand.Execute(); err != nil {
+
+		if !started {
+			return &usageError{err}
+		}
+
+		return err
+	}
+
+	return nil
 }
@@ -0,0 +1,409 @@
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package commands
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v2"
+)
+
+// ConfigPath - path of the configuration file, $XDG_CONFIG_HOME/tvguide/config.yaml by default
+var ConfigPath string
+
+// Profile - name of the profile of the configuration file, the profile of the file by default
+var Profile string
+
+// envPrefix - the prefix of the environment variables of the settings, e.g. TVGUIDE_PLAYLIST
+const envPrefix = "TVGUIDE_"
+
+// config - the content of the configuration file. The settings are named as the flags, the profile
+// settings override the common ones
+type config struct {
+	Profile  string                            `yaml:"profile"`
+	Settings map[string]interface{}            `yaml:"settings"`
+	Profiles map[string]map[string]interface{} `yaml:"profiles"`
+}
+
+// setting - the value of the flag and where it comes from
+type setting struct {
+	values []string
+	source string
+}
+
+// applied - the settings of the flags set by applyConfig
+var applied = make(map[string]setting)
+
+var cmdConfig = &cobra.Command{
+	Use:   "config",
+	Short: "Configuration",
+	Long:  "Configuration file with the profiles of the settings",
+}
+
+var cmdConfigShow = &cobra.Command{
+	Use:   "show",
+	Short: "Printing effective settings",
+	Long: "Printing the settings of the selected profile and where they come from: the flags, the environment (" +
+		envPrefix + "*), the profile or the defaults",
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		path, cfg, err := loadConfig()
+
+		if err != nil {
+			return err
+		}
+
+		name, settings, err := profileSettings(cfg)
+
+		if err != nil {
+			return err
+		}
+
+		flags := allFlags()
+		names := make([]string, 0, len(flags))
+
+		for n := range flags {
+			names = append(names, n)
+		}
+
+		sort.Strings(names)
+
+		effective := &effectiveConfig{Config: path, Found: cfg != nil, Profile: name,
+			Settings: make([]*effectiveSetting, 0, len(names))}
+
+		for _, n := range names {
+
+			f := cmd.Flags().Lookup(n)
+
+			if f == nil {
+				f = flags[n]
+			}
+
+			s := resolveSetting(f, settings)
+			value := strings.Join(s.values, ",")
+
+			if s.source == "flag" || s.source == "default" {
+				value = f.Value.String()
+			}
+
+			effective.Settings = append(effective.Settings, &effectiveSetting{Name: n, Value: value, Source: s.source})
+		}
+
+		return printResult(os.Stdout, effective.result())
+	},
+}
+
+// effectiveConfig - the effective settings printed by config show
+type effectiveConfig struct {
+	Config   string              `json:"config"`
+	Found    bool                `json:"found"`
+	Profile  string              `json:"profile"`
+	Settings []*effectiveSetting `json:"settings"`
+}
+
+// effectiveSetting - the value of the setting and where it comes from: flag, the environment variable,
+// profile NAME, config or default
+type effectiveSetting struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// result returns the effective settings as the command result, the records of ndjson and csv are
+// the settings
+func (c *effectiveConfig) result() *result {
+
+	r := &result{value: c, header: []string{"name", "value", "source"}}
+
+	for _, s := range c.Settings {
+		r.items = append(r.items, s)
+		r.rows = append(r.rows, []string{s.Name, s.Value, s.Source})
+	}
+
+	r.text = func(w io.Writer) {
+
+		path := c.Config
+
+		if !c.Found {
+			path += " (not found)"
+		}
+
+		fmt.Fprintf(w, "config: %s\nprofile: %s\n", path, c.Profile)
+
+		for _, s := range c.Settings {
+			fmt.Fprintf(w, "%s = %s (%s)\n", s.Name, s.Value, s.Source)
+		}
+	}
+
+	return r
+}
+
+// defaultConfigPath returns the path of the configuration file in the user configuration directory
+func defaultConfigPath() string {
+
+	dir, err := os.UserConfigDir()
+
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(dir, "tvguide", "config.yaml")
+}
+
+// loadConfig reads the configuration file specified by the flag, the environment or the default one.
+// The missing default file is not an error, the configuration is nil then
+func loadConfig() (path string, cfg *config, err error) {
+
+	path, explicit := ConfigPath, ConfigPath != ""
+
+	if !explicit {
+		path, explicit = os.LookupEnv(envPrefix + "CONFIG")
+	}
+
+	if !explicit {
+		path = defaultConfigPath()
+	}
+
+	if path == "" {
+		return
+	}
+
+	data, err := ioutil.ReadFile(path)
+
+	if err != nil {
+
+		if os.IsNotExist(err) && !explicit {
+			err = nil
+		}
+
+		return
+	}
+
+	cfg = &config{}
+
+	if err = yaml.UnmarshalStrict(data, cfg); err != nil {
+		return path, nil, &usageError{fmt.Errorf("%s: %v", path, err)}
+	}
+
+	return
+}
+
+// profileSettings returns the name of the selected profile and its settings merged with the common ones.
+// The common settings come from "config", the ones of the profile from "profile <name>"
+func profileSettings(cfg *config) (name string, settings map[string]setting, err error) {
+
+	name = Profile
+
+	if name == "" {
+		name = os.Getenv(envPrefix + "PROFILE")
+	}
+
+	if name == "" && cfg != nil {
+		name = cfg.Profile
+	}
+
+	settings = make(map[string]setting)
+
+	if cfg == nil {
+
+		if name != "" {
+			err = &usageError{fmt.Errorf("unknown profile %q, the configuration file is not found", name)}
+		}
+
+		return
+	}
+
+	for n, v := range cfg.Settings {
+		settings[n] = setting{values: settingValues(v), source: "config"}
+	}
+
+	if name != "" {
+
+		profile, ok := cfg.Profiles[name]
+
+		if !ok {
+			return name, nil, &usageError{fmt.Errorf("unknown profile %q", name)}
+		}
+
+		for n, v := range profile {
+			settings[n] = setting{values: settingValues(v), source: "profile " + name}
+		}
+	}
+
+	flags := allFlags()
+
+	for n := range settings {
+		if _, ok := flags[n]; !ok {
+			return name, nil, &usageError{fmt.Errorf("unknown setting %q of the profile %q", n, name)}
+		}
+	}
+
+	return
+}
+
+// applyConfig sets the flags of the command not specified on the command line from the environment
+// or the selected profile of the configuration file
+func applyConfig(cmd *cobra.Command) (err error) {
+
+	_, cfg, err := loadConfig()
+
+	if err != nil {
+		return
+	}
+
+	_, settings, err := profileSettings(cfg)
+
+	if err != nil {
+		return
+	}
+
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+
+		s := resolveSetting(f, settings)
+
+		if err != nil || s.source == "flag" || s.source == "default" {
+			return
+		}
+
+		values := s.values
+
+		if !isMultiValue(f) {
+			values = []string{strings.Join(values, ",")}
+		}
+
+		for _, v := range values {
+			if err = f.Value.Set(v); err != nil {
+				err = &usageError{fmt.Errorf("%s: invalid value %q of %s: %v", s.source, v, f.Name, err)}
+				return
+			}
+		}
+
+		f.Changed = true
+		applied[f.Name] = s
+	})
+
+	return
+}
+
+// resolveSetting returns the value of the flag in the order of precedence: the command line,
+// the environment, the profile and the default
+func resolveSetting(f *pflag.Flag, settings map[string]setting) setting {
+
+	if s, ok := applied[f.Name]; ok {
+		return s
+	}
+
+	if f.Changed {
+		return setting{source: "flag"}
+	}
+
+	env := envPrefix + strings.ToUpper(strings.Replace(f.Name, "-", "_", -1))
+
+	if v, ok := os.LookupEnv(env); ok {
+		return setting{values: []string{v}, source: env}
+	}
+
+	if s, ok := settings[f.Name]; ok && !isConfigFlag(f.Name) {
+		return s
+	}
+
+	return setting{source: "default"}
+}
+
+// settingValues returns the values of the setting the flag is set by: the items of the list,
+// the key=value pairs of the map or the single value
+func settingValues(v interface{}) []string {
+
+	switch value := v.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+
+		values := make([]string, 0, len(value))
+
+		for _, item := range value {
+			values = append(values, fmt.Sprint(item))
+		}
+
+		return values
+
+	case map[interface{}]interface{}:
+
+		values := make([]string, 0, len(value))
+
+		for k, item := range value {
+			values = append(values, fmt.Sprintf("%v=%v", k, item))
+		}
+
+		sort.Strings(values)
+
+		return values
+	}
+
+	return []string{fmt.Sprint(v)}
+}
+
+// isMultiValue tells whether the flag accepts several values: the list or the map. The list
+// of the single value flag is joined by commas
+func isMultiValue(f *pflag.Flag) bool {
+
+	t := f.Value.Type()
+
+	return strings.HasSuffix(t, "Slice") || strings.HasSuffix(t, "Array") || strings.HasPrefix(t, "stringTo")
+}
+
+// isConfigFlag tells whether the flag selects the configuration and cannot be set by it
+func isConfigFlag(name string) bool {
+	return name == "config" || name == "profile" || name == "help"
+}
+
+// allFlags returns the flags of all commands by their names except the configuration ones
+func allFlags() map[string]*pflag.Flag {
+
+	flags := make(map[string]*pflag.Flag)
+
+	var visit func(cmd *cobra.Command)
+
+	visit = func(cmd *cobra.Command) {
+
+		add := func(f *pflag.Flag) {
+			if !isConfigFlag(f.Name) {
+				flags[f.Name] = f
+			}
+		}
+
+		cmd.PersistentFlags().VisitAll(add)
+		cmd.Flags().VisitAll(add)
+
+		for _, c := range cmd.Commands() {
+			visit(c)
+		}
+	}
+
+	visit(rootCommand)
+
+	return flags
+}
@@ -0,0 +1,92 @@
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	tvguide "go-tvguide/pkg/tvguide"
+)
+
+var cmdDB = &cobra.Command{
+	Use:   "db",
+	Short: "Database maintenance",
+	Long:  "Maintenance of the database keeping the playlists and tv guides between launches",
+}
+
+var cmdDBPrune = &cobra.Command{
+	Use:   "prune",
+	Short: "Deleting expired programmes",
+	Long:  "Deleting the programmes ended more than the retention days ago and the playlists, channels and programmes of the sources not loaded since then",
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		if RetentionDays < 0 {
+			return &usageError{errors.New("db prune: the retention is not set, use --retention-days")}
+		}
+
+		store, err := openStore()
+
+		if err != nil {
+			return err
+		}
+
+		defer store.Close()
+
+		report, err := store.Prune(RetentionDays, time.Now(), Vacuum)
+
+		if err != nil {
+			return err
+		}
+
+		return printResult(os.Stdout, pruneResult(report))
+	},
+}
+
+// pruneResult returns the numbers of the deleted programmes and channels as the command result
+func pruneResult(report *tvguide.PruneReport) *result {
+
+	return &result{value: report, items: []interface{}{report},
+		header: []string{"programmes", "channels"},
+		rows:   [][]string{{strconv.FormatInt(report.Programmes, 10), strconv.FormatInt(report.Channels, 10)}},
+		text:   func(w io.Writer) { printPruneReport(w, report) }}
+}
+
+// pruneDatabase deletes the programmes expired according to the retention setting
+func pruneDatabase(store *tvguide.Store, vacuum bool) error {
+
+	report, err := store.Prune(RetentionDays, time.Now(), vacuum)
+
+	if err != nil {
+		return err
+	}
+
+	printPruneReport(console, report)
+
+	return nil
+}
+
+func printPruneReport(w io.Writer, report *tvguide.PruneReport) {
+	fmt.Fprintf(w, "Pruned programmes: %d, channels: %d\n", report.Programmes, report.Channels)
+}
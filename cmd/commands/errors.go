@@ -0,0 +1,110 @@
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package commands
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	tvguide "go-tvguide/pkg/tvguide"
+)
+
+// exit codes of the application
+const (
+	// ExitFailure - the command has failed
+	ExitFailure = 1
+	// ExitUsage - the command line is invalid
+	ExitUsage = 2
+	// ExitNotFound - the playlist, the tv guide or the requested item does not exist
+	ExitNotFound = 3
+	// ExitNetwork - the playlist or the tv guide cannot be downloaded
+	ExitNetwork = 4
+	// ExitFormat - the playlist or the tv guide cannot be parsed
+	ExitFormat = 5
+	// ExitDatabase - the database cannot be used
+	ExitDatabase = 6
+)
+
+// usageError - the error of the command line
+type usageError struct {
+	err error
+}
+
+func (e *usageError) Error() string {
+	return e.err.Error()
+}
+
+func (e *usageError) Unwrap() error {
+	return e.err
+}
+
+// started is set when the command starts, the errors occurred before are the errors of the command line
+var started bool
+
+// markStarted wraps the commands to tell the errors of the command line from the errors of the commands.
+// The usage is not printed on the errors of the started commands
+func markStarted(cmd *cobra.Command) {
+
+	if run := cmd.RunE; run != nil {
+
+		cmd.RunE = func(c *cobra.Command, args []string) error {
+
+			started = true
+			c.SilenceUsage = true
+
+			return run(c, args)
+		}
+	}
+
+	for _, c := range cmd.Commands() {
+		markStarted(c)
+	}
+}
+
+// ExitCode returns the exit code of the application for the error of the command
+func ExitCode(err error) int {
+
+	var (
+		usage      *usageError
+		httpStatus *tvguide.HTTPStatusError
+		urlError   *url.Error
+		netError   net.Error
+		parseError *tvguide.ParseError
+	)
+
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &usage) || errors.Is(err, tvguide.ErrInvalidArgument):
+		return ExitUsage
+	case errors.Is(err, tvguide.ErrNotFound) || errors.Is(err, tvguide.ErrInvalidPath) || errors.Is(err, os.ErrNotExist):
+		return ExitNotFound
+	case errors.As(err, &httpStatus) || errors.As(err, &urlError) || errors.As(err, &netError):
+		return ExitNetwork
+	case errors.Is(err, tvguide.ErrUnknownFormat) || errors.As(err, &parseError):
+		return ExitFormat
+	case errors.Is(err, tvguide.ErrSchemaTooNew) || errors.Is(err, tvguide.ErrNoSQLite) ||
+		errors.Is(err, tvguide.ErrSearchUnavailable):
+		return ExitDatabase
+	}
+
+	return ExitFailure
+}
@@ -0,0 +1,124 @@
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	tvguide "go-tvguide/pkg/tvguide"
+)
+
+var cmdGuideReport = &cobra.Command{
+	Use:   "guide-report",
+	Short: "Checking TV guide",
+	Long:  "Reporting the coverage of the TV guide, gaps, overlaps and incomplete programmes of the channels",
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		store, err := openStore()
+
+		if err != nil {
+			return err
+		}
+
+		defer store.Close()
+
+		_, guide, err := loadPlaylistAndGuide(store)
+
+		if err != nil {
+			return err
+		}
+
+		report, err := guide.Report(ReportThreshold)
+
+		if err != nil {
+			return err
+		}
+
+		return printResult(os.Stdout, guideReportResult(report))
+	},
+}
+
+// guideReportResult returns the tv guide report as the command result, the records of ndjson and csv
+// are the channel reports
+func guideReportResult(report *tvguide.GuideReport) *result {
+
+	r := &result{value: report, text: func(w io.Writer) { printGuideReport(w, report) },
+		header: []string{"channel_id", "name", "programmes", "from", "to", "gaps", "overlaps", "zero_length",
+			"untitled", "no_stop"}}
+
+	for _, c := range report.Channels {
+		r.items = append(r.items, c)
+		r.rows = append(r.rows, []string{c.ChannelID, c.Name, strconv.Itoa(c.Programmes), formatTime(c.From),
+			formatTime(c.To), strconv.Itoa(len(c.Gaps)), strconv.Itoa(len(c.Overlaps)), strconv.Itoa(len(c.ZeroLength)),
+			strconv.Itoa(len(c.Untitled)), strconv.Itoa(len(c.NoStop))})
+	}
+
+	return r
+}
+
+const reportTimeFormat = "2006-01-02 15:04"
+
+func printGuideReport(w io.Writer, report *tvguide.GuideReport) {
+
+	for _, c := range report.Channels {
+
+		if c.Programmes == 0 {
+			fmt.Fprintf(w, "%s (%s): no programmes\n", c.Name, c.ChannelID)
+			continue
+		}
+
+		fmt.Fprintf(w, "%s (%s): %s - %s, programmes: %d, issues: %d\n", c.Name, c.ChannelID,
+			c.From.Format(reportTimeFormat), c.To.Format(reportTimeFormat), c.Programmes, c.Issues())
+
+		for _, i := range c.Gaps {
+			fmt.Fprintf(w, "  gap %s - %s (%v)\n", i.Start.Format(reportTimeFormat), i.Stop.Format(reportTimeFormat),
+				i.Duration())
+		}
+
+		for _, i := range c.Overlaps {
+			fmt.Fprintf(w, "  overlap %s - %s (%v)\n", i.Start.Format(reportTimeFormat),
+				i.Stop.Format(reportTimeFormat), i.Duration())
+		}
+
+		printReportProgrammes(w, "zero length", c.ZeroLength)
+		printReportProgrammes(w, "no title", c.Untitled)
+		printReportProgrammes(w, "no stop time", c.NoStop)
+	}
+
+	if report.PlaylistChannels > 0 {
+
+		fmt.Fprintf(w, "Playlist channels without tv guide: %d of %d\n", len(report.MissingChannels),
+			report.PlaylistChannels)
+
+		for _, id := range report.MissingChannels {
+			fmt.Fprintf(w, "  %s\n", id)
+		}
+	}
+}
+
+func printReportProgrammes(w io.Writer, issue string, programmes []*tvguide.ReportProgramme) {
+
+	for _, p := range programmes {
+		fmt.Fprintf(w, "  %s %s %s\n", issue, p.Start.Format(reportTimeFormat), p.Title)
+	}
+}
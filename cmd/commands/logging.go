@@ -0,0 +1,159 @@
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	tvguide "go-tvguide/pkg/tvguide"
+)
+
+// LogLevel - the minimal level of the logged records: debug, info, warn or error
+var LogLevel string
+
+// LogFile - path of the log, the log is written to the standard error if it is not set. The view
+// command writes the log to the file only, not to corrupt the screen
+var LogFile string
+
+// Stats - print the counters of the playlist and tv guide loading: text or json
+var Stats string
+
+// logger - the log of the application
+var logger = slog.New(slog.DiscardHandler)
+
+// logFile - the file of the log, if opened
+var logFile *os.File
+
+// setupLogging creates the log of the command according to the flags
+func setupLogging(cmd *cobra.Command) (err error) {
+
+	var level slog.Level
+
+	if err = level.UnmarshalText([]byte(LogLevel)); err != nil {
+		return fmt.Errorf("invalid log level %q, expected one of debug, info, warn, error", LogLevel)
+	}
+
+	var w io.Writer = os.Stderr
+
+	switch {
+	case LogFile != "":
+
+		if logFile, err = os.OpenFile(LogFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644); err != nil {
+			return
+		}
+
+		w = logFile
+
+	case cmd == cmdView:
+		return
+	}
+
+	logger = slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{Level: level}))
+
+	return
+}
+
+// closeLogging closes the file of the log
+func closeLogging() {
+
+	if logFile != nil {
+		logFile.Close()
+	}
+}
+
+// openStore opens the database specified by the flags
+func openStore() (*tvguide.Store, error) {
+	return tvguide.Open(DatabasePath, &tvguide.Options{Logger: logger})
+}
+
+// loadStats - the counters of the playlist and tv guide loading printed by --stats
+type loadStats struct {
+	Playlist struct {
+		Load tvguide.LoadStats     `json:"load"`
+		Read tvguide.PlaylistStats `json:"read"`
+	} `json:"playlist"`
+
+	Guide struct {
+		Load               tvguide.LoadStats   `json:"load"`
+		Read               tvguide.ImportStats `json:"read"`
+		Unchanged          bool                `json:"unchanged"`
+		Channels           int                 `json:"channels"`
+		Programmes         int                 `json:"programmes"`
+		Skipped            int                 `json:"skipped"`
+		FilteredChannels   int                 `json:"filtered_channels"`
+		FilteredProgrammes int                 `json:"filtered_programmes"`
+		Repaired           int                 `json:"repaired"`
+	} `json:"guide"`
+}
+
+// setReport sets the counters of the tv guide reading from its report
+func (s *loadStats) setReport(report *tvguide.ImportReport) {
+
+	s.Guide.Read, s.Guide.Unchanged = report.Stats, report.Unchanged
+	s.Guide.Channels, s.Guide.Programmes, s.Guide.Skipped = report.Channels, report.Programmes, report.Skipped
+	s.Guide.FilteredChannels, s.Guide.FilteredProgrammes = report.FilteredChannels, report.FilteredProgrammes
+	s.Guide.Repaired = report.Repaired
+}
+
+// printStats prints the counters of the loading in the format of the --stats flag
+func printStats(w io.Writer, s *loadStats) error {
+
+	switch strings.ToLower(Stats) {
+	case "":
+		return nil
+	case "json":
+
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(s)
+	case "text":
+	default:
+		return &usageError{fmt.Errorf("invalid stats format %q, expected text or json", Stats)}
+	}
+
+	p, g := &s.Playlist, &s.Guide
+
+	fmt.Fprintf(w, "Playlist: loaded %d bytes in %v, read %d items in %v\n", p.Load.Bytes, p.Load.Duration,
+		p.Read.Items, p.Read.Duration)
+	fmt.Fprintf(w, "TV guide: loaded %d bytes in %v, parsed in %v, finished in %v\n", g.Load.Bytes, g.Load.Duration,
+		g.Read.Parse, g.Read.Finish)
+	fmt.Fprintf(w, "TV guide: channels %d, programmes %d, skipped %d, filtered channels %d, programmes %d, repaired %d\n",
+		g.Channels, g.Programmes, g.Skipped, g.FilteredChannels, g.FilteredProgrammes, g.Repaired)
+
+	tables := make([]string, 0, len(g.Read.Rows))
+
+	for table := range g.Read.Rows {
+		tables = append(tables, table)
+	}
+
+	sort.Strings(tables)
+
+	for _, table := range tables {
+		fmt.Fprintf(w, "  %-32s %d\n", table, g.Read.Rows[table])
+	}
+
+	return nil
+}
@@ -0,0 +1,119 @@
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package commands
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+
+	tvguide "go-tvguide/pkg/tvguide"
+)
+
+// memoryGuide - the playlist and the tv guide kept in memory by the builds without cgo. The guide
+// is not repaired and filtered, the programmes cannot be searched by person
+type memoryGuide struct {
+	tvguide.IBackend
+	langs tvguide.Languages
+}
+
+// Languages returns the preferred languages of the guide texts set by the flags
+func (g *memoryGuide) Languages() (tvguide.Languages, error) {
+	return g.langs, nil
+}
+
+// ProgrammesByPerson returns ErrNoSQLite, the memory backend cannot search by person
+func (g *memoryGuide) ProgrammesByPerson(name string, roles []string, from, to time.Time) ([]*tvguide.PersonProgramme, error) {
+	return nil, tvguide.ErrNoSQLite
+}
+
+// loadMemoryGuide loads the playlist and its tv guide specified by the flags to the memory backend
+func loadMemoryGuide() (g *memoryGuide, err error) {
+
+	if header, err = parseHeaders(Headers); err != nil {
+		return
+	}
+
+	if err = checkFormat("playlist", PlaylistFormat, tvguide.PlaylistFormats()); err != nil {
+		return
+	}
+
+	if err = checkFormat("tv guide", GuideFormat, tvguide.GuideFormats()); err != nil {
+		return
+	}
+
+	g = &memoryGuide{IBackend: tvguide.NewMemoryBackend(), langs: tvguide.ParseLanguages(GuideLanguages)}
+
+	path := PlaylistPath
+	data, err := loadPlaylistOrGuide(tvguide.Loader(path), path)
+
+	if err != nil {
+		return
+	}
+
+	format := PlaylistFormat
+
+	if format == "" {
+		format = tvguide.PlaylistFormatOf(data)
+	}
+
+	pparser, err := tvguide.PlaylistParserOf(format)
+
+	if err != nil {
+		return
+	}
+
+	if err = tvguide.ReadPlaylist(g, data, pparser); err != nil {
+		return
+	}
+
+	gpath := GuidePath
+
+	if gpath == "" {
+		gpath = pparser.Guide()
+	}
+
+	if data, err = loadPlaylistOrGuide(tvguide.Loader(gpath), gpath); err != nil {
+		return
+	}
+
+	if format = GuideFormat; format == "" {
+		format = tvguide.GuideFormatOf(data)
+	}
+
+	gparser, err := tvguide.GuideParserOf(format, StrictGuide, runtime.NumCPU())
+
+	if err != nil {
+		return
+	}
+
+	st := time.Now()
+
+	fmt.Fprintln(console, "TV guide reading. Please, wait...")
+
+	report, err := tvguide.ReadGuide(g, data, gparser)
+
+	fmt.Fprintf(console, "TV Guide reading completed in %.3fs\n", time.Since(st).Seconds())
+
+	if err != nil {
+		return
+	}
+
+	printImportReport(report)
+
+	return
+}
@@ -0,0 +1,236 @@
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	tvguide "go-tvguide/pkg/tvguide"
+)
+
+// NowGroup - the group of the channels printed by the now command
+var NowGroup string
+
+// NowChannel - the channel printed by the now command, its identifier or a part of its name
+var NowChannel string
+
+// NowAt - the time the current programmes are printed at, now by default
+var NowAt string
+
+// nowTimeFormats - the formats of the --at time, the time of day is the time of today
+var nowTimeFormats = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04", "15:04"}
+
+var cmdNow = &cobra.Command{
+	Use:   "now",
+	Short: "Printing current programmes",
+	Long:  "Printing the current and the next programmes of the playlist channels",
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		at, err := parseNowTime(NowAt, time.Now())
+
+		if err != nil {
+			return err
+		}
+
+		console = os.Stderr
+
+		store, err := openStore()
+
+		if err != nil {
+			return err
+		}
+
+		defer store.Close()
+
+		_, guide, err := loadPlaylistAndGuide(store)
+
+		if err != nil {
+			return err
+		}
+
+		langs, err := guide.Languages()
+
+		if err != nil {
+			return err
+		}
+
+		channels, err := guide.NowNext(at, langs)
+
+		if err != nil {
+			return err
+		}
+
+		return printResult(os.Stdout, nowResult(filterNowNext(channels), at))
+	},
+}
+
+// nowNext - the current and the next programmes of the channel printed by the now command. Elapsed
+// and remaining are the seconds passed since the start of the current programme and left until its end
+type nowNext struct {
+	*tvguide.ChannelNowNext
+	Elapsed   int64 `json:"elapsed_seconds"`
+	Remaining int64 `json:"remaining_seconds"`
+}
+
+// parseNowTime returns the time of the --at flag, now if it is empty
+func parseNowTime(s string, now time.Time) (time.Time, error) {
+
+	if s == "" {
+		return now, nil
+	}
+
+	for _, layout := range nowTimeFormats {
+
+		t, err := time.ParseInLocation(layout, s, time.Local)
+
+		if err != nil {
+			continue
+		}
+
+		if layout == "15:04" {
+			t = time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, time.Local)
+		}
+
+		return t, nil
+	}
+
+	return time.Time{}, &usageError{fmt.Errorf("invalid time %q, expected one of %s", s,
+		strings.Join(nowTimeFormats, ", "))}
+}
+
+// filterNowNext returns the channels of the group and the channel specified by the flags
+func filterNowNext(channels []*tvguide.ChannelNowNext) []*tvguide.ChannelNowNext {
+
+	filtered := make([]*tvguide.ChannelNowNext, 0, len(channels))
+
+	for _, c := range channels {
+
+		if NowGroup != "" && !strings.EqualFold(c.Channel.GroupTitle, NowGroup) {
+			continue
+		}
+
+		if NowChannel != "" && !strings.EqualFold(c.Channel.ID, NowChannel) &&
+			!strings.Contains(strings.ToLower(c.Channel.Name), strings.ToLower(NowChannel)) {
+			continue
+		}
+
+		filtered = append(filtered, c)
+	}
+
+	return filtered
+}
+
+// nowResult returns the current and the next programmes of the channels as the command result
+func nowResult(channels []*tvguide.ChannelNowNext, at time.Time) *result {
+
+	items := make([]*nowNext, 0, len(channels))
+
+	r := &result{header: []string{"group", "channel_id", "channel_name", "now_start", "now_stop",
+		"now_title", "elapsed_seconds", "remaining_seconds", "progress", "next_start", "next_stop", "next_title"}}
+
+	for _, c := range channels {
+
+		item := &nowNext{ChannelNowNext: c}
+
+		localProgramme(c.Now)
+		localProgramme(c.Next)
+
+		if c.Now != nil {
+
+			stop := c.Now.Stop
+
+			if stop.IsZero() {
+				y, m, d := c.Now.Start.Date()
+				stop = time.Date(y, m, d+1, 0, 0, 0, 0, time.Local)
+			}
+
+			item.Elapsed, item.Remaining = int64(at.Sub(c.Now.Start).Seconds()), int64(stop.Sub(at).Seconds())
+		}
+
+		items = append(items, item)
+		r.items = append(r.items, item)
+
+		now, next := programmeColumns(c.Now), programmeColumns(c.Next)
+		row := []string{c.Channel.GroupTitle, c.Channel.ID, c.Channel.Name}
+		row = append(row, now...)
+		row = append(row, strconv.FormatInt(item.Elapsed, 10), strconv.FormatInt(item.Remaining, 10),
+			strconv.FormatFloat(c.Progress, 'f', 2, 64))
+
+		r.rows = append(r.rows, append(row, next...))
+	}
+
+	r.value = items
+	r.text = func(w io.Writer) { printNowNext(w, items) }
+
+	return r
+}
+
+// programmeColumns returns the start, the stop and the title of the programme for the csv record
+func programmeColumns(p *tvguide.Programme) []string {
+
+	if p == nil {
+		return []string{"", "", ""}
+	}
+
+	return []string{formatTime(p.Start), formatTime(p.Stop), p.Title}
+}
+
+func printNowNext(w io.Writer, items []*nowNext) {
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "GROUP\tCHANNEL\tNOW\tELAPSED/LEFT\tNEXT")
+
+	for _, item := range items {
+
+		now, elapsed, next := "-", "", "-"
+
+		if p := item.Now; p != nil {
+			now = p.Start.Format("15:04") + " " + p.Title
+			elapsed = formatMinutes(item.Elapsed) + "/" + formatMinutes(item.Remaining)
+		}
+
+		if p := item.Next; p != nil {
+			next = p.Start.Format("15:04") + " " + p.Title
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", item.Channel.GroupTitle, item.Channel.Name, now, elapsed, next)
+	}
+
+	tw.Flush()
+}
+
+// formatMinutes returns the seconds as the hours and minutes, e.g. 1h05m or 25m
+func formatMinutes(seconds int64) string {
+
+	m := seconds / 60
+
+	if m >= 60 {
+		return fmt.Sprintf("%dh%02dm", m/60, m%60)
+	}
+
+	return fmt.Sprintf("%dm", m)
+}
@@ -0,0 +1,66 @@
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package commands
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	tvguide "go-tvguide/pkg/tvguide"
+)
+
+func TestNowResultLocalTime(t *testing.T) {
+
+	local := time.Local
+	time.Local = time.FixedZone("MSK", 3*60*60)
+	defer func() { time.Local = local }()
+
+	output := Output
+	Output = "json"
+	defer func() { Output = output }()
+
+	// the guide returns the local wall clock times labelled as UTC
+	channels := []*tvguide.ChannelNowNext{{
+		Channel:  &tvguide.PlaylistItem{ID: "News", Name: "News"},
+		Now:      &tvguide.Programme{Start: time.Date(2018, 10, 20, 21, 0, 0, 0, time.UTC), Title: "Late news"},
+		Next:     &tvguide.Programme{Start: time.Date(2018, 10, 21, 0, 0, 0, 0, time.UTC), Title: "Night"},
+		Progress: 0.5,
+	}}
+
+	at := time.Date(2018, 10, 20, 22, 30, 0, 0, time.Local)
+	r := nowResult(channels, at)
+
+	item := r.items[0].(*nowNext)
+
+	if item.Elapsed != 90*60 || item.Remaining != 90*60 {
+		t.Errorf("nowResult() = %d elapsed, %d remaining seconds, want %d and %d", item.Elapsed, item.Remaining, 90*60, 90*60)
+	}
+
+	var buf bytes.Buffer
+
+	if err := printResult(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{`"start": "2018-10-20T21:00:00+03:00"`, `"start": "2018-10-21T00:00:00+03:00"`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("printResult() = %s, want %s", buf.String(), want)
+		}
+	}
+}
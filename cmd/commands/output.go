@@ -0,0 +1,168 @@
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package commands
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	tvguide "go-tvguide/pkg/tvguide"
+)
+
+// Output - the format of the command results: text, json, ndjson, yaml or csv. The schemas of
+// the formats are described in docs/output.md
+var Output string
+
+// outputFormats - the supported formats of the command results
+var outputFormats = []string{"text", "json", "ndjson", "yaml", "csv"}
+
+// result - the result of the command in all output formats
+type result struct {
+	// value - the value encoded to json and yaml, the same schema is used by both
+	value interface{}
+	// items - the records printed by ndjson one per line
+	items []interface{}
+	// header and rows - the table printed by csv
+	header []string
+	rows   [][]string
+	// text prints the result in the human readable form
+	text func(w io.Writer)
+}
+
+// setupOutput checks the output format, the loading progress is printed to the standard error
+// if the results are machine-readable
+func setupOutput() error {
+
+	for _, f := range outputFormats {
+		if f == Output {
+
+			if Output != "text" {
+				console = os.Stderr
+			}
+
+			return nil
+		}
+	}
+
+	return fmt.Errorf("invalid output format %q, expected one of %s", Output, strings.Join(outputFormats, ", "))
+}
+
+// printResult prints the result of the command in the output format
+func printResult(w io.Writer, r *result) error {
+
+	switch Output {
+	case "json":
+
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(r.value)
+
+	case "ndjson":
+
+		encoder := json.NewEncoder(w)
+
+		for _, item := range r.items {
+			if err := encoder.Encode(item); err != nil {
+				return err
+			}
+		}
+
+		return nil
+
+	case "yaml":
+		return writeYAML(w, r.value)
+
+	case "csv":
+
+		writer := csv.NewWriter(w)
+		writer.Write(r.header)
+		writer.WriteAll(r.rows)
+
+		return writer.Error()
+	}
+
+	r.text(w)
+
+	return nil
+}
+
+// writeYAML prints the value in yaml with the keys of its json encoding in the same order
+func writeYAML(w io.Writer, v interface{}) error {
+
+	data, err := json.Marshal(v)
+
+	if err != nil {
+		return err
+	}
+
+	var value interface{}
+
+	if data = bytes.TrimSpace(data); len(data) > 0 && data[0] == '[' {
+		value = &[]yaml.MapSlice{}
+	} else {
+		value = &yaml.MapSlice{}
+	}
+
+	if err = yaml.Unmarshal(data, value); err != nil {
+		return err
+	}
+
+	if data, err = yaml.Marshal(value); err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+
+	return err
+}
+
+// formatTime returns the time of the csv record, empty if it is not set
+func formatTime(t time.Time) string {
+
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.Format(time.RFC3339)
+}
+
+// localTime returns the time of the guide in the local time zone. The guide returns the local wall clock
+// times of the programmes labelled as UTC
+func localTime(t time.Time) time.Time {
+
+	if t.IsZero() {
+		return t
+	}
+
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.Local)
+}
+
+// localProgramme sets the times of the programme to the local time zone
+func localProgramme(p *tvguide.Programme) {
+	if p != nil {
+		p.Start, p.Stop = localTime(p.Start), localTime(p.Stop)
+	}
+}
@@ -0,0 +1,94 @@
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	tvguide "go-tvguide/pkg/tvguide"
+)
+
+var cmdPerson = &cobra.Command{
+	Use:   "person NAME",
+	Short: "Searching TV guide by person",
+	Long:  "Searching upcoming programmes featuring the person as actor, director, presenter or in any other credit role",
+	Args:  cobra.MinimumNArgs(1),
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		store, err := openStore()
+
+		if err != nil {
+			return err
+		}
+
+		defer store.Close()
+
+		_, guide, err := loadPlaylistAndGuide(store)
+
+		if err != nil {
+			return err
+		}
+
+		name := strings.Join(args, " ")
+
+		programmes, err := guide.ProgrammesByPerson(name, PersonRoles, time.Now(), time.Time{})
+
+		if err != nil {
+			return err
+		}
+
+		return printResult(os.Stdout, personResult(name, programmes))
+	},
+}
+
+// personResult returns the upcoming programmes of the person as the command result
+func personResult(name string, programmes []*tvguide.PersonProgramme) *result {
+
+	r := &result{value: programmes,
+		header: []string{"start", "stop", "channel_id", "channel_name", "title", "person", "role", "character"}}
+
+	for _, p := range programmes {
+
+		localProgramme(&p.Programme)
+
+		r.items = append(r.items, p)
+		r.rows = append(r.rows, []string{formatTime(p.Start), formatTime(p.Stop), p.ChannelID, p.ChannelName, p.Title,
+			p.Person, p.Role, p.Character})
+	}
+
+	r.text = func(w io.Writer) {
+
+		if len(programmes) == 0 {
+			fmt.Fprintf(w, "No upcoming programmes with %s\n", name)
+			return
+		}
+
+		for _, p := range programmes {
+			fmt.Fprintf(w, "%s  %-20s  %s - %s\n", p.Start.Format("Mon 02 Jan 15:04"), p.ChannelName, p.Title,
+				p.PersonCredit())
+		}
+	}
+
+	return r
+}
@@ -18,6 +18,8 @@ package commands
 
 import (
 	"fmt"
+	"io"
+	"os"
 
 	"github.com/spf13/cobra"
 )
@@ -26,7 +28,16 @@ var cmdVersion = &cobra.Command{
 	Use:   "version",
 	Short: "Print version number of tvguide",
 	Long:  "Most of applications have version number. TVGuide is one of them",
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("TVGuide v2018.10.0.1a")
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		v := &struct {
+			Version string `json:"version"`
+		}{version}
+
+		return printResult(os.Stdout, &result{value: v, items: []interface{}{v}, header: []string{"version"},
+			rows: [][]string{{version}}, text: func(w io.Writer) { fmt.Fprintf(w, "TVGuide %s\n", version) }})
 	},
 }
+
+// version - the version of the application
+const version = "v2018.10.0.1a"
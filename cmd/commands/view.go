@@ -19,6 +19,9 @@ package commands
 import (
 	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"os"
 	"strings"
 	"time"
 
@@ -27,9 +30,7 @@ import (
 	"github.com/spf13/cobra"
 
 	ui "go-tvguide/cmd/ui"
-	loaders "go-tvguide/internal/pkg/loaders"
-	pl "go-tvguide/internal/pkg/playlists"
-	xmltv "go-tvguide/pkg/xmltv"
+	tvguide "go-tvguide/pkg/tvguide"
 )
 
 var cmdView = &cobra.Command{
@@ -39,126 +40,292 @@ var cmdView = &cobra.Command{
 
 	RunE: func(cmd *cobra.Command, args []string) error {
 
-		path := PlaylistPath
-		loader := loaders.Loader(path)
-
-		data, err := loadPlaylistOrGuide(loader, path)
-
-		if err != nil {
-			return err
+		if err := ui.SetKeys(Keys); err != nil {
+			return &usageError{err}
 		}
 
-		parser := pl.PlaylistParser(data)
+		store, err := openStore()
 
-		if parser == nil {
-			return errors.New("Playlist view: unknown playlist format")
+		// the builds without cgo view the playlist and the guide kept in memory
+		if errors.Is(err, tvguide.ErrNoSQLite) {
+			return viewMemoryGuide()
 		}
 
-		playlist := pl.CurrentPlaylist()
-
-		err = playlist.Read(data, parser)
-
 		if err != nil {
 			return err
 		}
 
-		gpath := parser.Guide()
-		gloader := loaders.Loader(gpath)
+		defer store.Close()
 
-		data, err = loadPlaylistOrGuide(gloader, gpath)
+		playlist, guide, err := loadPlaylistAndGuide(store)
 
 		if err != nil {
 			return err
 		}
 
-		guide := pl.CurrentGuide()
-		gparser := &xmltv.XMLTVParser{}
+		return runViewer(playlist, guide)
+	},
+}
 
-		err = func(g *pl.Guide, p *xmltv.XMLTVParser, d []byte) error {
+// viewMemoryGuide views the playlist and the guide loaded to the memory backend
+func viewMemoryGuide() error {
 
-			st := time.Now()
+	g, err := loadMemoryGuide()
 
-			fmt.Println("TV guide reading. Please, wait...")
+	if err != nil {
+		return err
+	}
 
-			defer func(t time.Time) {
+	return runViewer(g, g)
+}
 
-				et := time.Now()
-				d := et.Sub(t)
+// runViewer runs the viewer of the playlist and the guide until it is closed
+func runViewer(playlist ui.IPlaylist, guide ui.IGuide) error {
 
-				fmt.Printf("TV Guide reading completed in %.3fs\n", d.Seconds())
-			}(st)
+	gui, err := ui.NewPlaylistViewer(playlist, guide)
 
-			return guide.Read(data, gparser)
+	if err != nil {
+		return err
+	}
 
-		}(guide, gparser, data)
+	defer gui.Close()
 
-		if err != nil {
-			return err
-		}
+	if err := gui.MainLoop(); err != nil && err != gocui.ErrQuit {
+		return err
+	}
 
-		gui, err := ui.NewPlaylistViewer(playlist, guide)
+	return nil
+}
 
-		if err != nil {
-			return err
+// loadPlaylistAndGuide loads the playlist and its tv guide specified by the flags to the store
+func loadPlaylistAndGuide(store *tvguide.Store) (playlist *tvguide.Playlist, guide *tvguide.Guide, err error) {
+
+	overlaps, err := tvguide.ParseOverlapPolicy(OverlapRepair)
+
+	if err != nil {
+		return
+	}
+
+	if header, err = parseHeaders(Headers); err != nil {
+		return
+	}
+
+	if err = checkFormat("playlist", PlaylistFormat, tvguide.PlaylistFormats()); err != nil {
+		return
+	}
+
+	if err = checkFormat("tv guide", GuideFormat, tvguide.GuideFormats()); err != nil {
+		return
+	}
+
+	var stats loadStats
+
+	path := PlaylistPath
+	loader := tvguide.Loader(path)
+
+	data, err := loadPlaylistOrGuide(loader, path)
+
+	if err != nil {
+		return
+	}
+
+	playlist = store.Playlist()
+
+	unchanged, err := tvguide.LoadPlaylist(playlist, path, data, PlaylistFormat)
+
+	if err != nil {
+		return
+	}
+
+	if unchanged {
+		fmt.Fprintln(console, "The playlist is up to date")
+	}
+
+	stats.Playlist.Load, stats.Playlist.Read = loader.Stats(), playlist.Stats()
+
+	gpath := GuidePath
+
+	if gpath == "" {
+		gpath = playlist.Guide()
+	}
+
+	loader = tvguide.Loader(gpath)
+
+	data, err = loadPlaylistOrGuide(loader, gpath)
+
+	if err != nil {
+		return
+	}
+
+	guide = store.Guide()
+	guide.SetPlaylist(playlist)
+	guide.SetLanguages(tvguide.ParseLanguages(GuideLanguages))
+	guide.SetRepairPolicy(&tvguide.RepairPolicy{FillStop: FillStop, Overlaps: overlaps, DropDuplicates: DropDuplicates,
+		MaxDuration: MaxDuration})
+
+	filter := tvguide.NewGuideFilter(playlist, DaysBack, DaysAhead, time.Now())
+
+	if AllChannels {
+		filter.Playlist = nil
+	}
+
+	st := time.Now()
+
+	fmt.Fprintln(console, "TV guide reading. Please, wait...")
+
+	report, err := tvguide.LoadGuide(guide, gpath, data, GuideFormat, StrictGuide, filter)
+
+	fmt.Fprintf(console, "TV Guide reading completed in %.3fs\n", time.Since(st).Seconds())
+
+	if err != nil {
+		return
+	}
+
+	printImportReport(report)
+
+	stats.Guide.Load = loader.Stats()
+	stats.setReport(report)
+
+	if err = printStats(console, &stats); err != nil {
+		return
+	}
+
+	if RetentionDays >= 0 {
+		err = pruneDatabase(store, false)
+	}
+
+	return
+}
+
+// console - the output of the loading progress and the import reports, the results of
+// the commands are printed to the standard output
+var console io.Writer = os.Stdout
+
+// maxPrintedErrors limits the number of import errors printed to the console
+const maxPrintedErrors = 10
+
+func printImportReport(report *tvguide.ImportReport) {
+
+	if report.Unchanged {
+		fmt.Fprintln(console, "The tv guide is up to date")
+		return
+	}
+
+	fmt.Fprintf(console, "Channels: %d, programmes: %d, skipped: %d\n", report.Channels, report.Programmes, report.Skipped)
+
+	if report.FilteredChannels > 0 || report.FilteredProgrammes > 0 {
+		fmt.Fprintf(console, "Filtered out channels: %d, programmes: %d\n", report.FilteredChannels, report.FilteredProgrammes)
+	}
+
+	if report.Truncated {
+		fmt.Fprintln(console, "Warning: the tv guide is truncated")
+	}
+
+	count := report.Skipped
+
+	if report.Truncated {
+		count++
+	}
+
+	for index, e := range report.Errors {
+
+		if index == maxPrintedErrors {
+			fmt.Fprintf(console, "... and %d more\n", count-maxPrintedErrors)
+			break
 		}
 
-		defer gui.Close()
+		fmt.Fprintln(console, e)
+	}
 
-		if err := gui.MainLoop(); err != nil && err != gocui.ErrQuit {
-			return err
+	if report.Repaired > 0 {
+		fmt.Fprintf(console, "Repaired programmes: %d\n", report.Repaired)
+	}
+
+	for index, r := range report.Repairs {
+
+		if index == maxPrintedErrors {
+			fmt.Fprintf(console, "... and %d more\n", report.Repaired-maxPrintedErrors)
+			break
 		}
 
-		return nil
-	},
+		fmt.Fprintln(console, r)
+	}
 }
 
-func loadPlaylistOrGuide(loader loaders.ILoader, path string) ([]byte, error) {
+// header - the headers of the HTTP requests parsed from the flags
+var header http.Header
+
+// parseHeaders returns the headers specified in the form "Name: value"
+func parseHeaders(headers []string) (http.Header, error) {
+
+	h := make(http.Header)
 
-	data := make([]byte, 0)
+	for _, s := range headers {
 
-	switch loader.(type) {
-	case *loaders.FileLoader:
-		if floader, ok := loader.(*loaders.FileLoader); ok {
-			return loadFromFile(floader, path)
+		i := strings.Index(s, ":")
+
+		if i <= 0 {
+			return nil, &usageError{fmt.Errorf("invalid header %q, expected \"Name: value\"", s)}
 		}
 
-		return data, errors.New("Playlist or guide loading: something wrong")
+		h.Add(strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+1:]))
+	}
+
+	return h, nil
+}
+
+// checkFormat checks the format specified by the flag is registered, the empty one is detected
+func checkFormat(kind, format string, formats []string) error {
+
+	if format == "" {
+		return nil
+	}
 
-	case *loaders.HTTPLoader:
-		if nloader, ok := loader.(*loaders.HTTPLoader); ok {
-			return loadFromURL(nloader, path)
+	for _, f := range formats {
+		if f == format {
+			return nil
 		}
+	}
 
-		return data, errors.New("Playlist or guide loading: something wrong")
+	return &usageError{fmt.Errorf("unknown %s format %q, expected one of %s", kind, format, strings.Join(formats, ", "))}
+}
+
+func loadPlaylistOrGuide(loader tvguide.ILoader, path string) ([]byte, error) {
 
+	switch l := loader.(type) {
+	case *tvguide.FileLoader:
+		return loadFromFile(l, path)
+	case *tvguide.HTTPLoader:
+		return loadFromURL(l, path)
 	case nil:
-		return data, errors.New("Playlist or guide loading: invalid path")
+		return make([]byte, 0), fmt.Errorf("%w: %s", tvguide.ErrInvalidPath, path)
 	}
 
-	return data, nil
+	return loader.Load(path)
 }
 
-func loadFromFile(loader *loaders.FileLoader, path string) ([]byte, error) {
+func loadFromFile(loader *tvguide.FileLoader, path string) ([]byte, error) {
 
-	fmt.Printf("Loading file %s\t...\n", path)
+	fmt.Fprintf(console, "Loading file %s\t...\n", path)
 	return loader.Load(path)
 }
 
-func loadFromURL(loader *loaders.HTTPLoader, url string) ([]byte, error) {
+func loadFromURL(loader *tvguide.HTTPLoader, url string) ([]byte, error) {
 
 	comment := "Downloading " + url
 
 	fprogress := func(complete uint64) {
-		fmt.Printf("\r%s ... %s", comment, strings.Repeat(" ", 35))
-		fmt.Printf("\r%s ... %s", comment, humanize.Bytes(complete))
+		fmt.Fprintf(console, "\r%s ... %s", comment, strings.Repeat(" ", 35))
+		fmt.Fprintf(console, "\r%s ... %s", comment, humanize.Bytes(complete))
 	}
 
 	fdone := func() {
-		fmt.Print("\n")
+		fmt.Fprint(console, "\n")
 	}
 
 	loader.OnProgress = fprogress
 	loader.OnDone = fdone
+	loader.Header = header
 
 	return loader.Load(url)
 }
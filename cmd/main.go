@@ -30,7 +30,7 @@ func main() {
 
 	if err := commands.Execute(); err != nil {
 		log.Println(err)
-		os.Exit(1)
+		os.Exit(commands.ExitCode(err))
 	}
 
 }
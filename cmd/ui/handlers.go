@@ -26,8 +26,8 @@ import (
 	"github.com/jroimartin/gocui"
 	"github.com/logrusorgru/aurora"
 
-	pl "go-tvguide/internal/pkg/playlists"
 	strutils "go-tvguide/internal/pkg/strutils"
+	tvguide "go-tvguide/pkg/tvguide"
 )
 
 func quit(ui *gocui.Gui, view *gocui.View) error {
@@ -56,11 +56,11 @@ func listDown(ui *gocui.Gui, view *gocui.View) error {
 				index := channels.ItemIndex()
 				ch := channels.Item(index)
 
-				if pi, ok := ch.(*pl.PlaylistItem); ok {
+				if pi, ok := ch.(*tvguide.PlaylistItem); ok {
 
 					t := CurrentTime()
 
-					if err := loadChannelGuide(tvg, pi.ID, lang, t); err != nil {
+					if err := loadChannelGuide(tvg, pi.ID, langs, t); err != nil {
 						return err
 					}
 				}
@@ -78,13 +78,13 @@ func listDown(ui *gocui.Gui, view *gocui.View) error {
 		index := channels.ItemIndex()
 		ch := channels.Item(index)
 
-		if pi, ok := ch.(*pl.PlaylistItem); ok {
+		if pi, ok := ch.(*tvguide.PlaylistItem); ok {
 
 			ui.Update(func(g *gocui.Gui) error {
 
 				t := CurrentTime()
 
-				if err := loadChannelGuide(tvg, pi.ID, lang, t); err != nil {
+				if err := loadChannelGuide(tvg, pi.ID, langs, t); err != nil {
 					return err
 				}
 
@@ -123,11 +123,11 @@ func listUp(ui *gocui.Gui, view *gocui.View) error {
 				index := channels.ItemIndex()
 				ch := channels.Item(index)
 
-				if pi, ok := ch.(*pl.PlaylistItem); ok {
+				if pi, ok := ch.(*tvguide.PlaylistItem); ok {
 
 					t := CurrentTime()
 
-					if err := loadChannelGuide(tvg, pi.ID, lang, t); err != nil {
+					if err := loadChannelGuide(tvg, pi.ID, langs, t); err != nil {
 						return err
 					}
 				}
@@ -145,13 +145,13 @@ func listUp(ui *gocui.Gui, view *gocui.View) error {
 		index := channels.ItemIndex()
 		ch := channels.Item(index)
 
-		if pi, ok := ch.(*pl.PlaylistItem); ok {
+		if pi, ok := ch.(*tvguide.PlaylistItem); ok {
 
 			ui.Update(func(g *gocui.Gui) error {
 
 				t := CurrentTime()
 
-				if err := loadChannelGuide(tvg, pi.ID, lang, t); err != nil {
+				if err := loadChannelGuide(tvg, pi.ID, langs, t); err != nil {
 					return err
 				}
 
@@ -190,11 +190,11 @@ func listPageUp(ui *gocui.Gui, view *gocui.View) error {
 				index := channels.ItemIndex()
 				ch := channels.Item(index)
 
-				if pi, ok := ch.(*pl.PlaylistItem); ok {
+				if pi, ok := ch.(*tvguide.PlaylistItem); ok {
 
 					t := CurrentTime()
 
-					if err := loadChannelGuide(tvg, pi.ID, lang, t); err != nil {
+					if err := loadChannelGuide(tvg, pi.ID, langs, t); err != nil {
 						return err
 					}
 				}
@@ -212,13 +212,13 @@ func listPageUp(ui *gocui.Gui, view *gocui.View) error {
 		index := channels.ItemIndex()
 		ch := channels.Item(index)
 
-		if pi, ok := ch.(*pl.PlaylistItem); ok {
+		if pi, ok := ch.(*tvguide.PlaylistItem); ok {
 
 			ui.Update(func(g *gocui.Gui) error {
 
 				t := CurrentTime()
 
-				if err := loadChannelGuide(tvg, pi.ID, lang, t); err != nil {
+				if err := loadChannelGuide(tvg, pi.ID, langs, t); err != nil {
 					return err
 				}
 
@@ -257,11 +257,11 @@ func listPageDown(ui *gocui.Gui, view *gocui.View) error {
 				index := channels.ItemIndex()
 				ch := channels.Item(index)
 
-				if pi, ok := ch.(*pl.PlaylistItem); ok {
+				if pi, ok := ch.(*tvguide.PlaylistItem); ok {
 
 					t := CurrentTime()
 
-					if err := loadChannelGuide(tvg, pi.ID, lang, t); err != nil {
+					if err := loadChannelGuide(tvg, pi.ID, langs, t); err != nil {
 						return err
 					}
 				}
@@ -279,13 +279,13 @@ func listPageDown(ui *gocui.Gui, view *gocui.View) error {
 		index := channels.ItemIndex()
 		ch := channels.Item(index)
 
-		if pi, ok := ch.(*pl.PlaylistItem); ok {
+		if pi, ok := ch.(*tvguide.PlaylistItem); ok {
 
 			ui.Update(func(g *gocui.Gui) error {
 
 				t := CurrentTime()
 
-				if err := loadChannelGuide(tvg, pi.ID, lang, t); err != nil {
+				if err := loadChannelGuide(tvg, pi.ID, langs, t); err != nil {
 					return err
 				}
 
@@ -325,7 +325,7 @@ func switchView(ui *gocui.Gui, view *gocui.View) error {
 	return nil
 }
 
-func loadGroups(p *pl.Playlist) error {
+func loadGroups(p IPlaylist) error {
 
 	groups.SetTitle(titleGroups)
 
@@ -333,7 +333,12 @@ func loadGroups(p *pl.Playlist) error {
 		return errors.New("Failed to load playlist")
 	}
 
-	g := p.Groups()
+	g, err := p.Groups()
+
+	if err != nil {
+		return err
+	}
+
 	data := make([]interface{}, len(g))
 
 	for i, gr := range g {
@@ -343,7 +348,7 @@ func loadGroups(p *pl.Playlist) error {
 	return groups.SetItems(data)
 }
 
-func loadChannels(p *pl.Playlist, group string) error {
+func loadChannels(p IPlaylist, group string) error {
 
 	channels.SetTitle(titleChannels)
 
@@ -351,7 +356,12 @@ func loadChannels(p *pl.Playlist, group string) error {
 		return errors.New("Failed to load playist")
 	}
 
-	c := p.Channels(group)
+	c, err := p.Channels(group)
+
+	if err != nil {
+		return err
+	}
+
 	data := make([]interface{}, len(c))
 
 	for i, ch := range c {
@@ -361,15 +371,16 @@ func loadChannels(p *pl.Playlist, group string) error {
 	return channels.SetItems(data)
 }
 
-func loadChannelGuide(g *pl.Guide, cid string, lang string, t time.Time) error {
+// loadChannelGuide loads the programmes of the channel for the displayed day only
+func loadChannelGuide(g IGuide, cid string, langs tvguide.Languages, t time.Time) error {
 
-	guide.SetTitle(titleGuide)
+	guide.SetTitle(titleGuide + " - " + guideDay.Format("Mon 02 Jan"))
 
 	if g == nil {
 		return errors.New("Failed to load tv guide")
 	}
 
-	gg, err := g.ChannelGuide(cid, lang, t)
+	gg, err := g.ChannelGuideQuery(cid, guideDayQuery(guideDay, t, langs))
 
 	if err != nil {
 		return err
@@ -384,6 +395,46 @@ func loadChannelGuide(g *pl.Guide, cid string, lang string, t time.Time) error {
 	return guide.SetItems(data)
 }
 
+// guideDayQuery returns the query of the programmes of the day, the current day starts a few hours before
+// the current time t
+func guideDayQuery(day, t time.Time, langs tvguide.Languages) *tvguide.GuideQuery {
+
+	from := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	to := from.AddDate(0, 0, 1)
+
+	if past := t.Add(-guidePastTime); past.After(from) && past.Before(to) {
+		from = past
+	}
+
+	return &tvguide.GuideQuery{From: from, To: to, Languages: langs}
+}
+
+func nextGuideDay(ui *gocui.Gui, view *gocui.View) error {
+	return moveGuideDay(1)
+}
+
+func prevGuideDay(ui *gocui.Gui, view *gocui.View) error {
+	return moveGuideDay(-1)
+}
+
+func moveGuideDay(days int) error {
+
+	index := channels.ItemIndex()
+
+	if index < 0 {
+		return nil
+	}
+
+	if pi, ok := channels.Item(index).(*tvguide.PlaylistItem); ok {
+
+		guideDay = guideDay.AddDate(0, 0, days)
+
+		return loadChannelGuide(tvg, pi.ID, langs, CurrentTime())
+	}
+
+	return nil
+}
+
 func help(ui *gocui.Gui, view *gocui.View) error {
 
 	curview = ui.CurrentView()
@@ -403,7 +454,7 @@ func setTopWindowTitle(ui *gocui.Gui, view, title string) error {
 		return err
 	}
 
-	v.Title = fmt.Sprintf(" %v (Ctrl+Q to close) ", title)
+	v.Title = fmt.Sprintf(" %v (%v to close) ", title, keyOf("close"))
 
 	return nil
 }
@@ -423,18 +474,9 @@ func createHelpView(ui *gocui.Gui, title string) error {
 
 	fmt.Fprint(v, "  \n")
 
-	fmt.Fprintf(v, " %v: %v", aurora.Bold("Tab"), "Focuses between Groups, Channels or Guide lists\n")
-	fmt.Fprintf(v, " %v: %v", aurora.Bold("Ctrl+6"), "Focuses Groups list\n")
-	fmt.Fprintf(v, " %v: %v", aurora.Bold("Ctrl+7"), "Focuses Channels list\n")
-	fmt.Fprintf(v, " %v: %v", aurora.Bold("Ctrl+8"), "Focuses Guide list\n")
-	fmt.Fprintf(v, " %v: %v", aurora.Bold("ArrowUp"), "Moves to the previous list item circularly\n")
-	fmt.Fprintf(v, " %v: %v", aurora.Bold("ArrowDn"), "Moves to the next list item circularly\n")
-	fmt.Fprintf(v, " %v: %v", aurora.Bold("PgUp"), "Moves to the previous list page circularly\n")
-	fmt.Fprintf(v, " %v: %v", aurora.Bold("PgDn"), "Moves to the next list page circularly\n")
-	fmt.Fprintf(v, " %v: %v", aurora.Bold("Enter"), "\n")
-	fmt.Fprintf(v, " %v: %v", aurora.Bold("Esc"), "Closes any window displayed on top of the main windows\n")
-	fmt.Fprintf(v, " %v: %v", aurora.Bold("F1"), "Opens up the Help window\n")
-	fmt.Fprintf(v, " %v: %v", aurora.Bold("Ctrl+C"), "Exits the application\n")
+	for _, b := range keyBindings {
+		fmt.Fprintf(v, " %v: %v\n", aurora.Bold(b.key), b.help)
+	}
 
 	_, err = ui.SetCurrentView(viewHelp)
 
@@ -465,6 +507,18 @@ func destroyTopView(ui *gocui.Gui, view *gocui.View) error {
 		if err := destroyProgrammeView(ui); err != nil {
 			return err
 		}
+
+	case viewPersonInput:
+
+		if err := destroyPersonInputView(ui); err != nil {
+			return err
+		}
+
+	case viewPerson:
+
+		if err := destroyPersonView(ui); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -482,6 +536,18 @@ func destroyProgrammeView(ui *gocui.Gui) error {
 	return ui.DeleteView(viewProgramme)
 }
 
+func destroyPersonInputView(ui *gocui.Gui) error {
+
+	ui.Cursor = false
+	return ui.DeleteView(viewPersonInput)
+}
+
+func destroyPersonView(ui *gocui.Gui) error {
+
+	ui.Cursor = false
+	return ui.DeleteView(viewPerson)
+}
+
 func focusGroupsView(ui *gocui.Gui, view *gocui.View) error {
 
 	switch view.Name() {
@@ -548,11 +614,11 @@ func onEnter(ui *gocui.Gui, view *gocui.View) error {
 		index := guide.ItemIndex()
 		item := guide.Item(index)
 
-		if p, ok := item.(*pl.Programme); ok {
+		if p, ok := item.(*tvguide.Programme); ok {
 
 			pid := p.PID
 
-			pd, err := tvg.ProgrammeDescription(pid, lang)
+			pd, err := tvg.ProgrammeDescription(pid, langs)
 
 			if err != nil {
 				return err
@@ -562,12 +628,106 @@ func onEnter(ui *gocui.Gui, view *gocui.View) error {
 				return err
 			}
 		}
+
+	case viewPersonInput:
+
+		name := strings.TrimSpace(view.Buffer())
+
+		if name == "" {
+			return destroyTopView(ui, view)
+		}
+
+		if err := destroyPersonInputView(ui); err != nil {
+			return err
+		}
+
+		programmes, err := tvg.ProgrammesByPerson(name, nil, CurrentTime(), time.Time{})
+
+		// the memory backend of the builds without cgo cannot search by person
+		if errors.Is(err, tvguide.ErrNoSQLite) {
+			return createMessageView(ui, titlePerson+" "+name, "Searching by person is not supported by this build")
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if err := createPersonView(ui, titlePerson+" "+name, programmes); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-func createProgrammeView(ui *gocui.Gui, title string, pd *pl.ProgrammeDescription) error {
+func searchPerson(ui *gocui.Gui, view *gocui.View) error {
+
+	curview = ui.CurrentView()
+
+	w, h := ui.Size()
+	v, err := ui.SetView(viewPersonInput, w/4, h/2-1, w*3/4, h/2+1)
+
+	if err != nil && err != gocui.ErrUnknownView {
+		return err
+	}
+
+	v.Editable = true
+	v.Wrap = false
+
+	setTopWindowTitle(ui, viewPersonInput, titlePersonInput)
+
+	ui.Cursor = true
+	_, err = ui.SetCurrentView(viewPersonInput)
+
+	return err
+}
+
+func createPersonView(ui *gocui.Gui, title string, programmes []*tvguide.PersonProgramme) error {
+
+	w, h := ui.Size()
+	v, err := ui.SetView(viewPerson, w/6, h/6, w*5/6, h*5/6)
+
+	if err != nil && err != gocui.ErrUnknownView {
+		return err
+	}
+
+	v.Wrap = true
+	v.Autoscroll = false
+
+	setTopWindowTitle(ui, viewPerson, title)
+
+	if len(programmes) == 0 {
+		fmt.Fprintf(v, " %s\n", "No upcoming programmes")
+	}
+
+	for _, p := range programmes {
+		fmt.Fprintf(v, " %s %s %v - %s\n", p.Start.Format("Mon 02 Jan 15:04"), p.ChannelName,
+			aurora.Bold(p.Title), p.PersonCredit())
+	}
+
+	_, err = ui.SetCurrentView(viewPerson)
+
+	return err
+}
+
+func createMessageView(ui *gocui.Gui, title, message string) error {
+
+	w, h := ui.Size()
+	v, err := ui.SetView(viewPerson, w/6, h/2-1, w*5/6, h/2+1)
+
+	if err != nil && err != gocui.ErrUnknownView {
+		return err
+	}
+
+	setTopWindowTitle(ui, viewPerson, title)
+	fmt.Fprintf(v, " %s\n", message)
+
+	_, err = ui.SetCurrentView(viewPerson)
+
+	return err
+}
+
+func createProgrammeView(ui *gocui.Gui, title string, pd *tvguide.ProgrammeDescription) error {
 
 	w, h := ui.Size()
 	v, err := ui.SetView(viewProgramme, w/6, h/6, w*5/6, h*5/6)
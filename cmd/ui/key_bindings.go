@@ -17,81 +17,148 @@
 package ui
 
 import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
 	"github.com/jroimartin/gocui"
 )
 
-func setKeyBindings(ui *gocui.Gui) error {
-
-	err := ui.SetKeybinding("", gocui.KeyCtrlC, gocui.ModNone, quit)
+// keyBinding - the key of the action in the view, the empty view means all the views
+type keyBinding struct {
+	action  string
+	view    string
+	key     string
+	handler func(*gocui.Gui, *gocui.View) error
+	help    string
+}
 
-	if err != nil {
-		return err
+// keyBindings - the key bindings in the order of the help, set by init as the
+// handlers refer to them
+var keyBindings []*keyBinding
+
+func init() {
+
+	keyBindings = []*keyBinding{
+		{"switch-view", "", "Tab", switchView, "Focuses between Groups, Channels or Guide lists"},
+		{"focus-groups", "", "Ctrl+6", focusGroupsView, "Focuses Groups list"},
+		{"focus-channels", "", "Ctrl+7", focusChannelsView, "Focuses Channels list"},
+		{"focus-guide", "", "Ctrl+8", focusGuideView, "Focuses Guide list"},
+		{"up", "", "ArrowUp", listUp, "Moves to the previous list item circularly"},
+		{"down", "", "ArrowDown", listDown, "Moves to the next list item circularly"},
+		{"page-up", "", "PgUp", listPageUp, "Moves to the previous list page circularly"},
+		{"page-down", "", "PgDn", listPageDown, "Moves to the next list page circularly"},
+		{"prev-day", viewGuide, "ArrowLeft", prevGuideDay, "Shows the guide for the previous day"},
+		{"next-day", viewGuide, "ArrowRight", nextGuideDay, "Shows the guide for the next day"},
+		{"enter", "", "Enter", onEnter, "Shows the description of the programme or searches the entered person"},
+		{"close", "", "Ctrl+Q", destroyTopView, "Closes any window displayed on top of the main windows"},
+		{"help", "", "F1", help, "Opens up the Help window"},
+		{"search-person", "", "F3", searchPerson, "Searches upcoming programmes by the name of actor, director, presenter, etc."},
+		{"quit", "", "Ctrl+C", quit, "Exits the application"},
 	}
 
-	err = ui.SetKeybinding("", gocui.KeyArrowDown, gocui.ModNone, listDown)
+	for i := 0; i < 12; i++ {
+		namedKeys[fmt.Sprintf("f%d", i+1)] = gocui.KeyF1 - gocui.Key(i)
+	}
 
-	if err != nil {
-		return err
+	for c := 'a'; c <= 'z'; c++ {
+		namedKeys["ctrl+"+string(c)] = gocui.KeyCtrlA + gocui.Key(c-'a')
 	}
+}
 
-	err = ui.SetKeybinding("", gocui.KeyArrowUp, gocui.ModNone, listUp)
+// namedKeys - the keys by their lowercase names, the function and control keys are added by init
+var namedKeys = map[string]gocui.Key{
+	"tab": gocui.KeyTab, "enter": gocui.KeyEnter, "esc": gocui.KeyEsc, "space": gocui.KeySpace,
+	"backspace": gocui.KeyBackspace2, "insert": gocui.KeyInsert, "delete": gocui.KeyDelete,
+	"home": gocui.KeyHome, "end": gocui.KeyEnd, "pgup": gocui.KeyPgup, "pgdn": gocui.KeyPgdn,
+	"arrowup": gocui.KeyArrowUp, "arrowdown": gocui.KeyArrowDown, "arrowleft": gocui.KeyArrowLeft,
+	"arrowright": gocui.KeyArrowRight, "ctrl+2": gocui.KeyCtrl2, "ctrl+3": gocui.KeyCtrl3,
+	"ctrl+4": gocui.KeyCtrl4, "ctrl+5": gocui.KeyCtrl5, "ctrl+6": gocui.KeyCtrl6, "ctrl+7": gocui.KeyCtrl7,
+	"ctrl+8": gocui.KeyCtrl8,
+}
 
-	if err != nil {
-		return err
-	}
+// KeyActions returns the names of the actions the keys can be bound to
+func KeyActions() []string {
 
-	err = ui.SetKeybinding("", gocui.KeyPgup, gocui.ModNone, listPageUp)
+	actions := make([]string, 0, len(keyBindings))
 
-	if err != nil {
-		return err
+	for _, b := range keyBindings {
+		actions = append(actions, b.action)
 	}
 
-	err = ui.SetKeybinding("", gocui.KeyPgdn, gocui.ModNone, listPageDown)
+	return actions
+}
 
-	if err != nil {
-		return err
-	}
+// SetKeys binds the actions to the keys, e.g. "quit": "Ctrl+X" or "help": "h". The keys are named
+// as in the help: Tab, Enter, Esc, PgUp, ArrowUp, F1, Ctrl+Q, etc. or by the character
+func SetKeys(keys map[string]string) error {
 
-	err = ui.SetKeybinding("", gocui.KeyTab, gocui.ModNone, switchView)
+	for action, key := range keys {
 
-	if err != nil {
-		return err
-	}
+		b := bindingOf(action)
+
+		if b == nil {
+			return fmt.Errorf("unknown key action %q, expected one of %s", action, strings.Join(KeyActions(), ", "))
+		}
 
-	err = ui.SetKeybinding("", gocui.KeyF1, gocui.ModNone, help)
+		if _, _, err := parseKey(key); err != nil {
+			return err
+		}
 
-	if err != nil {
-		return err
+		b.key = key
 	}
 
-	err = ui.SetKeybinding("", gocui.KeyCtrlQ, gocui.ModNone, destroyTopView)
+	return nil
+}
+
+// bindingOf returns the key binding of the action
+func bindingOf(action string) *keyBinding {
 
-	if err != nil {
-		return err
+	for _, b := range keyBindings {
+		if b.action == action {
+			return b
+		}
 	}
 
-	err = ui.SetKeybinding("", gocui.KeyCtrl6, gocui.ModNone, focusGroupsView)
+	return nil
+}
 
-	if err != nil {
-		return err
-	}
+// keyOf returns the name of the key of the action
+func keyOf(action string) string {
+	return bindingOf(action).key
+}
 
-	err = ui.SetKeybinding("", gocui.KeyCtrl7, gocui.ModNone, focusChannelsView)
+// parseKey returns the key by its name, the key of the single character is the rune
+func parseKey(name string) (key interface{}, mod gocui.Modifier, err error) {
 
-	if err != nil {
-		return err
+	if k, ok := namedKeys[strings.ToLower(name)]; ok {
+		return k, gocui.ModNone, nil
 	}
 
-	err = ui.SetKeybinding("", gocui.KeyCtrl8, gocui.ModNone, focusGuideView)
+	if strings.HasPrefix(strings.ToLower(name), "alt+") {
+		name, mod = name[len("alt+"):], gocui.ModAlt
+	}
 
-	if err != nil {
-		return err
+	if r, size := utf8.DecodeRuneInString(name); size > 0 && size == len(name) && r != utf8.RuneError {
+		return r, mod, nil
 	}
 
-	err = ui.SetKeybinding("", gocui.KeyEnter, gocui.ModNone, onEnter)
+	return nil, gocui.ModNone, fmt.Errorf("unknown key %q", name)
+}
+
+func setKeyBindings(ui *gocui.Gui) error {
+
+	for _, b := range keyBindings {
+
+		key, mod, err := parseKey(b.key)
+
+		if err != nil {
+			return err
+		}
 
-	if err != nil {
-		return err
+		if err = ui.SetKeybinding(b.view, key, mod, b.handler); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -19,11 +19,12 @@ package ui
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/jroimartin/gocui"
 	"github.com/logrusorgru/aurora"
 
-	pl "go-tvguide/internal/pkg/playlists"
+	tvguide "go-tvguide/pkg/tvguide"
 )
 
 const (
@@ -42,6 +43,12 @@ const (
 	viewProgramme  = "programme_view"
 	titleProgramme = "Programme"
 
+	viewPersonInput  = "person_input_view"
+	titlePersonInput = "Person"
+
+	viewPerson  = "person_view"
+	titlePerson = "Programmes with"
+
 	captionUndefined = "<undefined>"
 )
 
@@ -49,33 +56,67 @@ var (
 	groups   *VirtualListBox
 	channels *VirtualListBox
 	guide    *VirtualListBox
-	playlist *pl.Playlist
-	tvg      *pl.Guide
-	lang     string
+	playlist IPlaylist
+	tvg      IGuide
+	langs    tvguide.Languages
 	curview  *gocui.View
+	guideDay time.Time
 )
 
+// guidePastTime - the programmes of the current day are displayed since this time ago
+const guidePastTime = 4 * time.Hour
+
+// IPlaylist - the playlist shown by the viewer
+type IPlaylist interface {
+	Groups() ([]string, error)
+	Channels(group string) ([]*tvguide.PlaylistItem, error)
+}
+
+// IGuide - the tv guide shown by the viewer
+type IGuide interface {
+	Languages() (tvguide.Languages, error)
+	ChannelGuideQuery(cid string, q *tvguide.GuideQuery) ([]*tvguide.Programme, error)
+	ProgrammeDescription(pid int, langs tvguide.Languages) (*tvguide.ProgrammeDescription, error)
+	ProgrammesByPerson(name string, roles []string, from, to time.Time) ([]*tvguide.PersonProgramme, error)
+}
+
 // NewPlaylistViewer returns the iptv playlist viewer
-func NewPlaylistViewer(p *pl.Playlist, g *pl.Guide) (*gocui.Gui, error) {
+func NewPlaylistViewer(p IPlaylist, g IGuide) (*gocui.Gui, error) {
 
 	playlist = p
 	tvg = g
 
-	lang = tvg.DefaultProgrammeLanguage()
+	guideDay = CurrentTime()
+
+	var err error
 
-	group, err := playlist.Group(0)
+	if langs, err = tvg.Languages(); err != nil {
+		return nil, err
+	}
+
+	names, err := playlist.Groups()
 
 	if err != nil {
 		return nil, err
 	}
 
-	c, err := playlist.Channel(0, group)
+	if len(names) == 0 {
+		return nil, fmt.Errorf("%w: group #0", tvguide.ErrNotFound)
+	}
+
+	group := names[0]
+
+	items, err := playlist.Channels(group)
 
 	if err != nil {
 		return nil, err
 	}
 
-	cid := c.ID
+	if len(items) == 0 {
+		return nil, fmt.Errorf("%w: channel #0 of the group %q", tvguide.ErrNotFound, group)
+	}
+
+	cid := items[0].ID
 
 	gui, err := gocui.NewGui(gocui.OutputNormal)
 
@@ -84,7 +125,10 @@ func NewPlaylistViewer(p *pl.Playlist, g *pl.Guide) (*gocui.Gui, error) {
 	}
 
 	gui.SetManagerFunc(layout)
-	setKeyBindings(gui)
+
+	if err = setKeyBindings(gui); err != nil {
+		return gui, err
+	}
 
 	gw, h := sizeOfGroupsView(gui)
 	view, err := gui.SetView(viewGroups, 0, 0, gw, h-1)
@@ -139,7 +183,7 @@ func NewPlaylistViewer(p *pl.Playlist, g *pl.Guide) (*gocui.Gui, error) {
 
 		t := CurrentTime()
 
-		if err := loadChannelGuide(tvg, cid, lang, t); err != nil {
+		if err := loadChannelGuide(tvg, cid, langs, t); err != nil {
 			return err
 		}
 
@@ -164,7 +208,7 @@ func getGroupText(view *gocui.View, item interface{}) string {
 
 func getChannelText(view *gocui.View, item interface{}) string {
 
-	if pitem, ok := item.(*pl.PlaylistItem); ok {
+	if pitem, ok := item.(*tvguide.PlaylistItem); ok {
 		return pitem.Name
 	}
 
@@ -173,7 +217,7 @@ func getChannelText(view *gocui.View, item interface{}) string {
 
 func getGuideText(view *gocui.View, item interface{}) string {
 
-	if p, ok := item.(*pl.Programme); ok {
+	if p, ok := item.(*tvguide.Programme); ok {
 
 		t := CurrentTime()
 		text := fmt.Sprintf("%02d.%02d - %02d.%02d %s", p.StartHour(), p.StartMinute(),
@@ -17,12 +17,25 @@
 package loaders
 
 import (
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"time"
 )
 
+// HTTPStatusError - the server has responded with the status other than 200 OK
+type HTTPStatusError struct {
+	URL        string
+	StatusCode int
+	Status     string
+}
+
+// Error returns text of the error
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("downloading %s: %s", e.URL, e.Status)
+}
+
 // DownloadStartEvent - an event that fires before the start of the download
 type DownloadStartEvent func()
 
@@ -36,6 +49,9 @@ type DownloadProgressEvent func(complete uint64)
 type Downloader struct {
 	complete uint64
 
+	// Header - the headers of the request, e.g. User-Agent or Authorization
+	Header http.Header
+
 	OnStart    DownloadStartEvent
 	OnDone     DownloadDoneEvent
 	OnProgress DownloadProgressEvent
@@ -78,7 +94,17 @@ func (d *Downloader) Run(url string) ([]byte, error) {
 	emptyData := make([]byte, 0)
 	var httpClient = &http.Client{Timeout: time.Second * 10}
 
-	resp, err := httpClient.Get(url)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+
+	if err != nil {
+		return emptyData, err
+	}
+
+	for name, values := range d.Header {
+		req.Header[name] = values
+	}
+
+	resp, err := httpClient.Do(req)
 
 	if err != nil {
 		return emptyData, err
@@ -86,6 +112,10 @@ func (d *Downloader) Run(url string) ([]byte, error) {
 
 	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		return emptyData, &HTTPStatusError{URL: url, StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+
 	d.start()
 
 	data, err := ioutil.ReadAll(io.TeeReader(resp.Body, d))
@@ -0,0 +1,69 @@
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package loaders
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDownloaderStatus(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if r.URL.Path == "/private.m3u" && r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+
+		if r.URL.Path != "/playlist.m3u" && r.URL.Path != "/private.m3u" {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Write([]byte("#EXTM3U\n"))
+	}))
+
+	defer server.Close()
+
+	data, err := new(HTTPLoader).Load(server.URL + "/playlist.m3u")
+
+	if err != nil || string(data) != "#EXTM3U\n" {
+		t.Errorf("Load() = %q, %v", data, err)
+	}
+
+	loader := new(HTTPLoader)
+
+	if _, err = loader.Load(server.URL + "/playlist.m3u"); err != nil || loader.Stats().Bytes != 8 {
+		t.Errorf("Stats() = %+v, %v, want 8 bytes", loader.Stats(), err)
+	}
+
+	var e *HTTPStatusError
+
+	if _, err = new(HTTPLoader).Load(server.URL + "/guide.xml"); !errors.As(err, &e) || e.StatusCode != http.StatusNotFound {
+		t.Errorf("Load() = %v, want the status error 404", err)
+	}
+
+	loader = new(HTTPLoader)
+	loader.Header = http.Header{"Authorization": {"Bearer token"}}
+
+	if _, err = loader.Load(server.URL + "/private.m3u"); err != nil {
+		t.Errorf("Load() = %v, want the headers sent", err)
+	}
+}
@@ -17,23 +17,38 @@
 package loaders
 
 import (
+	"errors"
 	"io/ioutil"
 	"net/url"
 	"os"
+	"time"
 )
 
+// ErrInvalidPath - the path is neither the existing file nor the URL
+var ErrInvalidPath = errors.New("invalid path of the playlist or the guide")
+
 // ILoader interface of playlist loaders
 type ILoader interface {
 	Load(path string) ([]byte, error)
+	// Stats returns the counters of the last loading
+	Stats() LoadStats
+}
+
+// LoadStats contains the counters of the loading
+type LoadStats struct {
+	Bytes    int64         `json:"bytes"`
+	Duration time.Duration `json:"duration_ns"`
 }
 
 // FileLoader - object for loading playlist data from the file
 type FileLoader struct {
+	stats LoadStats
 }
 
 // HTTPLoader - object for downloading playlist data from the remote server
 type HTTPLoader struct {
 	Downloader
+	stats LoadStats
 }
 
 type downloadResult struct {
@@ -41,7 +56,7 @@ type downloadResult struct {
 	err  error
 }
 
-// Loader return playlist loader for the specified path of the playlist
+// Loader return playlist loader for the specified path of the playlist, nil if the path is invalid
 func Loader(path string) ILoader {
 
 	if _, err := os.Stat(path); err == nil {
@@ -58,6 +73,21 @@ func Loader(path string) ILoader {
 // Load returns data of the playlist with specified file path
 func (loader *FileLoader) Load(path string) ([]byte, error) {
 
+	started := time.Now()
+
+	data, err := loader.load(path)
+	loader.stats = LoadStats{Bytes: int64(len(data)), Duration: time.Since(started)}
+
+	return data, err
+}
+
+// Stats returns the counters of the last loading
+func (loader *FileLoader) Stats() LoadStats {
+	return loader.stats
+}
+
+func (loader *FileLoader) load(path string) ([]byte, error) {
+
 	data := make([]byte, 0)
 
 	f, err := os.Open(path)
@@ -77,5 +107,16 @@ func (loader *FileLoader) Load(path string) ([]byte, error) {
 
 // Load returns data of the playlist with specified URI
 func (loader *HTTPLoader) Load(path string) ([]byte, error) {
-	return loader.Run(path)
+
+	started := time.Now()
+
+	data, err := loader.Run(path)
+	loader.stats = LoadStats{Bytes: int64(len(data)), Duration: time.Since(started)}
+
+	return data, err
+}
+
+// Stats returns the counters of the last loading
+func (loader *HTTPLoader) Stats() LoadStats {
+	return loader.stats
 }
@@ -0,0 +1,106 @@
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package playlists
+
+import (
+	"time"
+
+	xmltv "go-tvguide/pkg/xmltv"
+)
+
+// IPlaylistBackend - common storage interface of the playlist
+type IPlaylistBackend interface {
+	// BeginPlaylist starts the reading of the playlist replacing the stored one
+	BeginPlaylist() error
+	// AppendPlaylistItem appends the channel to the playlist being read
+	AppendPlaylistItem(item *PlaylistItem) error
+	// EndPlaylist completes the reading of the playlist. The stored playlist is kept if the reading
+	// has failed, the error of the reading is returned then
+	EndPlaylist(failed error) error
+
+	Groups() ([]string, error)
+	Channels(group string) ([]*PlaylistItem, error)
+}
+
+// IGuideBackend - common storage interface of the tv guide
+type IGuideBackend interface {
+	// BeginGuide starts the reading of the tv guide replacing the stored one
+	BeginGuide() error
+	// AppendChannel appends the channel to the tv guide being read
+	AppendChannel(c *xmltv.XMLTVChannel) error
+	// AppendProgramme appends the programme to the tv guide being read
+	AppendProgramme(p *xmltv.XMLTVProgramme) error
+	// EndGuide completes the reading of the tv guide. The stored guide is kept if the reading
+	// has failed, the error of the reading is returned then
+	EndGuide(failed error) error
+
+	ChannelGuideQuery(cid string, q *GuideQuery) ([]*Programme, error)
+	ProgrammeDescription(pid int, langs Languages) (*ProgrammeDescription, error)
+}
+
+// IBackend - common storage interface of the playlist and its tv guide
+type IBackend interface {
+	IPlaylistBackend
+	IGuideBackend
+}
+
+// ReadPlaylist reads content of the playlist into the backend
+func ReadPlaylist(b IPlaylistBackend, data []byte, parser IPlaylistParser) (err error) {
+
+	if parser == nil {
+		return ErrUnknownFormat
+	}
+
+	if err = b.BeginPlaylist(); err != nil {
+		return
+	}
+
+	defer func() {
+		err = b.EndPlaylist(err)
+	}()
+
+	return parser.AsyncParse(data, b.AppendPlaylistItem)
+}
+
+// ReadGuide reads content of the tv guide into the backend
+func ReadGuide(b IGuideBackend, data []byte, parser IGuideParser) (report *ImportReport, err error) {
+
+	report = &ImportReport{}
+
+	if parser == nil {
+		return report, ErrUnknownFormat
+	}
+
+	report.Stats.Bytes = int64(len(data))
+	started := time.Now()
+
+	if err = b.BeginGuide(); err != nil {
+		return
+	}
+
+	defer func() {
+
+		parsed := time.Now()
+		err = b.EndGuide(err)
+
+		report.Stats.Parse, report.Stats.Finish = parsed.Sub(started), time.Since(parsed)
+	}()
+
+	err = parseGuide(data, parser, nil, report, b.AppendChannel, b.AppendProgramme)
+
+	return
+}
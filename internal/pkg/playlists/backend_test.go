@@ -0,0 +1,331 @@
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package playlists
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	xmltv "go-tvguide/pkg/xmltv"
+)
+
+const testBackendPlaylist = `#EXTM3U
+#EXTINF:-1 tvg-name="News" group-title="News",News
+http://example.com/news
+#EXTINF:-1 tvg-name="Weather" group-title="News",Weather
+http://example.com/weather
+#EXTINF:-1 tvg-name="Sport" group-title="Sport",Sport
+http://example.com/sport
+`
+
+const testBackendGuide = `<?xml version="1.0" encoding="UTF-8"?>
+<tv>
+<channel id="1"><display-name lang="en">News</display-name><display-name lang="ru">Новости</display-name></channel>
+<channel id="2"><display-name lang="en">Sport</display-name></channel>
+<programme start="20181027180000 +0000" stop="20181027190000 +0000" channel="1">
+<title lang="en">Evening news</title><title lang="ru">Вечерние новости</title>
+<sub-title lang="en">Headlines</sub-title><desc lang="en">The news of the day</desc><desc lang="ru">Новости дня</desc>
+<credits><director>Jane Doe</director><director></director><actor role="Host">John Smith</actor></credits>
+<category lang="en">News</category><category lang="en">Current affairs</category><category lang="ru">Новости</category>
+<country lang="en">UK</country><country lang="en">UK</country>
+</programme>
+<programme start="20181027190000 +0000" stop="20181027200000 +0000" channel="1">
+<title lang="en">Weather</title><category lang="en">Météo</category>
+</programme>
+<programme start="20181027200000 +0000" channel="1"><title lang="ru">Ночь</title></programme>
+<programme start="20181027180000 +0000" stop="20181027200000 +0000" channel="2"><title lang="en">Football</title></programme>
+</tv>`
+
+// testBackend is the conformance test of the backends
+func testBackend(t *testing.T, newBackend func(t *testing.T) IBackend) {
+
+	b := newBackend(t)
+
+	if err := ReadPlaylist(b, []byte(testBackendPlaylist), &M3UPlaylistParser{}); err != nil {
+		t.Fatalf("ReadPlaylist() = %v", err)
+	}
+
+	if groups, err := b.Groups(); err != nil || !reflect.DeepEqual(groups, []string{"News", "Sport"}) {
+		t.Errorf("Groups() = %q, %v", groups, err)
+	}
+
+	items, err := b.Channels("News")
+
+	if err != nil {
+		t.Fatalf("Channels() = %v", err)
+	}
+
+	names := make([]string, 0)
+
+	for _, item := range items {
+		names = append(names, item.ID+" "+item.Name+" "+item.URL)
+	}
+
+	if want := []string{"News News http://example.com/news", "Weather Weather http://example.com/weather"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Channels() = %q, want %q", names, want)
+	}
+
+	// the stop times of the past years are patched by the sqlite backend
+	year := time.Now().Year() + 1
+	data := strings.Replace(testBackendGuide, "2018", fmt.Sprint(year), -1)
+
+	report, err := ReadGuide(b, []byte(data), &xmltv.XMLTVParser{})
+
+	if err != nil {
+		t.Fatalf("ReadGuide() = %v", err)
+	}
+
+	if report.Channels != 2 || report.Programmes != 4 {
+		t.Errorf("ReadGuide() = %d channels, %d programmes", report.Channels, report.Programmes)
+	}
+
+	clock := func(hour int) string {
+		return time.Date(year, 10, 27, hour, 0, 0, 0, time.UTC).Local().Format("15:04")
+	}
+
+	var tests = []struct {
+		cid   string
+		query GuideQuery
+		want  string
+	}{
+		{"News", GuideQuery{Languages: Languages{"en"}}, fmt.Sprintf("Evening news %s-%s|Weather %s-%s|Ночь %s-00:00",
+			clock(18), clock(19), clock(19), clock(20), clock(20))},
+		{"Новости", GuideQuery{Languages: Languages{"ru", "en"}}, fmt.Sprintf("Вечерние новости %s-%s|Weather %s-%s|Ночь %s-00:00",
+			clock(18), clock(19), clock(19), clock(20), clock(20))},
+		{"News", GuideQuery{Category: "METEO"}, fmt.Sprintf("Weather %s-%s", clock(19), clock(20))},
+		{"News", GuideQuery{Limit: 1, Offset: 1}, fmt.Sprintf("Weather %s-%s", clock(19), clock(20))},
+		{"News", GuideQuery{Offset: 5}, ""},
+		{"News", GuideQuery{From: time.Date(year, 10, 27, 19, 0, 0, 0, time.UTC),
+			To: time.Date(year, 10, 27, 20, 0, 0, 0, time.UTC)}, fmt.Sprintf("Weather %s-%s", clock(19), clock(20))},
+		{"Sport", GuideQuery{}, fmt.Sprintf("Football %s-%s", clock(18), clock(20))},
+		{"Unknown", GuideQuery{}, ""},
+	}
+
+	var pid int
+
+	for i, test := range tests {
+
+		programmes, err := b.ChannelGuideQuery(test.cid, &test.query)
+
+		if err != nil {
+			t.Fatalf("#%d: ChannelGuideQuery() = %v", i, err)
+		}
+
+		got := make([]string, 0)
+
+		for _, p := range programmes {
+
+			got = append(got, p.Title+" "+p.Start.Format("15:04")+"-"+p.Stop.Format("15:04"))
+
+			if p.Title == "Evening news" {
+				pid = p.PID
+			}
+		}
+
+		if strings.Join(got, "|") != test.want {
+			t.Errorf("#%d: ChannelGuideQuery(%q) = %q, want %q", i, test.cid, strings.Join(got, "|"), test.want)
+		}
+	}
+
+	pd, err := b.ProgrammeDescription(pid, Languages{"en"})
+
+	if err != nil {
+		t.Fatalf("ProgrammeDescription() = %v", err)
+	}
+
+	description := fmt.Sprintf("%s / %s / %s / %s / %s / %s / %s", pd.Title, pd.SubTitle, pd.Description,
+		pd.ProgrammeCategories(), pd.ProgrammeCountries(), pd.ProgrammeDirectors(), pd.ProgrammeActors())
+
+	if want := "Evening news / Headlines / The news of the day / Current affairs, News / UK / Jane Doe / " +
+		"John Smith (Host)"; description != want {
+		t.Errorf("ProgrammeDescription() = %q, want %q", description, want)
+	}
+
+	if pd, err = b.ProgrammeDescription(pid, Languages{"ru"}); err != nil || pd.Title != "Вечерние новости" ||
+		pd.Description != "Новости дня" || pd.ProgrammeCategories() != "Новости" {
+		t.Errorf("ProgrammeDescription(ru) = %q, %q, %q, %v", pd.Title, pd.Description, pd.ProgrammeCategories(), err)
+	}
+
+	if _, err = b.ProgrammeDescription(-1, nil); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ProgrammeDescription(-1) = %v, want ErrNotFound", err)
+	}
+
+	// the failed reading keeps the stored guide
+	if _, err = ReadGuide(b, []byte(data[:len(data)/2]), &xmltv.XMLTVParser{Strict: true}); err == nil {
+		t.Error("ReadGuide(truncated) = nil, want error")
+	}
+
+	if programmes, err := b.ChannelGuideQuery("Sport", &GuideQuery{}); err != nil || len(programmes) != 1 {
+		t.Errorf("ChannelGuideQuery() after the failed reading = %d programmes, %v", len(programmes), err)
+	}
+
+	// the reading replaces the stored playlist and guide
+	if err = ReadPlaylist(b, []byte(testBackendPlaylist), &M3UPlaylistParser{}); err != nil {
+		t.Fatalf("ReadPlaylist() again = %v", err)
+	}
+
+	if items, err = b.Channels("News"); err != nil || len(items) != 2 {
+		t.Errorf("Channels() after the second reading = %d items, %v", len(items), err)
+	}
+
+	if _, err = ReadGuide(b, []byte(data), &xmltv.XMLTVParser{}); err != nil {
+		t.Fatalf("ReadGuide() again = %v", err)
+	}
+
+	if programmes, err := b.ChannelGuideQuery("News", &GuideQuery{}); err != nil || len(programmes) != 3 {
+		t.Errorf("ChannelGuideQuery() after the second reading = %d programmes, %v", len(programmes), err)
+	}
+}
+
+func TestSQLiteBackend(t *testing.T) {
+
+	testBackend(t, func(t *testing.T) IBackend {
+
+		store := openTestStore(t, ":memory:", nil)
+
+		t.Cleanup(func() { store.Close() })
+
+		return store.Backend()
+	})
+}
+
+func TestMemoryBackend(t *testing.T) {
+
+	testBackend(t, func(t *testing.T) IBackend {
+		return NewMemoryBackend()
+	})
+}
+
+// testConcurrentReads queries the channel guide from several goroutines while the guide with three
+// and the guide with two programmes of the channel are read in turn. The queries must see one of them
+func testConcurrentReads(t *testing.T, read func(data []byte) error,
+	query func(cid string, q *GuideQuery) ([]*Programme, error)) {
+
+	weather := `<programme start="20181027190000 +0000" stop="20181027200000 +0000" channel="1">
+<title lang="en">Weather</title><category lang="en">Météo</category>
+</programme>
+`
+	year := fmt.Sprint(time.Now().Year() + 1)
+	full := strings.Replace(testBackendGuide, "2018", year, -1)
+	short := strings.Replace(strings.Replace(testBackendGuide, weather, "", 1), "2018", year, -1)
+
+	for _, test := range []struct {
+		data string
+		want int
+	}{{short, 2}, {full, 3}} {
+
+		if err := read([]byte(test.data)); err != nil {
+			t.Fatal(err)
+		}
+
+		if programmes, err := query("News", &GuideQuery{}); err != nil || len(programmes) != test.want {
+			t.Fatalf("ChannelGuideQuery() = %d programmes, %v, want %d", len(programmes), err, test.want)
+		}
+	}
+
+	var wg sync.WaitGroup
+
+	done := make(chan struct{})
+
+	for i := 0; i < 4; i++ {
+
+		wg.Add(1)
+
+		go func() {
+
+			defer wg.Done()
+
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				programmes, err := query("News", &GuideQuery{})
+
+				if err != nil {
+					t.Error(err)
+					return
+				}
+
+				if len(programmes) != 2 && len(programmes) != 3 {
+					t.Errorf("ChannelGuideQuery() = %d programmes while reading, want 2 or 3", len(programmes))
+					return
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 10; i++ {
+
+		data := full
+
+		if i%2 == 0 {
+			data = short
+		}
+
+		if err := read([]byte(data)); err != nil {
+			t.Error(err)
+			break
+		}
+	}
+
+	close(done)
+	wg.Wait()
+}
+
+func TestConcurrentReads(t *testing.T) {
+
+	t.Run("sqlite", func(t *testing.T) {
+
+		store := openTestStore(t, filepath.Join(t.TempDir(), "guide.db3"), &Options{MaxOpenConns: 4})
+
+		defer store.Close()
+
+		var mode string
+
+		if err := store.db.QueryRow(`PRAGMA journal_mode`).Scan(&mode); err != nil || mode != "wal" {
+			t.Errorf("journal_mode = %q, %v, want wal", mode, err)
+		}
+
+		g := store.Guide()
+
+		testConcurrentReads(t, func(data []byte) error {
+
+			_, err := g.Load("guide.xml", data, &xmltv.XMLTVParser{}, nil)
+			return err
+		}, g.ChannelGuideQuery)
+	})
+
+	t.Run("memory", func(t *testing.T) {
+
+		b := NewMemoryBackend()
+
+		testConcurrentReads(t, func(data []byte) error {
+
+			_, err := ReadGuide(b, data, &xmltv.XMLTVParser{})
+			return err
+		}, b.ChannelGuideQuery)
+	})
+}
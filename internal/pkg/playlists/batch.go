@@ -0,0 +1,127 @@
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package playlists
+
+import (
+	"database/sql"
+	"strings"
+)
+
+const (
+	// maxBatchVariables - the lowest SQLITE_MAX_VARIABLE_NUMBER of the supported sqlite versions
+	maxBatchVariables = 999
+	// maxBatchRows limits the number of rows inserted by the single statement
+	maxBatchRows = 200
+)
+
+// batch accumulates rows of the table and inserts them with multi-row INSERT statements
+type batch struct {
+	tx      *sql.Tx
+	table   string
+	columns []string
+	size    int
+	rows    int
+	total   int64
+	args    []interface{}
+	stmt    *sql.Stmt
+}
+
+func newBatch(tx *sql.Tx, table string, columns []string) *batch {
+
+	size := maxBatchVariables / len(columns)
+
+	if size > maxBatchRows {
+		size = maxBatchRows
+	}
+
+	return &batch{tx: tx, table: table, columns: columns, size: size,
+		args: make([]interface{}, 0, size*len(columns))}
+}
+
+// add appends the row to the batch and inserts the batch if it is full
+func (b *batch) add(values ...interface{}) error {
+
+	b.args = append(b.args, values...)
+	b.rows++
+	b.total++
+
+	if b.rows < b.size {
+		return nil
+	}
+
+	return b.flush()
+}
+
+// flush inserts the accumulated rows
+func (b *batch) flush() (err error) {
+
+	if b.rows == 0 {
+		return
+	}
+
+	if b.rows == b.size {
+
+		if b.stmt == nil {
+			if b.stmt, err = b.tx.Prepare(b.command(b.size)); err != nil {
+				return
+			}
+		}
+
+		_, err = b.stmt.Exec(b.args...)
+	} else {
+		_, err = b.tx.Exec(b.command(b.rows), b.args...)
+	}
+
+	b.args = b.args[:0]
+	b.rows = 0
+
+	return
+}
+
+func (b *batch) close() error {
+
+	if b.stmt != nil {
+		return b.stmt.Close()
+	}
+
+	return nil
+}
+
+// command returns INSERT statement for the specified number of rows
+func (b *batch) command(rows int) string {
+
+	var sb strings.Builder
+
+	row := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(b.columns)), ", ") + ")"
+
+	sb.WriteString("INSERT INTO ")
+	sb.WriteString(b.table)
+	sb.WriteString("(")
+	sb.WriteString(strings.Join(b.columns, ", "))
+	sb.WriteString(") VALUES ")
+
+	for i := 0; i < rows; i++ {
+
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+
+		sb.WriteString(row)
+	}
+
+	return sb.String()
+}
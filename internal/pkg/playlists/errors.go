@@ -0,0 +1,68 @@
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package playlists
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// ErrUnknownFormat - the format of the playlist or the tv guide data is not supported
+var ErrUnknownFormat = errors.New("unknown format of the playlist or the tv guide")
+
+// ErrInvalidArgument - the option of the query or the reading is invalid
+var ErrInvalidArgument = errors.New("invalid argument")
+
+// ErrNotFound - the requested item of the playlist or the programme of the guide does not exist
+var ErrNotFound = errors.New("not found")
+
+// ParseError describes the position of the playlist or the tv guide data that cannot be parsed
+type ParseError struct {
+	// Line - the number of the line starting from 1, zero if the data is not a text
+	Line int
+	// Offset - the offset of the data in bytes
+	Offset int64
+	Err    error
+}
+
+// Error returns text of the error
+func (e *ParseError) Error() string {
+
+	if e.Line > 0 {
+		return fmt.Sprintf("%v (line %d, offset %d)", e.Err, e.Line, e.Offset)
+	}
+
+	return fmt.Sprintf("%v (offset %d)", e.Err, e.Offset)
+}
+
+// Unwrap returns the reason of the error
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// lineAt returns the number of the line of the text data at the offset
+func lineAt(data []byte, offset int64) int {
+
+	if offset < 0 {
+		offset = 0
+	} else if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+
+	return bytes.Count(data[:offset], []byte{'\n'}) + 1
+}
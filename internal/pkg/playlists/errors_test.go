@@ -0,0 +1,92 @@
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package playlists
+
+import (
+	"bufio"
+	"errors"
+	"strings"
+	"testing"
+
+	xmltv "go-tvguide/pkg/xmltv"
+)
+
+func TestParseErrors(t *testing.T) {
+
+	var tests = []struct {
+		name   string
+		parse  func() error
+		line   int
+		offset int64
+		reason error
+	}{
+		{"empty playlist", func() error {
+			return (&M3UPlaylistParser{}).Parse(nil)
+		}, 1, 0, errEmptyPlaylist},
+		{"long playlist line", func() error {
+			return (&M3UPlaylistParser{}).Parse([]byte("#EXTM3U\n#EXTINF:-1,News\n" +
+				strings.Repeat("x", bufio.MaxScanTokenSize+1)))
+		}, 3, 24, bufio.ErrTooLong},
+		{"broken programme", func() error {
+			_, err := newTestGuide(t).Read([]byte(`<tv>
+<channel id="1"><display-name>News</display-name></channel>
+<programme start="bogus" channel="1"><title>News</title></programme>
+</tv>`), &xmltv.XMLTVParser{Strict: true}, nil)
+			return err
+		}, 3, 65, nil},
+	}
+
+	for _, test := range tests {
+
+		var e *ParseError
+
+		if err := test.parse(); !errors.As(err, &e) || e.Line != test.line || e.Offset != test.offset ||
+			(test.reason != nil && !errors.Is(err, test.reason)) {
+			t.Errorf("%s: error = %v, want the parse error at line %d, offset %d", test.name, err, test.line, test.offset)
+		}
+	}
+}
+
+func TestNotFound(t *testing.T) {
+
+	g := newTestGuide(t)
+	p := &Playlist{db: g.db, stmts: g.stmts}
+
+	if err := p.Read([]byte(testNowNextPlaylist), &M3UPlaylistParser{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := p.Group(100); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Group(100) = %v, want ErrNotFound", err)
+	}
+
+	if _, err := p.Channel(-1, "News"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Channel(-1) = %v, want ErrNotFound", err)
+	}
+
+	if _, err := g.ProgrammeDescription(1, nil); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ProgrammeDescription(1) = %v, want ErrNotFound", err)
+	}
+
+	if parser := GuideParser([]byte("not a guide"), false, 1); parser != nil {
+		t.Errorf("GuideParser() = %T, want nil", parser)
+	}
+
+	if err := p.Read([]byte("not a playlist"), PlaylistParser([]byte("not a playlist"))); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("Read() = %v, want ErrUnknownFormat", err)
+	}
+}
@@ -0,0 +1,246 @@
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package playlists
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	jtv "go-tvguide/pkg/jtv"
+	xmltv "go-tvguide/pkg/xmltv"
+)
+
+// SniffLen - the number of the first bytes of the data the format is detected by
+const SniffLen = 4096
+
+// PlaylistFormat describes the playlist format: the detection of the format by the first bytes
+// of the data and the constructor of its parser
+type PlaylistFormat struct {
+	Name  string
+	Sniff func(head []byte) bool
+	New   func() IPlaylistParser
+}
+
+// GuideFormat describes the tv guide format: the detection of the format by the first bytes
+// of the data and the constructor of its parser. Workers is the number of the parsing goroutines
+type GuideFormat struct {
+	Name  string
+	Sniff func(head []byte) bool
+	New   func(strict bool, workers int) IGuideParser
+}
+
+var formats struct {
+	mu        sync.RWMutex
+	playlists []*PlaylistFormat
+	guides    []*GuideFormat
+}
+
+func init() {
+
+	RegisterPlaylistFormat(&PlaylistFormat{Name: "m3u", Sniff: isM3U,
+		New: func() IPlaylistParser { return &M3UPlaylistParser{} }})
+
+	RegisterGuideFormat(&GuideFormat{Name: "jtv", Sniff: jtv.IsJTV,
+		New: func(strict bool, workers int) IGuideParser { return &jtv.Parser{Strict: strict} }})
+	RegisterGuideFormat(&GuideFormat{Name: "xmltv", Sniff: isXML,
+		New: func(strict bool, workers int) IGuideParser {
+			return &xmltv.XMLTVParser{Strict: strict, Workers: workers}
+		}})
+}
+
+// RegisterPlaylistFormat adds the playlist format to the detected ones, the formats are detected in
+// the order of registration. The format registered earlier with the same name is replaced
+func RegisterPlaylistFormat(f *PlaylistFormat) {
+
+	formats.mu.Lock()
+	defer formats.mu.Unlock()
+
+	for index, r := range formats.playlists {
+		if r.Name == f.Name {
+			formats.playlists[index] = f
+			return
+		}
+	}
+
+	formats.playlists = append(formats.playlists, f)
+}
+
+// RegisterGuideFormat adds the tv guide format to the detected ones, the formats are detected in
+// the order of registration. The format registered earlier with the same name is replaced
+func RegisterGuideFormat(f *GuideFormat) {
+
+	formats.mu.Lock()
+	defer formats.mu.Unlock()
+
+	for index, r := range formats.guides {
+		if r.Name == f.Name {
+			formats.guides[index] = f
+			return
+		}
+	}
+
+	formats.guides = append(formats.guides, f)
+}
+
+// PlaylistFormats returns the names of the registered playlist formats
+func PlaylistFormats() []string {
+
+	formats.mu.RLock()
+	defer formats.mu.RUnlock()
+
+	names := make([]string, 0, len(formats.playlists))
+
+	for _, f := range formats.playlists {
+		names = append(names, f.Name)
+	}
+
+	return names
+}
+
+// GuideFormats returns the names of the registered tv guide formats
+func GuideFormats() []string {
+
+	formats.mu.RLock()
+	defer formats.mu.RUnlock()
+
+	names := make([]string, 0, len(formats.guides))
+
+	for _, f := range formats.guides {
+		names = append(names, f.Name)
+	}
+
+	return names
+}
+
+// PlaylistFormatOf returns the name of the playlist format detected by the first bytes of the data,
+// empty if the format is unknown
+func PlaylistFormatOf(data []byte) string {
+
+	formats.mu.RLock()
+	defer formats.mu.RUnlock()
+
+	head := sniffHead(data)
+
+	for _, f := range formats.playlists {
+		if f.Sniff(head) {
+			return f.Name
+		}
+	}
+
+	return ""
+}
+
+// GuideFormatOf returns the name of the tv guide format detected by the first bytes of the data,
+// empty if the format is unknown
+func GuideFormatOf(data []byte) string {
+
+	formats.mu.RLock()
+	defer formats.mu.RUnlock()
+
+	head := sniffHead(data)
+
+	for _, f := range formats.guides {
+		if f.Sniff(head) {
+			return f.Name
+		}
+	}
+
+	return ""
+}
+
+// PlaylistParser returns the parser for the format of the playlist data or nil if the format is unknown
+func PlaylistParser(data []byte) IPlaylistParser {
+
+	parser, _ := PlaylistParserOf(PlaylistFormatOf(data))
+
+	return parser
+}
+
+// GuideParser returns parser for the format of the tv guide data or nil if the format is unknown.
+// Workers is used by XMLTV parser only
+func GuideParser(data []byte, strict bool, workers int) IGuideParser {
+
+	parser, _ := GuideParserOf(GuideFormatOf(data), strict, workers)
+
+	return parser
+}
+
+// PlaylistParserOf returns the parser of the playlist format with the name, ErrUnknownFormat
+// if the format is not registered or the name is empty
+func PlaylistParserOf(name string) (IPlaylistParser, error) {
+
+	formats.mu.RLock()
+	defer formats.mu.RUnlock()
+
+	for _, f := range formats.playlists {
+		if f.Name == name {
+			return f.New(), nil
+		}
+	}
+
+	if name == "" {
+		return nil, ErrUnknownFormat
+	}
+
+	return nil, fmt.Errorf("%w: playlist format %q", ErrUnknownFormat, name)
+}
+
+// GuideParserOf returns the parser of the tv guide format with the name, ErrUnknownFormat
+// if the format is not registered or the name is empty
+func GuideParserOf(name string, strict bool, workers int) (IGuideParser, error) {
+
+	formats.mu.RLock()
+	defer formats.mu.RUnlock()
+
+	for _, f := range formats.guides {
+		if f.Name == name {
+			return f.New(strict, workers), nil
+		}
+	}
+
+	if name == "" {
+		return nil, ErrUnknownFormat
+	}
+
+	return nil, fmt.Errorf("%w: tv guide format %q", ErrUnknownFormat, name)
+}
+
+// Sniff returns the first bytes of the stream the format is detected by, the returned reader
+// reads the whole stream including them
+func Sniff(r io.Reader) (head []byte, stream io.Reader, err error) {
+
+	br := bufio.NewReaderSize(r, SniffLen)
+
+	if head, err = br.Peek(SniffLen); errors.Is(err, io.EOF) {
+		err = nil
+	}
+
+	return head, br, err
+}
+
+// sniffHead returns the first bytes of the data the format is detected by
+func sniffHead(data []byte) []byte {
+
+	if len(data) > SniffLen {
+		return data[:SniffLen]
+	}
+
+	return data
+}
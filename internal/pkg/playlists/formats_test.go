@@ -0,0 +1,78 @@
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package playlists
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestFormats(t *testing.T) {
+
+	var tests = []struct {
+		name     string
+		data     []byte
+		playlist string
+		guide    string
+	}{
+		{"m3u", []byte("\n#EXTM3U\n#EXTINF:-1,News\nhttp://example.com/news\n"), "m3u", ""},
+		{"xmltv", []byte("\xef\xbb\xbf<?xml version=\"1.0\"?>\n<tv></tv>"), "", "xmltv"},
+		{"jtv", []byte("PK\x03\x04"), "", "jtv"},
+		{"long xmltv", append([]byte("<tv>"), bytes.Repeat([]byte(" "), 2*SniffLen)...), "", "xmltv"},
+		{"unknown", []byte("not a playlist"), "", ""},
+	}
+
+	for _, test := range tests {
+
+		if format := PlaylistFormatOf(test.data); format != test.playlist {
+			t.Errorf("%s: PlaylistFormatOf() = %q, want %q", test.name, format, test.playlist)
+		}
+
+		if format := GuideFormatOf(test.data); format != test.guide {
+			t.Errorf("%s: GuideFormatOf() = %q, want %q", test.name, format, test.guide)
+		}
+	}
+
+	if _, err := PlaylistParserOf("pls"); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("PlaylistParserOf() = %v, want ErrUnknownFormat", err)
+	}
+
+	RegisterPlaylistFormat(&PlaylistFormat{Name: "test", Sniff: func(head []byte) bool {
+		return bytes.HasPrefix(head, []byte("[test]"))
+	}, New: func() IPlaylistParser { return &M3UPlaylistParser{} }})
+
+	head, stream, err := Sniff(strings.NewReader("[test]\n" + strings.Repeat("x", 2*SniffLen)))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(head) != SniffLen {
+		t.Errorf("len(Sniff()) = %d, want %d", len(head), SniffLen)
+	}
+
+	if format := PlaylistFormatOf(head); format != "test" {
+		t.Errorf("PlaylistFormatOf() = %q, want \"test\"", format)
+	}
+
+	if data, err := io.ReadAll(stream); err != nil || len(data) != 7+2*SniffLen {
+		t.Errorf("ReadAll() = %d, %v, want the whole stream", len(data), err)
+	}
+}
@@ -19,6 +19,9 @@ package playlists
 import (
 	"database/sql"
 	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
 	"time"
 
 	xmltv "go-tvguide/pkg/xmltv"
@@ -27,1042 +30,472 @@ import (
 type gpatch struct {
 }
 
-// Guide content
+// Guide content. The guide is safe for concurrent use, the readers see the guide read earlier
+// until the new one is committed
 type Guide struct {
 	pdb
 	gpatch
-	db   *sql.DB
-	tx   *sql.Tx
-	stmt map[string]*sql.Stmt
+	db    *sql.DB
+	stmts *stmtCache
+	log   *slog.Logger
+
+	// mu guards the source of the stored guide and the settings
+	mu        sync.RWMutex
+	sid       int64
+	playlist  *Playlist
+	languages Languages
+	repair    *RepairPolicy
+
+	// wmu serializes the readings of the guide, the fields below belong to the reading
+	wmu     sync.Mutex
+	tx      *sql.Tx
+	batches map[string]*batch
+	cid     int64
+	pid     int64
+	readSID int64
 }
 
-var g *Guide
-
-var queries = map[string]string{
-	"cmdAppendGuideChannel":              cmdAppendGuideChannel,
-	"cmdUpdateGuideChannelID":            cmdUpdateGuideChannelID,
-	"cmdAppendChannelDisplayName":        cmdAppendChannelDisplayName,
-	"cmdAppendChannelURL":                cmdAppendChannelURL,
-	"cmdAppendGuideProgramme":            cmdAppendGuideProgramme,
-	"cmdUpdateGuideProgrammePID":         cmdUpdateGuideProgrammePID,
-	"cmdAppendProgrammeTitle":            cmdAppendProgrammeTitle,
-	"cmdAppendProgrammeSubTitle":         cmdAppendProgrammeSubTitle,
-	"cmdAppendProgrammeDesc":             cmdAppendProgrammeDesc,
-	"cmdAppendProgrammeDates":            cmdAppendProgrammeDates,
-	"cmdAppendProgrammeCategories":       cmdAppendProgrammeCategories,
-	"cmdAppendProgrammeKeywords":         cmdAppendProgrammeKeywords,
-	"cmdAppendProgrammeLanguage":         cmdAppendProgrammeLanguage,
-	"cmdAppendProgrammeOriginalLanguage": cmdAppendProgrammeOriginalLanguage,
-	"cmdAppendProgrammeCountries":        cmdAppendProgrammeCountries,
-	"cmdAppendProgrammeDirectors":        cmdAppendProgrammeDirectors,
-	"cmdAppendProgrammeWriters":          cmdAppendProgrammeWriters,
-	"cmdAppendProgrammeAdapters":         cmdAppendProgrammeAdapters,
-	"cmdAppendProgrammeProducers":        cmdAppendProgrammeProducers,
-	"cmdAppendProgrammeComposers":        cmdAppendProgrammeComposers,
-	"cmdAppendProgrammeEditors":          cmdAppendProgrammeEditors,
-	"cmdAppendProgrammePresenters":       cmdAppendProgrammePresenters,
-	"cmdAppendProgrammeCommentators":     cmdAppendProgrammeCommentators,
-	"cmdAppendProgrammeGuests":           cmdAppendProgrammeGuests,
-	"cmdAppendProgrammeActors":           cmdAppendProgrammeActors,
-	"cmdAppendProgrammeLength":           cmdAppendProgrammeLength,
-	"cmdAppendProgrammeIcon":             cmdAppendProgrammeIcon,
-	"cmdAppendProgrammeEpisodeNum":       cmdAppendProgrammeEpisodeNum,
-	"cmdAppendProgrammeVideo":            cmdAppendProgrammeVideo,
-	"cmdAppendProgrammeAudio":            cmdAppendProgrammeAudio,
-	"cmdAppendProgrammePreviouslyShown":  cmdAppendProgrammePreviouslyShown,
-	"cmdAppendProgrammePremiere":         cmdAppendProgrammePremiere,
-	"cmdAppendProgrammeLastChance":       cmdAppendProgrammeLastChance,
-	"cmdAppendProgrammeSubtitles":        cmdAppendProgrammeSubtitles,
-	"cmdAppendProgrammeRating":           cmdAppendProgrammeRating,
-	"cmdAppendProgrammeStarRating":       cmdAppendProgrammeStarRating,
-	"cmdAppendProgrammeReview":           cmdAppendProgrammeReview,
-	"cmdAppendProgrammeLangStat":         cmdAppendProgrammeLangStat}
-
 const (
-	cmdSelectDefaultLanguage = `SELECT lang FROM programme_lang_stat ORDER BY lang_count DESC LIMIT 1`
+	cmdSelectDefaultLanguage = `SELECT lang FROM programme_lang_stat WHERE sid = ? ORDER BY lang_count DESC LIMIT 1`
 
 	cmdSelectChannelGuide = `SELECT p.pid, datetime(p.start, 'localtime') AS start
-		, datetime(p.stop, 'localtime') AS stop, pt.title 
+		, datetime(p.stop, 'localtime') AS stop
+		, ifnull((SELECT pt.title FROM programme_titles AS pt WHERE pt.pid = p.pid
+			ORDER BY lang_rank(?1, pt.lang) LIMIT 1), '') AS title
 	FROM programme AS p
-		INNER JOIN channels AS c ON (p.channel_id = c.channel_id)
-			INNER JOIN channel_display_names AS cdn ON (cdn.cid = c.cid) AND (cdn.lang = ?)
-				INNER JOIN playlist AS pl ON (pl.id = cdn.display_name) AND (pl.id = ?)
-					INNER JOIN programme_titles AS pt ON (pt.pid = p.pid) AND (pt.lang = cdn.lang)
-	WHERE datetime(p.start, 'localtime') >= ?
-	ORDER BY p.start`
-)
-
-var dh = time.Duration(-4 * time.Hour)
-
-// CurrentGuide returns guide object
-func CurrentGuide() *Guide {
-
-	if g == nil {
-		g = &Guide{db: db}
-	}
-
-	return g
-}
-
-// Read reads content of the tv guide
-func (g *Guide) Read(data []byte, parser *xmltv.XMLTVParser) (err error) {
-
-	onHead := parser.OnHead
-	onChannel := parser.OnChannel
-	onProgramme := parser.OnProgramme
-
-	defer func() {
-		parser.OnHead = onHead
-		parser.OnChannel = onChannel
-		parser.OnProgramme = onProgramme
-	}()
-
-	tx, err := g.db.Begin()
-
-	if err != nil {
-		return
-	}
-
-	defer func() {
-
-		for _, stmt := range g.stmt {
-			stmt.Close()
-		}
-
-		g.stmt = make(map[string]*sql.Stmt, 0)
-
-		if err != nil {
-			tx.Rollback()
-			return
-		}
-
-		tx.Commit()
-	}()
-
-	g.tx = tx
-
-	g.stmt = map[string]*sql.Stmt{}
-
-	for key, command := range queries {
-
-		var stmt *sql.Stmt
-
-		stmt, err = tx.Prepare(command)
-
-		if err != nil {
-			return
-		}
-
-		g.stmt[key] = stmt
-	}
-
-	parser.OnChannel = func(ch *xmltv.XMLTVChannel) error {
-		return g.appendChannel(ch)
-	}
-
-	parser.OnProgramme = func(p *xmltv.XMLTVProgramme) error {
-		return g.appendProgramme(p)
-	}
-
-	if err = parser.Parse(data); err != nil {
-		return
-	}
-
-	if err = g.appendProgrammeLangStat(); err != nil {
-		return
-	}
-
-	if err = g.analyze(g.db, g.tx); err != nil {
-		return
-	}
-
-	err = g.patchProgrammeStopTime(g.db, g.tx, time.Now().Year())
-
-	return
-}
-
-func (g *Guide) appendChannel(c *xmltv.XMLTVChannel) (err error) {
-
-	if c == nil {
-		return errors.New("Guide.AppendChannel: cannot append an empty channel")
-	}
-
-	var cid int64
-
-	cs := g.stmt["cmdAppendGuideChannel"]
-	us := g.stmt["cmdUpdateGuideChannelID"]
-
-	res, err := cs.Exec(&c.ID)
-
-	if err != nil {
-		return
-	}
-
-	cid, err = res.LastInsertId()
-
-	if err != nil {
-		return
-	}
-
-	_, err = us.Exec(&cid, &cid)
-
-	if err != nil {
-		return
-	}
-
-	if len(c.DisplayName) > 0 {
-
-		dn := make([]*xmltv.XMLTVChannelDisplayName, len(c.DisplayName))
-
-		for index, d := range c.DisplayName {
-			dn[index] = &d
-		}
-
-		if err = g.appendChannelDisplayNames(cid, dn); err != nil {
-			return err
-		}
-	}
-
-	if len(c.URL) > 0 {
-
-		urls := make([]*xmltv.XMLTVChannelURL, len(c.URL))
-
-		for index, url := range c.URL {
-			urls[index] = &url
-		}
-
-		if err = g.appendChannelURL(cid, urls); err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
-
-func (g *Guide) appendChannelDisplayNames(cid int64, d []*xmltv.XMLTVChannelDisplayName) (err error) {
-
-	for _, dn := range d {
-		if _, err = g.stmt["cmdAppendChannelDisplayName"].Exec(&cid, &dn.Lang, &dn.Value); err != nil {
-			return err
-		}
-	}
-
-	return
-}
-
-func (g *Guide) appendChannelURL(cid int64, urls []*xmltv.XMLTVChannelURL) (err error) {
-
-	for _, url := range urls {
-		if _, err = g.stmt["cmdAppendChannelURL"].Exec(&cid, &url.Value); err != nil {
-			return
-		}
-	}
-
-	return
-}
-
-func (g *Guide) appendProgramme(p *xmltv.XMLTVProgramme) (err error) {
-
-	var pid int64
-
-	pid, err = g.appendProgrammeRecord(p)
-
-	if err != nil {
-		return
-	}
-
-	if err = g.checkAppendProgrammeTitle(pid, p); err != nil {
-		return
-	}
-
-	if err = g.checkAppendProgrammeSubTitle(pid, p); err != nil {
-		return
-	}
-
-	if err = g.checkAppendProgrammeDesc(pid, p); err != nil {
-		return
-	}
-
-	if err = g.checkAppendProgrammeActors(pid, p); err != nil {
-		return
-	}
-
-	if err = g.checkAppendProgrammeAdapters(pid, p); err != nil {
-		return
-	}
-
-	if err = g.checkAppendProgrammeCommentators(pid, p); err != nil {
-		return
-	}
-
-	if err = g.checkAppendProgrammeComposers(pid, p); err != nil {
-		return
-	}
-
-	if err = g.checkAppendProgrammeDirectors(pid, p); err != nil {
-		return
-	}
-
-	if err = g.checkAppendProgrammeEditors(pid, p); err != nil {
-		return
-	}
-
-	if err = g.checkAppendProgrammeGuests(pid, p); err != nil {
-		return
-	}
-
-	if err = g.checkAppendProgrammePresenters(pid, p); err != nil {
-		return
-	}
-
-	if err = g.checkAppendProgrammeProducers(pid, p); err != nil {
-		return
-	}
-
-	if err = g.checkAppendProgrammeWriters(pid, p); err != nil {
-		return
-	}
-
-	if err = g.checkAppendProgrammeDates(pid, p); err != nil {
-		return
-	}
-
-	if err = g.checkAppendProgrammeCategories(pid, p); err != nil {
-		return
-	}
-
-	if err = g.checkAppendProgrammeKeywords(pid, p); err != nil {
-		return
-	}
-
-	if err = g.checkAppendProgrammeLanguages(pid, p); err != nil {
-		return
-	}
-
-	if err = g.checkAppendProgrammeOriginalLanguages(pid, p); err != nil {
-		return
-	}
-
-	if err = g.checkAppendProgrammeLength(pid, p); err != nil {
-		return
-	}
-
-	if err = g.checkAppendProgrammeIcon(pid, p); err != nil {
-		return
-	}
-
-	if err = g.checkAppendProgrammeCountry(pid, p); err != nil {
-		return
-	}
-
-	if err = g.checkAppendProgrammeEpisodeNum(pid, p); err != nil {
-		return
-	}
-
-	if err = g.checkAppendProgrammeVideo(pid, p); err != nil {
-		return
-	}
-
-	if err = g.checkAppendProgrammeAudio(pid, p); err != nil {
-		return
-	}
-
-	if err = g.checkAppendProgrammePreviouslyShown(pid, p); err != nil {
-		return
-	}
-
-	if err = g.checkAppendProgrammePremiere(pid, p); err != nil {
-		return
-	}
-
-	if err = g.checkAppendProgrammeLastChance(pid, p); err != nil {
-		return
-	}
-
-	if err = g.checkAppendProgrammeSubtitles(pid, p); err != nil {
-		return
-	}
-
-	if err = g.checkAppendProgrammeRating(pid, p); err != nil {
-		return
-	}
-
-	if err = g.checkAppendProgrammeStarRating(pid, p); err != nil {
-		return
-	}
-
-	if err = g.checkAppendProgrammeReview(pid, p); err != nil {
-		return
-	}
-
-	return
-}
-
-func (g *Guide) checkAppendProgrammeTitle(pid int64, p *xmltv.XMLTVProgramme) (err error) {
-
-	if len(p.Title) > 0 {
-
-		titles := make([]*xmltv.XMLTVProgrammeTitle, len(p.Title))
-
-		for idx, t := range p.Title {
-			titles[idx] = &t
-		}
-
-		if err = g.appendProgrammeTitle(pid, titles); err != nil {
-			return
-		}
-	}
-
-	return
-}
-
-func (g *Guide) checkAppendProgrammeSubTitle(pid int64, p *xmltv.XMLTVProgramme) (err error) {
-
-	if len(p.SubTitle) > 0 {
-
-		subtitles := make([]*xmltv.XMLTVProgrammeSubTitle, len(p.SubTitle))
-
-		for idx, t := range p.SubTitle {
-			subtitles[idx] = &t
-		}
-
-		if err = g.appendProgrammeSubTitle(pid, subtitles); err != nil {
-			return
-		}
-	}
-
-	return
-}
-
-func (g *Guide) checkAppendProgrammeDesc(pid int64, p *xmltv.XMLTVProgramme) (err error) {
-
-	if len(p.Desc) > 0 {
-
-		desc := make([]*xmltv.XMLTVProgrammeDesc, len(p.Desc))
-
-		for idx, d := range p.Desc {
-			desc[idx] = &d
-		}
-
-		if err = g.appendProgrammeDesc(pid, desc); err != nil {
-			return
-		}
-	}
-
-	return
-}
-
-func (g *Guide) checkAppendProgrammeActors(pid int64, p *xmltv.XMLTVProgramme) (err error) {
-
-	if len(p.Credits.Actors) > 0 {
-
-		actors := make([]*xmltv.XMLTVProgrammeActor, len(p.Credits.Actors))
-
-		for idx, a := range p.Credits.Actors {
-			actors[idx] = &a
-		}
-
-		if err = g.appendProgrammeActors(pid, actors); err != nil {
-			return
-		}
-	}
-
-	return
-}
-
-func (g *Guide) checkAppendProgrammeAdapters(pid int64, p *xmltv.XMLTVProgramme) (err error) {
-
-	if len(p.Credits.Adapters) > 0 {
-
-		adapters := make([]*string, len(p.Credits.Adapters))
-
-		for idx, a := range p.Credits.Adapters {
-			adapters[idx] = &a
-		}
-
-		if err = g.appendProgrammeAdapters(pid, adapters); err != nil {
-			return
-		}
-	}
-
-	return
-}
-
-func (g *Guide) checkAppendProgrammeCommentators(pid int64, p *xmltv.XMLTVProgramme) (err error) {
-
-	if len(p.Credits.Commentators) > 0 {
-
-		commentators := make([]*string, len(p.Credits.Commentators))
-
-		for idx, c := range p.Credits.Commentators {
-			commentators[idx] = &c
-		}
-
-		if err = g.appendProgrammeCommentators(pid, commentators); err != nil {
-			return
-		}
-	}
-
-	return
-}
-
-func (g *Guide) checkAppendProgrammeComposers(pid int64, p *xmltv.XMLTVProgramme) (err error) {
-
-	if len(p.Credits.Composers) > 0 {
-
-		composers := make([]*string, len(p.Credits.Composers))
-
-		for idx, c := range p.Credits.Composers {
-			composers[idx] = &c
-		}
-
-		if err = g.appendProgrammeComposers(pid, composers); err != nil {
-			return
-		}
-	}
-
-	return
-}
-
-func (g *Guide) checkAppendProgrammeDirectors(pid int64, p *xmltv.XMLTVProgramme) (err error) {
-
-	if len(p.Credits.Directors) > 0 {
-
-		directors := make([]*string, len(p.Credits.Directors))
-
-		for idx, d := range p.Credits.Directors {
-			directors[idx] = &d
-		}
-
-		if err = g.appendProgrammeDirectors(pid, directors); err != nil {
-			return
-		}
-	}
-
-	return
-}
-
-func (g *Guide) checkAppendProgrammeEditors(pid int64, p *xmltv.XMLTVProgramme) (err error) {
-
-	if len(p.Credits.Editors) > 0 {
-
-		editors := make([]*string, len(p.Credits.Editors))
-
-		for idx, e := range p.Credits.Editors {
-			editors[idx] = &e
-		}
-
-		if err = g.appendProgrammeEditors(pid, editors); err != nil {
-			return
-		}
-	}
-
-	return
-}
-
-func (g *Guide) checkAppendProgrammeGuests(pid int64, p *xmltv.XMLTVProgramme) (err error) {
-
-	if len(p.Credits.Guests) > 0 {
-
-		guests := make([]*string, len(p.Credits.Guests))
-
-		for idx, gst := range p.Credits.Guests {
-			guests[idx] = &gst
-		}
-
-		if err = g.appendProgrammeGuests(pid, guests); err != nil {
-			return
-		}
-	}
-
-	return
-}
-
-func (g *Guide) checkAppendProgrammePresenters(pid int64, p *xmltv.XMLTVProgramme) (err error) {
-
-	if len(p.Credits.Presenters) > 0 {
-
-		presenters := make([]*string, len(p.Credits.Presenters))
-
-		for idx, pr := range p.Credits.Presenters {
-			presenters[idx] = &pr
-		}
-
-		if err = g.appendProgrammePresenters(pid, presenters); err != nil {
-			return
-		}
-	}
-
-	return
-}
-
-func (g *Guide) checkAppendProgrammeProducers(pid int64, p *xmltv.XMLTVProgramme) (err error) {
-
-	if len(p.Credits.Producers) > 0 {
-
-		producers := make([]*string, len(p.Credits.Producers))
-
-		for idx, pr := range p.Credits.Producers {
-			producers[idx] = &pr
-		}
-
-		if err = g.appendProgrammeProducers(pid, producers); err != nil {
-			return
-		}
-	}
-
-	return
-}
-
-func (g *Guide) checkAppendProgrammeWriters(pid int64, p *xmltv.XMLTVProgramme) (err error) {
-
-	if len(p.Credits.Writers) > 0 {
-
-		writers := make([]*string, len(p.Credits.Writers))
-
-		for idx, w := range p.Credits.Writers {
-			writers[idx] = &w
-		}
-
-		if err = g.appendProgrammeWriters(pid, writers); err != nil {
-			return
-		}
-	}
-
-	return
-}
-
-func (g *Guide) checkAppendProgrammeDates(pid int64, p *xmltv.XMLTVProgramme) (err error) {
-
-	if len(p.Dates) > 0 {
-
-		dates := make([]*string, len(p.Dates))
-
-		for idx, d := range p.Dates {
-			dates[idx] = &d
-		}
-
-		if err = g.appendProgrammeDates(pid, dates); err != nil {
-			return
-		}
-	}
-
-	return
-}
-
-func (g *Guide) checkAppendProgrammeCategories(pid int64, p *xmltv.XMLTVProgramme) (err error) {
-
-	if len(p.Categories) > 0 {
-
-		categories := make([]*xmltv.XMLTVProgrammeCategory, len(p.Categories))
-
-		for idx, c := range p.Categories {
-			categories[idx] = &c
-		}
-
-		if err = g.appendProgrammeCategories(pid, categories); err != nil {
-			return
-		}
-	}
-
-	return
-}
-
-func (g *Guide) checkAppendProgrammeKeywords(pid int64, p *xmltv.XMLTVProgramme) (err error) {
-
-	if len(p.Keywords) > 0 {
-
-		keywords := make([]*xmltv.XMLTVProgrammeKeyword, len(p.Keywords))
-
-		for idx, k := range p.Keywords {
-			keywords[idx] = &k
-		}
-
-		if err = g.appendProgrammeKeywords(pid, keywords); err != nil {
-			return
-		}
-	}
-
-	return
-}
-
-func (g *Guide) checkAppendProgrammeLanguages(pid int64, p *xmltv.XMLTVProgramme) (err error) {
-
-	if len(p.Languages) > 0 {
-
-		languages := make([]*xmltv.XMLTVProgrammeLanguage, len(p.Languages))
-
-		for idx, lang := range p.Languages {
-			languages[idx] = &lang
-		}
-
-		if err = g.appendProgrammeLanguages(pid, languages); err != nil {
-			return
-		}
-	}
-
-	return
-}
-
-func (g *Guide) checkAppendProgrammeOriginalLanguages(pid int64, p *xmltv.XMLTVProgramme) (err error) {
+		INNER JOIN channels AS c ON (p.channel_id = c.channel_id) AND (c.sid = p.sid)
+			INNER JOIN channel_display_names AS cdn ON (cdn.cid = c.cid) AND (cdn.display_name = ?2)
+	WHERE (p.sid = ?3) AND ((?4 = '') OR (datetime(p.start) >= ?4)) AND ((?5 = '') OR (datetime(p.start) < ?5))
+		AND ((?6 = '') OR EXISTS (SELECT pc.pid FROM programme_categories AS pc
+			WHERE (pc.pid = p.pid) AND (fold(ifnull(pc.category, '')) = fold(?6))))
+	GROUP BY p.pid
+	ORDER BY p.start
+	LIMIT ?7 OFFSET ?8`
+)
 
-	if len(p.OriginalLanguages) > 0 {
+var dh = time.Duration(-4 * time.Hour)
 
-		languages := make([]*xmltv.XMLTVProgrammeOriginalLanguage, len(p.OriginalLanguages))
+// ImportReport contains the result of the tv guide reading
+type ImportReport struct {
+	Channels           int
+	Programmes         int
+	Skipped            int
+	FilteredChannels   int
+	FilteredProgrammes int
+	Truncated          bool
+	Errors             []*xmltv.ElementError
 
-		for idx, lang := range p.OriginalLanguages {
-			languages[idx] = &lang
-		}
+	// Repaired - the number of the fixes of the programmes, Repairs - the first of them
+	Repaired int
+	Repairs  []*Repair
 
-		if err = g.appendProgrammeOriginalLanguages(pid, languages); err != nil {
-			return
-		}
-	}
+	// Unchanged is set when the source has been read already with the same content and filter
+	Unchanged bool
 
-	return
+	// Stats - the counters of the reading
+	Stats ImportStats
 }
 
-func (g *Guide) checkAppendProgrammeLength(pid int64, p *xmltv.XMLTVProgramme) (err error) {
-
-	if len(p.Length) > 0 {
+// maxReportErrors limits the number of errors kept in the import report
+const maxReportErrors = 1000
 
-		length := make([]*xmltv.XMLTVProgrammeLength, len(p.Length))
+func (r *ImportReport) appendError(e *xmltv.ElementError) {
 
-		for idx, l := range p.Length {
-			length[idx] = &l
-		}
+	if errors.Is(e, xmltv.ErrTruncated) {
+		r.Truncated = true
+	} else {
+		r.Skipped++
+	}
 
-		if err = g.appendProgrammeLength(pid, length); err != nil {
-			return
-		}
+	if len(r.Errors) < maxReportErrors {
+		r.Errors = append(r.Errors, e)
 	}
+}
 
-	return
+// Read reads content of the tv guide. The filter, if specified, restricts stored channels and programmes
+func (g *Guide) Read(data []byte, parser IGuideParser, filter *GuideFilter) (report *ImportReport, err error) {
+	return g.read(data, parser, filter, nil)
 }
 
-func (g *Guide) checkAppendProgrammeIcon(pid int64, p *xmltv.XMLTVProgramme) (err error) {
+// Load reads content of the tv guide from the source with the specified URL replacing the content
+// read from it earlier. The stored guide is used if neither the content of the source nor the filter
+// nor the repair policy has changed
+func (g *Guide) Load(url string, data []byte, parser IGuideParser, filter *GuideFilter) (report *ImportReport, err error) {
+
+	s, err := findSource(g.db, sourceGuide, url)
 
-	if len(p.Icon) > 0 {
+	if err != nil {
+		return
+	}
 
-		icons := make([]*xmltv.XMLTVProgrammeIcon, len(p.Icon))
+	hash, signature := hashOf(data), filter.signature()+g.repairPolicy().signature()
 
-		for idx, icon := range p.Icon {
-			icons[idx] = &icon
-		}
+	if s.id != 0 && s.hash == hash && s.filter == signature {
 
-		if err = g.appendProgrammeIcon(pid, icons); err != nil {
-			return
-		}
+		g.logger().Info("tv guide is up to date", "url", url)
+		g.publish(s.id)
+		return &ImportReport{Unchanged: true}, nil
 	}
 
-	return
-}
+	s.hash, s.filter = hash, signature
 
-func (g *Guide) checkAppendProgrammeCountry(pid int64, p *xmltv.XMLTVProgramme) (err error) {
+	return g.read(data, parser, filter, s)
+}
 
-	if len(p.Country) > 0 {
+func (g *Guide) read(data []byte, parser IGuideParser, filter *GuideFilter, s *source) (report *ImportReport, err error) {
 
-		countries := make([]*xmltv.XMLTVProgrammeCountry, len(p.Country))
+	report = &ImportReport{}
 
-		for idx, country := range p.Country {
-			countries[idx] = &country
-		}
+	if parser == nil {
+		return report, ErrUnknownFormat
+	}
 
-		if err = g.appendProgrammeCountry(pid, countries); err != nil {
+	if filter != nil {
+		if err = filter.prepare(); err != nil {
 			return
 		}
 	}
 
-	return
-}
+	report.Stats.Bytes = int64(len(data))
+	started := time.Now()
 
-func (g *Guide) checkAppendProgrammeEpisodeNum(pid int64, p *xmltv.XMLTVProgramme) (err error) {
+	if err = g.begin(s); err != nil {
+		return
+	}
 
-	if len(p.EpisodeNum) > 0 {
+	defer func() {
 
-		enums := make([]*xmltv.XMLTVProgrammeEpisodeNum, len(p.EpisodeNum))
+		parsed := time.Now()
+		err = g.end(report, err)
 
-		for idx, enum := range p.EpisodeNum {
-			enums[idx] = &enum
-		}
+		report.Stats.Parse, report.Stats.Finish = parsed.Sub(started), time.Since(parsed)
+		logImport(g.logger(), report, err)
+	}()
 
-		if err = g.appendProgrammeEpisodeNum(pid, enums); err != nil {
-			return
-		}
-	}
+	err = parseGuide(data, parser, filter, report, g.appendChannel, g.appendProgramme)
 
 	return
 }
 
-func (g *Guide) checkAppendProgrammeVideo(pid int64, p *xmltv.XMLTVProgramme) (err error) {
-
-	if len(p.Video) > 0 {
-
-		video := make([]*xmltv.XMLTVProgrammeVideo, len(p.Video))
-
-		for idx, v := range p.Video {
-			video[idx] = &v
-		}
+// logger returns the logger of the guide
+func (g *Guide) logger() *slog.Logger {
 
-		if err = g.appendProgrammeVideo(pid, video); err != nil {
-			return
-		}
+	if g.log == nil {
+		return discardLogger
 	}
 
-	return
+	return g.log
 }
 
-func (g *Guide) checkAppendProgrammeAudio(pid int64, p *xmltv.XMLTVProgramme) (err error) {
+// source returns the identifier of the source of the stored guide
+func (g *Guide) source() int64 {
 
-	if len(p.Audio) > 0 {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
 
-		audio := make([]*xmltv.XMLTVProgrammeAudio, len(p.Audio))
+	return g.sid
+}
 
-		for idx, a := range p.Audio {
-			audio[idx] = &a
-		}
+// publish makes the tv guide of the source visible to the readers
+func (g *Guide) publish(sid int64) {
 
-		if err = g.appendProgrammeAudio(pid, audio); err != nil {
-			return
-		}
-	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
 
-	return
+	g.sid = sid
 }
 
-func (g *Guide) checkAppendProgrammePreviouslyShown(pid int64, p *xmltv.XMLTVProgramme) (err error) {
+// begin starts the transaction replacing the tv guide of the source, if specified. The transaction
+// is the staging area of the guide, the other readings wait until it ends
+func (g *Guide) begin(s *source) (err error) {
 
-	if len(p.PreviouslyShown) > 0 {
+	g.wmu.Lock()
 
-		shown := make([]*xmltv.XMLTVProgrammePreviouslyShown, len(p.PreviouslyShown))
-
-		for idx, s := range p.PreviouslyShown {
-			shown[idx] = &s
-		}
+	tx, err := g.db.Begin()
 
-		if err = g.appendProgrammePreviouslyShown(pid, shown); err != nil {
-			return
-		}
+	if err != nil {
+		g.wmu.Unlock()
+		return
 	}
 
-	return
-}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			g.wmu.Unlock()
+		}
+	}()
 
-func (g *Guide) checkAppendProgrammePremiere(pid int64, p *xmltv.XMLTVProgramme) (err error) {
+	g.tx = tx
+	g.readSID = 0
 
-	if len(p.Premiere) > 0 {
+	// the content read without the source is kept by the source 0
+	if s == nil {
 
-		premiere := make([]*xmltv.XMLTVProgrammePremiere, len(p.Premiere))
+		if err = deleteGuideSource(tx, 0); err != nil {
+			return
+		}
+	} else {
 
-		for idx, prem := range p.Premiere {
-			premiere[idx] = &prem
+		if err = deleteGuideSource(tx, s.id); err != nil {
+			return
 		}
 
-		if err = g.appendProgrammePremiere(pid, premiere); err != nil {
+		if err = saveSource(tx, s); err != nil {
 			return
 		}
+
+		g.readSID = s.id
 	}
 
-	return
+	return g.beginBulkLoad()
 }
 
-func (g *Guide) checkAppendProgrammeLastChance(pid int64, p *xmltv.XMLTVProgramme) (err error) {
-
-	if len(p.LastChance) > 0 {
+// end completes the tv guide read, commits it and makes it visible to the readers or rolls it back
+// if the reading has failed
+func (g *Guide) end(report *ImportReport, failed error) (err error) {
 
-		lc := make([]*xmltv.XMLTVProgrammmeLastChance, len(p.LastChance))
+	defer g.wmu.Unlock()
 
-		for idx, l := range p.LastChance {
-			lc[idx] = &l
-		}
+	defer func() {
 
-		if err = g.appendProgrammeLastChance(pid, lc); err != nil {
-			return
-		}
-	}
+		report.Stats.Rows = make(map[string]int64)
 
-	return
-}
+		for table, b := range g.batches {
 
-func (g *Guide) checkAppendProgrammeSubtitles(pid int64, p *xmltv.XMLTVProgramme) (err error) {
+			if b.total > 0 {
+				report.Stats.Rows[table] = b.total
+			}
 
-	if len(p.Subtitles) > 0 {
+			b.close()
+		}
 
-		subtitles := make([]*xmltv.XMLTVProgrammeSubtitles, len(p.Subtitles))
+		g.batches = nil
 
-		for idx, s := range p.Subtitles {
-			subtitles[idx] = &s
+		if err != nil {
+			g.tx.Rollback()
+			return
 		}
 
-		if err = g.appendProgrammeSubtitles(pid, subtitles); err != nil {
-			return
+		if err = g.tx.Commit(); err == nil {
+			g.publish(g.readSID)
 		}
-	}
+	}()
 
-	return
-}
+	if err = failed; err != nil {
+		return
+	}
 
-func (g *Guide) checkAppendProgrammeRating(pid int64, p *xmltv.XMLTVProgramme) (err error) {
+	if err = g.endBulkLoad(); err != nil {
+		return
+	}
 
-	if len(p.Rating) > 0 {
+	if err = g.patchProgrammeStopTime(g.db, g.tx, g.readSID, time.Now().Year()); err != nil {
+		return
+	}
 
-		rating := make([]*xmltv.XMLTVProgrammeRating, len(p.Rating))
+	if err = g.repairProgrammes(report); err != nil {
+		return
+	}
 
-		for idx, r := range p.Rating {
-			rating[idx] = &r
-		}
+	if err = g.appendProgrammeLangStat(); err != nil {
+		return
+	}
 
-		if err = g.appendProgrammeRating(pid, rating); err != nil {
-			return
-		}
+	if err = g.updateSearchIndex(); err != nil {
+		return
 	}
 
-	return
+	return g.analyze(g.db, g.tx)
 }
 
-func (g *Guide) checkAppendProgrammeStarRating(pid int64, p *xmltv.XMLTVProgramme) (err error) {
+// parseGuide parses the tv guide passing the channels and the programmes accepted by the filter,
+// if specified, to the append functions
+func parseGuide(data []byte, parser IGuideParser, filter *GuideFilter, report *ImportReport,
+	appendChannel func(*xmltv.XMLTVChannel) error, appendProgramme func(*xmltv.XMLTVProgramme) error) error {
 
-	if len(p.StarRating) > 0 {
+	events := parser.Events()
+	saved := *events
 
-		rating := make([]*xmltv.XMLTVProgrammeStarRating, len(p.StarRating))
+	defer func() {
+		*events = saved
+	}()
 
-		for idx, r := range p.StarRating {
-			rating[idx] = &r
-		}
+	events.OnChannel = func(ch *xmltv.XMLTVChannel) error {
 
-		if err = g.appendProgrammeStarRating(pid, rating); err != nil {
-			return
+		if filter != nil && !filter.acceptChannel(ch) {
+
+			report.FilteredChannels++
+			return nil
 		}
-	}
 
-	return
-}
+		report.Channels++
+		return appendChannel(ch)
+	}
 
-func (g *Guide) checkAppendProgrammeReview(pid int64, p *xmltv.XMLTVProgramme) (err error) {
+	events.Filter = nil
 
-	if len(p.Review) > 0 {
+	if filter != nil {
 
-		review := make([]*xmltv.XMLTVProgrammeReview, len(p.Review))
+		events.Filter = func(channel, start, stop string) bool {
 
-		for idx, r := range p.Review {
-			review[idx] = &r
-		}
+			if filter.acceptProgramme(channel, start, stop) {
+				return true
+			}
 
-		if err = g.appendProgrammeReview(pid, review); err != nil {
-			return
+			report.FilteredProgrammes++
+			return false
 		}
 	}
 
-	return
-}
+	events.OnProgramme = func(p *xmltv.XMLTVProgramme) error {
 
-func (g *Guide) appendProgrammeRecord(p *xmltv.XMLTVProgramme) (int64, error) {
+		report.Programmes++
+		return appendProgramme(p)
+	}
 
-	var pid int64 = -1
+	events.OnError = func(e *xmltv.ElementError) error {
 
-	cs := g.stmt["cmdAppendGuideProgramme"]
-	us := g.stmt["cmdUpdateGuideProgrammePID"]
+		report.appendError(e)
+		return nil
+	}
 
-	start, err := xmltv.TimeOfProgramme(p.Start)
+	err := parser.Parse(data)
 
-	if err != nil {
-		return pid, err
-	}
+	// the errors of the guide elements are reported with their positions, the line numbers are known
+	// for the XMLTV data only
+	var e *xmltv.ElementError
 
-	stop, err := xmltv.TimeOfProgramme(p.Stop)
+	if errors.As(err, &e) {
 
-	if err != nil {
-		return pid, err
-	}
+		line := 0
 
-	res, err := cs.Exec(&p.Channel, &start, &stop, &p.PDCStart, &p.VPSStart,
-		&p.ShowView, &p.VideoPlus, &p.ClumpIdx)
+		if _, ok := parser.(*xmltv.XMLTVParser); ok {
+			line = lineAt(data, e.Offset)
+		}
 
-	if err != nil {
-		return pid, err
+		return &ParseError{Line: line, Offset: e.Offset, Err: err}
 	}
 
-	pid, err = res.LastInsertId()
+	return err
+}
+
+// beginBulkLoad prepares the batches of the guide tables, drops the indexes of the empty tables and
+// gets the last identifiers of the channels and programmes
+func (g *Guide) beginBulkLoad() (err error) {
 
-	if err != nil {
-		return pid, err
+	if err = g.tx.QueryRow(cmdSelectMaxChannelID).Scan(&g.cid); err != nil {
+		return
 	}
 
-	_, err = us.Exec(&pid, &pid)
+	if err = g.tx.QueryRow(cmdSelectMaxProgrammeID).Scan(&g.pid); err != nil {
+		return
+	}
 
-	return pid, err
-}
+	var exists bool
 
-func (g *Guide) appendProgrammeTitle(pid int64, titles []*xmltv.XMLTVProgrammeTitle) (err error) {
+	if err = g.tx.QueryRow(cmdSelectProgrammeExists).Scan(&exists); err != nil {
+		return
+	}
 
-	for _, t := range titles {
-		if _, err = g.stmt["cmdAppendProgrammeTitle"].Exec(&pid, &t.Lang, &t.Value); err != nil {
+	// the indexes are rebuilt after the load into the empty tables only, rebuilding them for
+	// the programmes of the other sources would cost more than the inserts into the indexes
+	if !exists {
+		if err = dropGuideIndexes(g.tx); err != nil {
 			return
 		}
 	}
 
-	return
-}
+	g.batches = make(map[string]*batch, len(guideTables))
 
-func (g *Guide) appendProgrammeSubTitle(pid int64, subtitles []*xmltv.XMLTVProgrammeSubTitle) (err error) {
-
-	for _, s := range subtitles {
-		if _, err = g.stmt["cmdAppendProgrammeSubTitle"].Exec(&pid, &s.Lang, &s.Value); err != nil {
-			return
-		}
+	for table, columns := range guideTables {
+		g.batches[table] = newBatch(g.tx, table, columns)
 	}
 
 	return
 }
 
-func (g *Guide) appendProgrammeDesc(pid int64, desc []*xmltv.XMLTVProgrammeDesc) (err error) {
+// endBulkLoad inserts the rest of the batches and creates the dropped indexes
+func (g *Guide) endBulkLoad() (err error) {
 
-	for _, d := range desc {
-		if _, err = g.stmt["cmdAppendProgrammeDesc"].Exec(&pid, &d.Lang, &d.Value); err != nil {
+	for _, b := range g.batches {
+		if err = b.flush(); err != nil {
 			return
 		}
 	}
 
-	return
+	return createGuideIndexes(g.tx)
 }
 
-func (g *Guide) appendProgrammeActors(pid int64, actors []*xmltv.XMLTVProgrammeActor) (err error) {
+func (g *Guide) insert(table string, values ...interface{}) error {
+	return g.batches[table].add(values...)
+}
 
-	for _, a := range actors {
-		if _, err = g.stmt["cmdAppendProgrammeActors"].Exec(&pid, &a.Name, &a.Role); err != nil {
-			return
-		}
+func (g *Guide) appendChannel(c *xmltv.XMLTVChannel) (err error) {
+
+	if c == nil {
+		return errors.New("Guide.AppendChannel: cannot append an empty channel")
 	}
 
-	return
-}
+	g.cid++
+	cid := g.cid
 
-func (g *Guide) appendProgrammeAdapters(pid int64, adapters []*string) (err error) {
+	if err = g.insert("channels", cid, g.readSID, c.ID); err != nil {
+		return
+	}
 
-	for _, a := range adapters {
-		if _, err = g.stmt["cmdAppendProgrammeAdapters"].Exec(&pid, &a); err != nil {
+	for _, dn := range c.DisplayName {
+		if err = g.insert("channel_display_names", cid, dn.Lang, dn.Value); err != nil {
 			return
 		}
 	}
 
-	return
-}
-
-func (g *Guide) appendProgrammeCommentators(pid int64, commentators []*string) (err error) {
-
-	for _, c := range commentators {
-		if _, err = g.stmt["cmdAppendProgrammeCommentators"].Exec(&pid, &c); err != nil {
+	for _, url := range c.URL {
+		if err = g.insert("channel_urls", cid, url.Value); err != nil {
 			return
 		}
 	}
 
-	return
+	return nil
+}
+
+// programmeAppenders append the programme details into the child tables of the programme
+var programmeAppenders = [...]func(g *Guide, pid int64, p *xmltv.XMLTVProgramme) error{
+	(*Guide).appendProgrammeTitle,
+	(*Guide).appendProgrammeSubTitle,
+	(*Guide).appendProgrammeDesc,
+	(*Guide).appendProgrammeCredits,
+	(*Guide).appendProgrammeDates,
+	(*Guide).appendProgrammeCategories,
+	(*Guide).appendProgrammeKeywords,
+	(*Guide).appendProgrammeLanguages,
+	(*Guide).appendProgrammeOriginalLanguages,
+	(*Guide).appendProgrammeLength,
+	(*Guide).appendProgrammeIcon,
+	(*Guide).appendProgrammeCountry,
+	(*Guide).appendProgrammeEpisodeNum,
+	(*Guide).appendProgrammeVideo,
+	(*Guide).appendProgrammeAudio,
+	(*Guide).appendProgrammePreviouslyShown,
+	(*Guide).appendProgrammePremiere,
+	(*Guide).appendProgrammeLastChance,
+	(*Guide).appendProgrammeSubtitles,
+	(*Guide).appendProgrammeRating,
+	(*Guide).appendProgrammeStarRating,
+	(*Guide).appendProgrammeReview,
 }
 
-func (g *Guide) appendProgrammeComposers(pid int64, composers []*string) (err error) {
+func (g *Guide) appendProgramme(p *xmltv.XMLTVProgramme) (err error) {
+
+	var pid int64
+
+	pid, err = g.appendProgrammeRecord(p)
+
+	if err != nil {
+		return
+	}
 
-	for _, c := range composers {
-		if _, err = g.stmt["cmdAppendProgrammeComposers"].Exec(&pid, &c); err != nil {
+	for _, appendDetails := range programmeAppenders {
+		if err = appendDetails(g, pid, p); err != nil {
 			return
 		}
 	}
@@ -1070,21 +503,26 @@ func (g *Guide) appendProgrammeComposers(pid int64, composers []*string) (err er
 	return
 }
 
-func (g *Guide) appendProgrammeDirectors(pid int64, directors []*string) (err error) {
+func (g *Guide) appendProgrammeRecord(p *xmltv.XMLTVProgramme) (int64, error) {
 
-	for _, d := range directors {
-		if _, err = g.stmt["cmdAppendProgrammeDirectors"].Exec(&pid, &d); err != nil {
-			return
-		}
+	start, stop, err := p.Times()
+
+	if err != nil {
+		return -1, err
 	}
 
-	return
+	g.pid++
+
+	err = g.insert("programme", g.pid, g.readSID, p.Channel, start, stop, p.PDCStart, p.VPSStart,
+		p.ShowView, p.VideoPlus, p.ClumpIdx)
+
+	return g.pid, err
 }
 
-func (g *Guide) appendProgrammeEditors(pid int64, editors []*string) (err error) {
+func (g *Guide) appendProgrammeTitle(pid int64, p *xmltv.XMLTVProgramme) (err error) {
 
-	for _, e := range editors {
-		if _, err = g.stmt["cmdAppendProgrammeEditors"].Exec(&pid, &e); err != nil {
+	for _, t := range p.Title {
+		if err = g.insert("programme_titles", pid, t.Lang, t.Value); err != nil {
 			return
 		}
 	}
@@ -1092,10 +530,10 @@ func (g *Guide) appendProgrammeEditors(pid int64, editors []*string) (err error)
 	return
 }
 
-func (g *Guide) appendProgrammeGuests(pid int64, guests []*string) (err error) {
+func (g *Guide) appendProgrammeSubTitle(pid int64, p *xmltv.XMLTVProgramme) (err error) {
 
-	for _, gst := range guests {
-		if _, err = g.stmt["cmdAppendProgrammeGuests"].Exec(&pid, &gst); err != nil {
+	for _, s := range p.SubTitle {
+		if err = g.insert("programme_sub_titles", pid, s.Lang, s.Value); err != nil {
 			return
 		}
 	}
@@ -1103,10 +541,10 @@ func (g *Guide) appendProgrammeGuests(pid int64, guests []*string) (err error) {
 	return
 }
 
-func (g *Guide) appendProgrammePresenters(pid int64, presenters []*string) (err error) {
+func (g *Guide) appendProgrammeDesc(pid int64, p *xmltv.XMLTVProgramme) (err error) {
 
-	for _, p := range presenters {
-		if _, err = g.stmt["cmdAppendProgrammePresenters"].Exec(&pid, &p); err != nil {
+	for _, d := range p.Desc {
+		if err = g.insert("programme_desc", pid, d.Lang, d.Value); err != nil {
 			return
 		}
 	}
@@ -1114,33 +552,44 @@ func (g *Guide) appendProgrammePresenters(pid int64, presenters []*string) (err
 	return
 }
 
-func (g *Guide) appendProgrammeProducers(pid int64, producers []*string) (err error) {
+func (g *Guide) appendProgrammeCredits(pid int64, p *xmltv.XMLTVProgramme) (err error) {
 
-	for _, p := range producers {
-		if _, err = g.stmt["cmdAppendProgrammeProducers"].Exec(&pid, &p); err != nil {
+	for _, a := range p.Credits.Actors {
+		if err = g.insert("programme_actors", pid, a.Name, a.Role); err != nil {
 			return
 		}
 	}
 
-	return
-}
-
-func (g *Guide) appendProgrammeWriters(pid int64, writers []*string) (err error) {
+	persons := [...]struct {
+		table string
+		names []string
+	}{
+		{"programme_adapters", p.Credits.Adapters},
+		{"programme_commentators", p.Credits.Commentators},
+		{"programme_composers", p.Credits.Composers},
+		{"programme_directors", p.Credits.Directors},
+		{"programme_editors", p.Credits.Editors},
+		{"programme_guests", p.Credits.Guests},
+		{"programme_presenters", p.Credits.Presenters},
+		{"programme_producers", p.Credits.Producers},
+		{"programme_writers", p.Credits.Writers},
+	}
 
-	for _, w := range writers {
-		if _, err = g.stmt["cmdAppendProgrammeWriters"].Exec(&pid, &w); err != nil {
-			return
+	for _, credit := range persons {
+		for _, name := range credit.names {
+			if err = g.insert(credit.table, pid, name); err != nil {
+				return
+			}
 		}
 	}
 
 	return
 }
 
-func (g *Guide) appendProgrammeDates(pid int64, dates []*string) (err error) {
+func (g *Guide) appendProgrammeDates(pid int64, p *xmltv.XMLTVProgramme) (err error) {
 
-	for _, d := range dates {
-
-		if _, err = g.stmt["cmdAppendProgrammeDates"].Exec(&pid, &d); err != nil {
+	for _, d := range p.Dates {
+		if err = g.insert("programme_dates", pid, d); err != nil {
 			return
 		}
 	}
@@ -1148,10 +597,10 @@ func (g *Guide) appendProgrammeDates(pid int64, dates []*string) (err error) {
 	return
 }
 
-func (g *Guide) appendProgrammeCategories(pid int64, categories []*xmltv.XMLTVProgrammeCategory) (err error) {
+func (g *Guide) appendProgrammeCategories(pid int64, p *xmltv.XMLTVProgramme) (err error) {
 
-	for _, c := range categories {
-		if _, err = g.stmt["cmdAppendProgrammeCategories"].Exec(&pid, &c.Lang, &c.Value); err != nil {
+	for _, c := range p.Categories {
+		if err = g.insert("programme_categories", pid, c.Lang, c.Value); err != nil {
 			return
 		}
 	}
@@ -1159,10 +608,10 @@ func (g *Guide) appendProgrammeCategories(pid int64, categories []*xmltv.XMLTVPr
 	return
 }
 
-func (g *Guide) appendProgrammeKeywords(pid int64, keywords []*xmltv.XMLTVProgrammeKeyword) (err error) {
+func (g *Guide) appendProgrammeKeywords(pid int64, p *xmltv.XMLTVProgramme) (err error) {
 
-	for _, k := range keywords {
-		if _, err = g.stmt["cmdAppendProgrammeKeywords"].Exec(&pid, &k.Lang, &k.Value); err != nil {
+	for _, k := range p.Keywords {
+		if err = g.insert("programme_keywords", pid, k.Lang, k.Value); err != nil {
 			return
 		}
 	}
@@ -1170,10 +619,10 @@ func (g *Guide) appendProgrammeKeywords(pid int64, keywords []*xmltv.XMLTVProgra
 	return
 }
 
-func (g *Guide) appendProgrammeLanguages(pid int64, languages []*xmltv.XMLTVProgrammeLanguage) (err error) {
+func (g *Guide) appendProgrammeLanguages(pid int64, p *xmltv.XMLTVProgramme) (err error) {
 
-	for _, lang := range languages {
-		if _, err = g.stmt["cmdAppendProgrammeLanguage"].Exec(&pid, &lang.Lang, &lang.Value); err != nil {
+	for _, lang := range p.Languages {
+		if err = g.insert("programme_languages", pid, lang.Lang, lang.Value); err != nil {
 			return
 		}
 	}
@@ -1181,10 +630,10 @@ func (g *Guide) appendProgrammeLanguages(pid int64, languages []*xmltv.XMLTVProg
 	return
 }
 
-func (g *Guide) appendProgrammeOriginalLanguages(pid int64, languages []*xmltv.XMLTVProgrammeOriginalLanguage) (err error) {
+func (g *Guide) appendProgrammeOriginalLanguages(pid int64, p *xmltv.XMLTVProgramme) (err error) {
 
-	for _, lang := range languages {
-		if _, err = g.stmt["cmdAppendProgrammeOriginalLanguage"].Exec(&pid, &lang.Lang, &lang.Value); err != nil {
+	for _, lang := range p.OriginalLanguages {
+		if err = g.insert("programme_original_languages", pid, lang.Lang, lang.Value); err != nil {
 			return
 		}
 	}
@@ -1192,10 +641,10 @@ func (g *Guide) appendProgrammeOriginalLanguages(pid int64, languages []*xmltv.X
 	return
 }
 
-func (g *Guide) appendProgrammeLength(pid int64, length []*xmltv.XMLTVProgrammeLength) (err error) {
+func (g *Guide) appendProgrammeLength(pid int64, p *xmltv.XMLTVProgramme) (err error) {
 
-	for _, l := range length {
-		if _, err = g.stmt["cmdAppendProgrammeLength"].Exec(&pid, &l.Value, &l.Units); err != nil {
+	for _, l := range p.Length {
+		if err = g.insert("programme_length", pid, l.Value, l.Units); err != nil {
 			return
 		}
 	}
@@ -1203,10 +652,10 @@ func (g *Guide) appendProgrammeLength(pid int64, length []*xmltv.XMLTVProgrammeL
 	return
 }
 
-func (g *Guide) appendProgrammeIcon(pid int64, icons []*xmltv.XMLTVProgrammeIcon) (err error) {
+func (g *Guide) appendProgrammeIcon(pid int64, p *xmltv.XMLTVProgramme) (err error) {
 
-	for _, icon := range icons {
-		if _, err = g.stmt["cmdAppendProgrammeIcon"].Exec(&pid, &icon.Src, &icon.Width, &icon.Height); err != nil {
+	for _, icon := range p.Icon {
+		if err = g.insert("programme_icon", pid, icon.Src, icon.Width, icon.Height); err != nil {
 			return
 		}
 	}
@@ -1214,10 +663,10 @@ func (g *Guide) appendProgrammeIcon(pid int64, icons []*xmltv.XMLTVProgrammeIcon
 	return
 }
 
-func (g *Guide) appendProgrammeCountry(pid int64, countries []*xmltv.XMLTVProgrammeCountry) (err error) {
+func (g *Guide) appendProgrammeCountry(pid int64, p *xmltv.XMLTVProgramme) (err error) {
 
-	for _, country := range countries {
-		if _, err = g.stmt["cmdAppendProgrammeCountries"].Exec(&pid, &country.Lang, &country.Value); err != nil {
+	for _, country := range p.Country {
+		if err = g.insert("programme_countries", pid, country.Lang, country.Value); err != nil {
 			return
 		}
 	}
@@ -1225,10 +674,10 @@ func (g *Guide) appendProgrammeCountry(pid int64, countries []*xmltv.XMLTVProgra
 	return
 }
 
-func (g *Guide) appendProgrammeEpisodeNum(pid int64, enums []*xmltv.XMLTVProgrammeEpisodeNum) (err error) {
+func (g *Guide) appendProgrammeEpisodeNum(pid int64, p *xmltv.XMLTVProgramme) (err error) {
 
-	for _, enum := range enums {
-		if _, err = g.stmt["cmdAppendProgrammeEpisodeNum"].Exec(&pid, &enum.System, &enum.Value); err != nil {
+	for _, enum := range p.EpisodeNum {
+		if err = g.insert("programme_episode_num", pid, enum.System, enum.Value); err != nil {
 			return
 		}
 	}
@@ -1236,10 +685,10 @@ func (g *Guide) appendProgrammeEpisodeNum(pid int64, enums []*xmltv.XMLTVProgram
 	return
 }
 
-func (g *Guide) appendProgrammeVideo(pid int64, video []*xmltv.XMLTVProgrammeVideo) (err error) {
+func (g *Guide) appendProgrammeVideo(pid int64, p *xmltv.XMLTVProgramme) (err error) {
 
-	for _, v := range video {
-		if _, err = g.stmt["cmdAppendProgrammeVideo"].Exec(&pid, &v.Present, &v.Colour, &v.Aspect, &v.Quality); err != nil {
+	for _, v := range p.Video {
+		if err = g.insert("programme_video", pid, v.Present, v.Colour, v.Aspect, v.Quality); err != nil {
 			return
 		}
 	}
@@ -1247,10 +696,10 @@ func (g *Guide) appendProgrammeVideo(pid int64, video []*xmltv.XMLTVProgrammeVid
 	return
 }
 
-func (g *Guide) appendProgrammeAudio(pid int64, audio []*xmltv.XMLTVProgrammeAudio) (err error) {
+func (g *Guide) appendProgrammeAudio(pid int64, p *xmltv.XMLTVProgramme) (err error) {
 
-	for _, a := range audio {
-		if _, err = g.stmt["cmdAppendProgrammeAudio"].Exec(&pid, &a.Present, &a.Stereo); err != nil {
+	for _, a := range p.Audio {
+		if err = g.insert("programme_audio", pid, a.Present, a.Stereo); err != nil {
 			return
 		}
 	}
@@ -1258,10 +707,10 @@ func (g *Guide) appendProgrammeAudio(pid int64, audio []*xmltv.XMLTVProgrammeAud
 	return
 }
 
-func (g *Guide) appendProgrammePreviouslyShown(pid int64, shown []*xmltv.XMLTVProgrammePreviouslyShown) (err error) {
+func (g *Guide) appendProgrammePreviouslyShown(pid int64, p *xmltv.XMLTVProgramme) (err error) {
 
-	for _, s := range shown {
-		if _, err = g.stmt["cmdAppendProgrammePreviouslyShown"].Exec(&pid, &s.Start, &s.Channel); err != nil {
+	for _, s := range p.PreviouslyShown {
+		if err = g.insert("programme_previously_shown", pid, s.Start, s.Channel); err != nil {
 			return
 		}
 	}
@@ -1269,10 +718,10 @@ func (g *Guide) appendProgrammePreviouslyShown(pid int64, shown []*xmltv.XMLTVPr
 	return
 }
 
-func (g *Guide) appendProgrammePremiere(pid int64, premiere []*xmltv.XMLTVProgrammePremiere) (err error) {
+func (g *Guide) appendProgrammePremiere(pid int64, p *xmltv.XMLTVProgramme) (err error) {
 
-	for _, prem := range premiere {
-		if _, err = g.stmt["cmdAppendProgrammePremiere"].Exec(&pid, &prem.Lang, &prem.Value); err != nil {
+	for _, prem := range p.Premiere {
+		if err = g.insert("programme_premiere", pid, prem.Lang, prem.Value); err != nil {
 			return
 		}
 	}
@@ -1280,10 +729,10 @@ func (g *Guide) appendProgrammePremiere(pid int64, premiere []*xmltv.XMLTVProgra
 	return
 }
 
-func (g *Guide) appendProgrammeLastChance(pid int64, ls []*xmltv.XMLTVProgrammmeLastChance) (err error) {
+func (g *Guide) appendProgrammeLastChance(pid int64, p *xmltv.XMLTVProgramme) (err error) {
 
-	for _, l := range ls {
-		if _, err = g.stmt["cmdAppendProgrammeLastChance"].Exec(&pid, &l.Lang, &l.Value); err != nil {
+	for _, l := range p.LastChance {
+		if err = g.insert("programme_last_chance", pid, l.Lang, l.Value); err != nil {
 			return
 		}
 	}
@@ -1291,21 +740,12 @@ func (g *Guide) appendProgrammeLastChance(pid int64, ls []*xmltv.XMLTVProgrammme
 	return
 }
 
-func (g *Guide) appendProgrammeSubtitles(pid int64, subtitles []*xmltv.XMLTVProgrammeSubtitles) (err error) {
-
-	var stype string
-
-	for _, st := range subtitles {
-
-		stype = st.Type
+func (g *Guide) appendProgrammeSubtitles(pid int64, p *xmltv.XMLTVProgramme) (err error) {
 
-		if len(st.Language) > 0 {
-
-			for _, lang := range st.Language {
-
-				if _, err = g.stmt["cmdAppendProgrammeSubtitles"].Exec(&pid, &stype, &lang.Lang, &lang.Value); err != nil {
-					return
-				}
+	for _, st := range p.Subtitles {
+		for _, lang := range st.Language {
+			if err = g.insert("programme_subtitles", pid, st.Type, lang.Lang, lang.Value); err != nil {
+				return
 			}
 		}
 	}
@@ -1313,26 +753,10 @@ func (g *Guide) appendProgrammeSubtitles(pid int64, subtitles []*xmltv.XMLTVProg
 	return
 }
 
-func (g *Guide) appendProgrammeRating(pid int64, rating []*xmltv.XMLTVProgrammeRating) (err error) {
-
-	var (
-		s     string
-		value string
-		src   string
-		w     string
-		h     string
-	)
-
-	for _, r := range rating {
-
-		s = r.System
-
-		value = r.Value.Value
-		src = r.Icon.Src
-		w = r.Icon.Width
-		h = r.Icon.Height
+func (g *Guide) appendProgrammeRating(pid int64, p *xmltv.XMLTVProgramme) (err error) {
 
-		if _, err = g.stmt["cmdAppendProgrammeRating"].Exec(&pid, &s, &value, &src, &w, &h); err != nil {
+	for _, r := range p.Rating {
+		if err = g.insert("programme_rating", pid, r.System, r.Value.Value, r.Icon.Src, r.Icon.Width, r.Icon.Height); err != nil {
 			return
 		}
 	}
@@ -1340,26 +764,10 @@ func (g *Guide) appendProgrammeRating(pid int64, rating []*xmltv.XMLTVProgrammeR
 	return
 }
 
-func (g *Guide) appendProgrammeStarRating(pid int64, rating []*xmltv.XMLTVProgrammeStarRating) (err error) {
-
-	var (
-		s     string
-		value string
-		src   string
-		w     string
-		h     string
-	)
-
-	for _, r := range rating {
+func (g *Guide) appendProgrammeStarRating(pid int64, p *xmltv.XMLTVProgramme) (err error) {
 
-		s = r.System
-
-		value = r.Value.Value
-		src = r.Icon.Src
-		w = r.Icon.Width
-		h = r.Icon.Height
-
-		if _, err = g.stmt["cmdAppendProgrammeStarRating"].Exec(&pid, &s, &value, &src, &w, &h); err != nil {
+	for _, r := range p.StarRating {
+		if err = g.insert("programme_star_rating", pid, r.System, r.Value.Value, r.Icon.Src, r.Icon.Width, r.Icon.Height); err != nil {
 			return
 		}
 	}
@@ -1367,10 +775,10 @@ func (g *Guide) appendProgrammeStarRating(pid int64, rating []*xmltv.XMLTVProgra
 	return
 }
 
-func (g *Guide) appendProgrammeReview(pid int64, review []*xmltv.XMLTVProgrammeReview) (err error) {
+func (g *Guide) appendProgrammeReview(pid int64, p *xmltv.XMLTVProgramme) (err error) {
 
-	for _, r := range review {
-		if _, err = g.stmt["cmdAppendProgrammeReview"].Exec(&pid, &r.Type, &r.Source, r.Reviewer, &r.Lang, &r.Value); err != nil {
+	for _, r := range p.Review {
+		if err = g.insert("programme_review", pid, r.Type, r.Source, r.Reviewer, r.Lang, r.Value); err != nil {
 			return
 		}
 	}
@@ -1380,11 +788,15 @@ func (g *Guide) appendProgrammeReview(pid int64, review []*xmltv.XMLTVProgrammeR
 
 func (g *Guide) appendProgrammeLangStat() (err error) {
 
-	_, err = g.stmt["cmdAppendProgrammeLangStat"].Exec()
+	if _, err = g.tx.Exec(cmdDeleteProgrammeLangStat, g.readSID); err != nil {
+		return
+	}
+
+	_, err = g.tx.Exec(cmdAppendProgrammeLangStat, g.readSID)
 	return
 }
 
-func (gp *gpatch) patchProgrammeStopTime(db *sql.DB, tx *sql.Tx, yearLessThan int) (err error) {
+func (gp *gpatch) patchProgrammeStopTime(db *sql.DB, tx *sql.Tx, sid int64, yearLessThan int) (err error) {
 
 	var stmt *sql.Stmt
 
@@ -1393,7 +805,7 @@ func (gp *gpatch) patchProgrammeStopTime(db *sql.DB, tx *sql.Tx, yearLessThan in
 			return
 		}
 
-		_, err = stmt.Exec(&yearLessThan)
+		_, err = stmt.Exec(sid, &yearLessThan)
 
 		return
 	}
@@ -1402,51 +814,93 @@ func (gp *gpatch) patchProgrammeStopTime(db *sql.DB, tx *sql.Tx, yearLessThan in
 		return
 	}
 
-	_, err = stmt.Exec(&yearLessThan)
+	_, err = stmt.Exec(sid, &yearLessThan)
 
 	return
 }
 
-// DefaultProgrammeLanguage returns most common language in the TV guide
-func (g *Guide) DefaultProgrammeLanguage() (lang string) {
+// programmeTimes parses start and stop times of the programme selected from the database.
+// The programme without stop time lasts until the midnight
+func programmeTimes(sstart string, sstop sql.NullString) (start, stop time.Time, err error) {
 
-	stmt, err := g.db.Prepare(cmdSelectDefaultLanguage)
+	if start, err = xmltv.TimeOfProgramme(sstart); err != nil {
+		return
+	}
 
-	if err != nil {
+	if sstop.Valid {
+		stop, err = xmltv.TimeOfProgramme(sstop.String)
 		return
 	}
 
-	defer stmt.Close()
+	stop = start.AddDate(0, 0, 1)
+	stop = time.Date(stop.Year(), stop.Month(), stop.Day(), 0, 0, 0, 0, time.UTC)
+
+	return
+}
+
+// DefaultProgrammeLanguage returns most common language in the TV guide, the empty language
+// if the guide is empty
+func (g *Guide) DefaultProgrammeLanguage() (lang string, err error) {
 
-	err = stmt.QueryRow().Scan(&lang)
+	stmt, err := g.stmts.prepare(cmdSelectDefaultLanguage)
 
 	if err != nil {
-		return ""
+		return
+	}
+
+	if err = stmt.QueryRow(g.source()).Scan(&lang); err == sql.ErrNoRows {
+		return "", nil
 	}
 
 	return
 }
 
+// GuideQuery - options of the channel guide query
+type GuideQuery struct {
+	// From and To limit the start times of the programmes, zero times mean no limits
+	From, To time.Time
+	// Limit - maximum number of the programmes, zero means no limit
+	Limit int
+	// Offset - number of the programmes skipped
+	Offset int
+	// Category - the programmes of the category only, case and accent insensitive
+	Category string
+	// Languages - preferred languages of the titles
+	Languages Languages
+}
+
 // ChannelGuide returns the tv guide for specified channel cid
-func (g *Guide) ChannelGuide(cid string, lang string, t time.Time) ([]*Programme, error) {
+func (g *Guide) ChannelGuide(cid string, langs Languages, t time.Time) ([]*Programme, error) {
+	return g.ChannelGuideQuery(cid, &GuideQuery{From: t.Add(dh), Languages: langs})
+}
+
+// ChannelGuideQuery returns the programmes of the channel cid selected by the query options. The channel
+// is matched by its display name in any language
+func (g *Guide) ChannelGuideQuery(cid string, q *GuideQuery) ([]*Programme, error) {
 
-	dt := t.Add(dh)
 	chguide := make([]*Programme, 0)
 
-	stmt, err := g.db.Prepare(cmdSelectChannelGuide)
+	limit := q.Limit
+
+	if limit <= 0 {
+		limit = -1
+	}
+
+	stmt, err := g.stmts.prepare(cmdSelectChannelGuide)
 
 	if err != nil {
 		return chguide, err
 	}
 
-	defer stmt.Close()
-
-	rows, err := stmt.Query(&lang, &cid, &dt)
+	rows, err := stmt.Query(q.Languages.list(), cid, g.source(), searchTime(q.From), searchTime(q.To),
+		q.Category, limit, q.Offset)
 
 	if err != nil {
 		return chguide, err
 	}
 
+	defer rows.Close()
+
 	for rows.Next() {
 
 		var (
@@ -1464,25 +918,12 @@ func (g *Guide) ChannelGuide(cid string, lang string, t time.Time) ([]*Programme
 			return make([]*Programme, 0), err
 		}
 
-		start, err = xmltv.TimeOfProgramme(sstart)
+		start, stop, err = programmeTimes(sstart, sstop)
 
 		if err != nil {
 			return make([]*Programme, 0), err
 		}
 
-		if sstop.Valid {
-
-			stop, err = xmltv.TimeOfProgramme(sstop.String)
-
-			if err != nil {
-				return make([]*Programme, 0), err
-			}
-		} else {
-
-			stop = start.AddDate(0, 0, 1)
-			stop = time.Date(stop.Year(), stop.Month(), stop.Day(), 0, 0, 0, 0, time.UTC)
-		}
-
 		p := &Programme{pid, start, stop, title}
 		chguide = append(chguide, p)
 	}
@@ -1497,19 +938,17 @@ func (g *Guide) ChannelGuide(cid string, lang string, t time.Time) ([]*Programme
 }
 
 // ProgrammeDescription returns description of the programme
-func (g *Guide) ProgrammeDescription(pid int, lang string) (*ProgrammeDescription, error) {
+func (g *Guide) ProgrammeDescription(pid int, langs Languages) (*ProgrammeDescription, error) {
 
 	pd := &ProgrammeDescription{}
 	pd.PID = pid
 
-	stmt, err := g.db.Prepare(cmdSelectProgrammeDescription)
+	stmt, err := g.stmts.prepare(cmdSelectProgrammeDescription)
 
 	if err != nil {
 		return pd, err
 	}
 
-	defer stmt.Close()
-
 	var (
 		id       int
 		start    time.Time
@@ -1521,33 +960,23 @@ func (g *Guide) ProgrammeDescription(pid int, lang string) (*ProgrammeDescriptio
 		subtitle sql.NullString
 	)
 
-	err = stmt.QueryRow(&lang, &lang, &lang, &pid).Scan(&id, &sstart, &sstop, &title, &desc, &subtitle)
+	err = stmt.QueryRow(langs.list(), &pid).Scan(&id, &sstart, &sstop, &title, &desc, &subtitle)
+
+	if err == sql.ErrNoRows {
+		return pd, fmt.Errorf("%w: programme %d", ErrNotFound, pid)
+	}
 
 	if err != nil {
 		return pd, err
 	}
 
-	start, err = xmltv.TimeOfProgramme(sstart)
+	start, stop, err = programmeTimes(sstart, sstop)
 
 	if err != nil {
 		return pd, err
 	}
 
 	pd.Start = start
-
-	if sstop.Valid {
-
-		stop, err = xmltv.TimeOfProgramme(sstop.String)
-
-		if err != nil {
-			return pd, err
-		}
-	} else {
-
-		stop = start.AddDate(0, 0, 1)
-		stop = time.Date(stop.Year(), stop.Month(), stop.Day(), 0, 0, 0, 0, time.UTC)
-	}
-
 	pd.Stop = stop
 
 	if title.Valid {
@@ -1562,7 +991,7 @@ func (g *Guide) ProgrammeDescription(pid int, lang string) (*ProgrammeDescriptio
 		pd.SubTitle = subtitle.String
 	}
 
-	categories, err := g.ProgrammeCategories(pid, lang)
+	categories, err := g.ProgrammeCategories(pid, langs)
 
 	if err != nil {
 		return pd, err
@@ -1577,7 +1006,7 @@ func (g *Guide) ProgrammeDescription(pid int, lang string) (*ProgrammeDescriptio
 		}
 	}
 
-	countries, err := g.ProgrammeCountries(pid, lang)
+	countries, err := g.ProgrammeCountries(pid, langs)
 
 	if err != nil {
 		return pd, err
@@ -1640,25 +1069,25 @@ func (g *Guide) ProgrammeDescription(pid int, lang string) (*ProgrammeDescriptio
 	return pd, nil
 }
 
-// ProgrammeCategories returns categories of the programme
-func (g *Guide) ProgrammeCategories(pid int, lang string) ([]*string, error) {
+// ProgrammeCategories returns categories of the programme in the most preferred language available
+func (g *Guide) ProgrammeCategories(pid int, langs Languages) ([]*string, error) {
 
 	categories := make([]*string, 0)
 
-	stmt, err := g.db.Prepare(cmdSelectProgrammeCategories)
+	stmt, err := g.stmts.prepare(cmdSelectProgrammeCategories)
 
 	if err != nil {
 		return categories, err
 	}
 
-	defer stmt.Close()
-
-	rows, err := stmt.Query(&pid, &lang)
+	rows, err := stmt.Query(&pid, langs.list())
 
 	if err != nil {
 		return categories, err
 	}
 
+	defer rows.Close()
+
 	for rows.Next() {
 
 		var category string
@@ -1681,25 +1110,25 @@ func (g *Guide) ProgrammeCategories(pid int, lang string) ([]*string, error) {
 	return categories, nil
 }
 
-// ProgrammeCountries returns countries where the programme was made
-func (g *Guide) ProgrammeCountries(pid int, lang string) ([]*string, error) {
+// ProgrammeCountries returns countries where the programme was made in the most preferred language available
+func (g *Guide) ProgrammeCountries(pid int, langs Languages) ([]*string, error) {
 
 	countries := make([]*string, 0)
 
-	stmt, err := g.db.Prepare(cmdSelectProgrammeCountries)
+	stmt, err := g.stmts.prepare(cmdSelectProgrammeCountries)
 
 	if err != nil {
 		return countries, err
 	}
 
-	defer stmt.Close()
-
-	rows, err := stmt.Query(&pid, &lang)
+	rows, err := stmt.Query(&pid, langs.list())
 
 	if err != nil {
 		return countries, err
 	}
 
+	defer rows.Close()
+
 	for rows.Next() {
 
 		var country string
@@ -1727,20 +1156,20 @@ func (g *Guide) ProgrammeDirectors(pid int) ([]*string, error) {
 
 	directors := make([]*string, 0)
 
-	stmt, err := g.db.Prepare(cmdSelectProgrammeDirectors)
+	stmt, err := g.stmts.prepare(cmdSelectProgrammeDirectors)
 
 	if err != nil {
 		return directors, err
 	}
 
-	defer stmt.Close()
-
 	rows, err := stmt.Query(&pid)
 
 	if err != nil {
 		return directors, err
 	}
 
+	defer rows.Close()
+
 	for rows.Next() {
 
 		var director string
@@ -1768,20 +1197,20 @@ func (g *Guide) ProgrammeActors(pid int) ([]*ProgrammeActor, error) {
 
 	actors := make([]*ProgrammeActor, 0)
 
-	stmt, err := g.db.Prepare(cmdSelectProgrammeActors)
+	stmt, err := g.stmts.prepare(cmdSelectProgrammeActors)
 
 	if err != nil {
 		return actors, err
 	}
 
-	defer stmt.Close()
-
 	rows, err := stmt.Query(&pid)
 
 	if err != nil {
 		return actors, err
 	}
 
+	defer rows.Close()
+
 	for rows.Next() {
 
 		var actor, role string
@@ -1811,20 +1240,20 @@ func (g *Guide) ProgrammeRating(pid int) ([]*ProgrammeRating, error) {
 
 	ratings := make([]*ProgrammeRating, 0)
 
-	stmt, err := g.db.Prepare(cmdSelectProgrammeRating)
+	stmt, err := g.stmts.prepare(cmdSelectProgrammeRating)
 
 	if err != nil {
 		return ratings, err
 	}
 
-	defer stmt.Close()
-
 	rows, err := stmt.Query(&pid)
 
 	if err != nil {
 		return ratings, err
 	}
 
+	defer rows.Close()
+
 	for rows.Next() {
 
 		var system, value string
@@ -0,0 +1,137 @@
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package playlists
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	xmltv "go-tvguide/pkg/xmltv"
+)
+
+// GuideFilter restricts the content of the tv guide stored while reading.
+// Playlist keeps only channels matched with the playlist items, From and To keep only
+// programmes on air within the time window. Zero values disable the restriction
+type GuideFilter struct {
+	Playlist *Playlist
+	From     time.Time
+	To       time.Time
+
+	ids      map[string]bool
+	channels map[string]bool
+}
+
+// NewGuideFilter returns the filter for the playlist channels and the time window from
+// daysBack days before today to daysAhead days after today. Negative number of days means no limit
+func NewGuideFilter(p *Playlist, daysBack, daysAhead int, t time.Time) *GuideFilter {
+
+	f := &GuideFilter{Playlist: p}
+	today := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+
+	if daysBack >= 0 {
+		f.From = today.AddDate(0, 0, -daysBack)
+	}
+
+	if daysAhead >= 0 {
+		f.To = today.AddDate(0, 0, daysAhead+1)
+	}
+
+	return f
+}
+
+// signature describes the filter to tell whether the stored guide has been read with the same filter
+func (f *GuideFilter) signature() string {
+
+	if f == nil {
+		return ""
+	}
+
+	var playlist string
+
+	if f.Playlist != nil {
+		playlist = f.Playlist.contentHash()
+	}
+
+	return fmt.Sprintf("playlist=%s;from=%s;to=%s", playlist, timeSignature(f.From), timeSignature(f.To))
+}
+
+func timeSignature(t time.Time) string {
+
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.Format(time.RFC3339)
+}
+
+func (f *GuideFilter) prepare() (err error) {
+
+	f.ids = nil
+	f.channels = make(map[string]bool)
+
+	if f.Playlist != nil {
+		f.ids, err = f.Playlist.ids()
+	}
+
+	return
+}
+
+func (f *GuideFilter) acceptChannel(ch *xmltv.XMLTVChannel) bool {
+
+	if f.ids == nil {
+
+		f.channels[ch.ID] = true
+		return true
+	}
+
+	for _, dn := range ch.DisplayName {
+
+		if f.ids[dn.Value] {
+
+			f.channels[ch.ID] = true
+			return true
+		}
+	}
+
+	return false
+}
+
+// acceptProgramme checks the raw attributes of the programme read before its normalization,
+// so the channel id is trimmed like the id of the accepted channels
+func (f *GuideFilter) acceptProgramme(channel, start, stop string) bool {
+
+	if f.ids != nil && !f.channels[strings.TrimSpace(channel)] {
+		return false
+	}
+
+	if !f.From.IsZero() {
+
+		if t, err := xmltv.TimeOfProgramme(stop); err == nil && !t.IsZero() && !t.After(f.From) {
+			return false
+		}
+	}
+
+	if !f.To.IsZero() {
+
+		if t, err := xmltv.TimeOfProgramme(start); err == nil && !t.Before(f.To) {
+			return false
+		}
+	}
+
+	return true
+}
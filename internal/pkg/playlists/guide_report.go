@@ -0,0 +1,290 @@
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package playlists
+
+import (
+	"database/sql"
+	"time"
+
+	xmltv "go-tvguide/pkg/xmltv"
+)
+
+const (
+	cmdSelectReportChannels = `SELECT c.channel_id, ifnull((SELECT cdn.display_name FROM channel_display_names AS cdn
+			WHERE cdn.cid = c.cid ORDER BY lang_rank(?, cdn.lang) LIMIT 1), '') AS name
+	FROM channels AS c
+	WHERE c.sid = ?
+	ORDER BY c.cid`
+
+	cmdSelectReportProgrammes = `SELECT p.pid, p.channel_id, datetime(p.start) AS start, datetime(p.stop) AS stop
+		, ifnull((SELECT pt.title FROM programme_titles AS pt WHERE (pt.pid = p.pid) AND (ifnull(pt.title, '') <> '')
+			ORDER BY lang_rank(?, pt.lang) LIMIT 1), '') AS title
+	FROM programme AS p
+	WHERE p.sid = ?
+	ORDER BY p.channel_id, p.start, p.pid`
+
+	cmdSelectPlaylistChannelsWithoutGuide = `SELECT pl.id FROM playlist AS pl
+	WHERE (pl.sid = ?1) AND NOT EXISTS (SELECT c.cid FROM channels AS c
+		INNER JOIN channel_display_names AS cdn ON (cdn.cid = c.cid) AND (cdn.display_name = pl.id)
+		WHERE (c.sid = ?2) AND EXISTS (SELECT p.pid FROM programme AS p
+			WHERE (p.sid = c.sid) AND (p.channel_id = c.channel_id)))
+	ORDER BY pl.rowid`
+
+	cmdSelectPlaylistChannelCount = `SELECT COUNT(*) FROM playlist WHERE sid = ?`
+)
+
+// GuideReport describes the coverage and the data quality of the tv guide
+type GuideReport struct {
+	Channels []*ChannelReport `json:"channels"`
+	// PlaylistChannels - the number of the playlist channels, if the guide has the playlist
+	PlaylistChannels int `json:"playlist_channels"`
+	// MissingChannels - the playlist channels without programmes in the guide
+	MissingChannels []string `json:"missing_channels"`
+}
+
+// ChannelReport describes the coverage and the data quality of the channel guide
+type ChannelReport struct {
+	ChannelID  string `json:"channel_id"`
+	Name       string `json:"name"`
+	Programmes int    `json:"programmes"`
+	// From and To - the covered time range, zero if the channel has no programmes
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+	// Gaps and Overlaps between the neighbouring programmes longer than the threshold
+	Gaps     []*Interval `json:"gaps"`
+	Overlaps []*Interval `json:"overlaps"`
+	// ZeroLength - the programmes ending when or before they start
+	ZeroLength []*ReportProgramme `json:"zero_length"`
+	// Untitled - the programmes without title
+	Untitled []*ReportProgramme `json:"untitled"`
+	// NoStop - the programmes without stop time, including the ones patched on reading
+	NoStop []*ReportProgramme `json:"no_stop"`
+}
+
+// Interval - the time range
+type Interval struct {
+	Start time.Time `json:"start"`
+	Stop  time.Time `json:"stop"`
+}
+
+// Duration returns the length of the interval
+func (i *Interval) Duration() time.Duration {
+	return i.Stop.Sub(i.Start)
+}
+
+// ReportProgramme - the programme mentioned by the report
+type ReportProgramme struct {
+	PID   int       `json:"pid"`
+	Start time.Time `json:"start"`
+	Title string    `json:"title"`
+}
+
+// Issues returns the number of the issues found in the channel guide
+func (c *ChannelReport) Issues() int {
+	return len(c.Gaps) + len(c.Overlaps) + len(c.ZeroLength) + len(c.Untitled) + len(c.NoStop)
+}
+
+// Report returns the coverage and the data quality report of the guide. The gaps and the overlaps
+// not longer than the threshold are ignored. The times of the report are in the local time zone
+func (g *Guide) Report(threshold time.Duration) (report *GuideReport, err error) {
+
+	report = &GuideReport{Channels: make([]*ChannelReport, 0), MissingChannels: make([]string, 0)}
+
+	languages, err := g.Languages()
+
+	if err != nil {
+		return
+	}
+
+	langs := languages.list()
+
+	channels := make(map[string]*ChannelReport)
+	sid := g.source()
+
+	rows, err := g.stmts.query(cmdSelectReportChannels, langs, sid)
+
+	if err != nil {
+		return
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+
+		c := newChannelReport()
+
+		if err = rows.Scan(&c.ChannelID, &c.Name); err != nil {
+			return
+		}
+
+		channels[c.ChannelID] = c
+		report.Channels = append(report.Channels, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	if err = g.reportProgrammes(report, channels, sid, langs, threshold); err != nil {
+		return
+	}
+
+	playlist := g.currentPlaylist()
+
+	if playlist == nil {
+		return
+	}
+
+	psid := playlist.source()
+
+	if err = g.db.QueryRow(cmdSelectPlaylistChannelCount, psid).Scan(&report.PlaylistChannels); err != nil {
+		return
+	}
+
+	mrows, err := g.stmts.query(cmdSelectPlaylistChannelsWithoutGuide, psid, sid)
+
+	if err != nil {
+		return
+	}
+
+	defer mrows.Close()
+
+	for mrows.Next() {
+
+		var id string
+
+		if err = mrows.Scan(&id); err != nil {
+			return
+		}
+
+		report.MissingChannels = append(report.MissingChannels, id)
+	}
+
+	err = mrows.Err()
+
+	return
+}
+
+func newChannelReport() *ChannelReport {
+
+	return &ChannelReport{Gaps: make([]*Interval, 0), Overlaps: make([]*Interval, 0),
+		ZeroLength: make([]*ReportProgramme, 0), Untitled: make([]*ReportProgramme, 0),
+		NoStop: make([]*ReportProgramme, 0)}
+}
+
+// reportProgrammes checks the programmes of the channels ordered by the start time. The channels of
+// the programmes without the channel description are appended to the report
+func (g *Guide) reportProgrammes(report *GuideReport, channels map[string]*ChannelReport, sid int64, langs string,
+	threshold time.Duration) (err error) {
+
+	rows, err := g.stmts.query(cmdSelectReportProgrammes, langs, sid)
+
+	if err != nil {
+		return
+	}
+
+	defer rows.Close()
+
+	// prev - the end of the previous programmes of the channel, zero if unknown
+	var (
+		c    *ChannelReport
+		prev time.Time
+	)
+
+	for rows.Next() {
+
+		var (
+			pid     int
+			channel string
+			sstart  string
+			sstop   sql.NullString
+			start   time.Time
+			stop    time.Time
+			title   string
+		)
+
+		if err = rows.Scan(&pid, &channel, &sstart, &sstop, &title); err != nil {
+			return
+		}
+
+		if start, err = xmltv.TimeOfProgramme(sstart); err != nil {
+			return
+		}
+
+		start = start.Local()
+		rp := &ReportProgramme{PID: pid, Start: start, Title: title}
+
+		if c == nil || c.ChannelID != channel {
+
+			if c = channels[channel]; c == nil {
+				c = newChannelReport()
+				c.ChannelID = channel
+				channels[channel] = c
+				report.Channels = append(report.Channels, c)
+			}
+
+			c.From, prev = start, time.Time{}
+		}
+
+		c.Programmes++
+
+		if !prev.IsZero() {
+
+			if d := start.Sub(prev); d > threshold {
+				c.Gaps = append(c.Gaps, &Interval{prev, start})
+			} else if -d > threshold {
+				c.Overlaps = append(c.Overlaps, &Interval{start, prev})
+			}
+		}
+
+		if title == "" {
+			c.Untitled = append(c.Untitled, rp)
+		}
+
+		if !sstop.Valid {
+
+			c.NoStop = append(c.NoStop, rp)
+			prev = time.Time{}
+
+			if start.After(c.To) {
+				c.To = start
+			}
+
+			continue
+		}
+
+		if stop, err = xmltv.TimeOfProgramme(sstop.String); err != nil {
+			return
+		}
+
+		stop = stop.Local()
+
+		if !stop.After(start) {
+			c.ZeroLength = append(c.ZeroLength, rp)
+		}
+
+		if stop.After(prev) {
+			prev = stop
+		}
+
+		if stop.After(c.To) {
+			c.To = stop
+		}
+	}
+
+	return rows.Err()
+}
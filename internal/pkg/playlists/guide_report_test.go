@@ -0,0 +1,129 @@
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package playlists
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	xmltv "go-tvguide/pkg/xmltv"
+)
+
+const testReportGuide = `<?xml version="1.0" encoding="UTF-8"?>
+<tv>
+<channel id="1"><display-name lang="en">News</display-name></channel>
+<channel id="2"><display-name lang="en">Sport</display-name></channel>
+<channel id="3"><display-name lang="en">Empty</display-name></channel>
+<programme start="20181027180000 +0000" stop="20181027190000 +0000" channel="1"><title lang="en">News</title></programme>
+<programme start="20181027193000 +0000" stop="20181027200000 +0000" channel="1"><title lang="en">Weather</title></programme>
+<programme start="20181027195000 +0000" stop="20181027203000 +0000" channel="1"><title lang="en">Talk</title></programme>
+<programme start="20181027203000 +0000" stop="20181027203000 +0000" channel="1"><title lang="en">Zero</title></programme>
+<programme start="20181027203200 +0000" stop="20181027210000 +0000" channel="1"></programme>
+<programme start="20181027210000 +0000" channel="1"><title lang="en">Night</title></programme>
+<programme start="20181027180000 +0000" stop="20181027200000 +0000" channel="2"><title lang="en">Football</title></programme>
+<programme start="20181027200000 +0000" stop="20181027220000 +0000" channel="2"><title lang="en">Hockey</title></programme>
+</tv>`
+
+func TestGuideReport(t *testing.T) {
+
+	g := newTestGuide(t)
+	p := &Playlist{db: g.db, stmts: g.stmts}
+
+	if err := p.Read([]byte(testNowNextPlaylist), &M3UPlaylistParser{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the stop times of the past years are patched on reading
+	year := time.Now().Year() + 1
+	data := strings.Replace(testReportGuide, "2018", fmt.Sprint(year), -1)
+
+	if _, err := g.Read([]byte(data), &xmltv.XMLTVParser{}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	g.SetPlaylist(p)
+
+	report, err := g.Report(5 * time.Minute)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.PlaylistChannels != 3 || !reflect.DeepEqual(report.MissingChannels, []string{"Unknown"}) {
+		t.Errorf("Report() playlist channels = %d, missing %q", report.PlaylistChannels, report.MissingChannels)
+	}
+
+	at := func(hour, min int) time.Time {
+		return time.Date(year, 10, 27, hour, min, 0, 0, time.UTC).Local()
+	}
+
+	var tests = []struct {
+		name     string
+		count    int
+		from, to time.Time
+		issues   string
+	}{
+		{"News", 6, at(18, 0), at(21, 0), "gaps: 19:00-19:30, overlaps: 19:50-20:00, zero: Zero, untitled: 1, no stop: Night"},
+		{"Sport", 2, at(18, 0), at(22, 0), "gaps: , overlaps: , zero: , untitled: 0, no stop: "},
+		{"Empty", 0, time.Time{}, time.Time{}, "gaps: , overlaps: , zero: , untitled: 0, no stop: "},
+	}
+
+	if len(report.Channels) != len(tests) {
+		t.Fatalf("Report() channels = %d, want %d", len(report.Channels), len(tests))
+	}
+
+	intervals := func(list []*Interval) string {
+
+		s := make([]string, 0)
+
+		for _, i := range list {
+			s = append(s, i.Start.Format("15:04")+"-"+i.Stop.Format("15:04"))
+		}
+
+		return strings.Join(s, ", ")
+	}
+
+	titles := func(list []*ReportProgramme) string {
+
+		s := make([]string, 0)
+
+		for _, p := range list {
+			s = append(s, p.Title)
+		}
+
+		return strings.Join(s, ", ")
+	}
+
+	for i, test := range tests {
+
+		c := report.Channels[i]
+
+		issues := fmt.Sprintf("gaps: %s, overlaps: %s, zero: %s, untitled: %d, no stop: %s", intervals(c.Gaps),
+			intervals(c.Overlaps), titles(c.ZeroLength), len(c.Untitled), titles(c.NoStop))
+
+		if c.Name != test.name || c.Programmes != test.count || !c.From.Equal(test.from) || !c.To.Equal(test.to) {
+			t.Errorf("#%d: Report() = %s %d %v %v", i, c.Name, c.Programmes, c.From, c.To)
+		}
+
+		if issues != test.issues {
+			t.Errorf("#%d: Report() = %q, want %q", i, issues, test.issues)
+		}
+	}
+}
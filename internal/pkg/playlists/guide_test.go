@@ -0,0 +1,449 @@
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package playlists
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	xmltv "go-tvguide/pkg/xmltv"
+)
+
+// syntheticGuide generates XMLTV guide with the specified number of channels and programmes per channel
+func syntheticGuide(channels, programmes int) []byte {
+
+	var b bytes.Buffer
+
+	start := time.Date(2018, 10, 27, 0, 0, 0, 0, time.UTC)
+
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	b.WriteString(`<tv generator-info-name="synthetic">` + "\n")
+
+	for c := 0; c < channels; c++ {
+		fmt.Fprintf(&b, `<channel id="%d"><display-name lang="ru">Channel %d</display-name><url>http://example.com/%d</url></channel>`+"\n", c, c, c)
+	}
+
+	for c := 0; c < channels; c++ {
+		for p := 0; p < programmes; p++ {
+
+			pstart := start.Add(time.Duration(p) * 30 * time.Minute)
+			pstop := pstart.Add(30 * time.Minute)
+
+			fmt.Fprintf(&b, `<programme start="%s" stop="%s" channel="%d">`, pstart.Format("20060102150405 -0700"),
+				pstop.Format("20060102150405 -0700"), c)
+			fmt.Fprintf(&b, `<title lang="ru">Programme %d-%d</title><sub-title lang="ru">Episode %d</sub-title>`, c, p, p)
+			fmt.Fprintf(&b, `<desc lang="ru">Description of the programme %d on the channel %d</desc>`, p, c)
+			fmt.Fprintf(&b, `<credits><director>Director %d</director><actor role="Hero">Actor %d</actor>`, p%7, p%11)
+			fmt.Fprintf(&b, `<actor>Actor %d</actor><presenter>Presenter %d</presenter></credits>`, p%13, c)
+			fmt.Fprintf(&b, `<date>2018</date><category lang="ru">Category %d</category><country lang="ru">Russia</country>`, p%5)
+			fmt.Fprintf(&b, `<episode-num system="onscreen">%d</episode-num><rating system="MPAA"><value>PG</value></rating>`, p)
+			b.WriteString("</programme>\n")
+		}
+	}
+
+	b.WriteString("</tv>\n")
+
+	return b.Bytes()
+}
+
+// openTestStore opens the store of the test, the test is skipped by the builds without cgo
+func openTestStore(tb testing.TB, dsn string, opts *Options) *Store {
+
+	store, err := Open(dsn, opts)
+
+	if errors.Is(err, ErrNoSQLite) {
+		tb.Skip(err)
+	}
+
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	return store
+}
+
+// newTestGuide returns the guide with an isolated in-memory database
+func newTestGuide(tb testing.TB) *Guide {
+
+	store := openTestStore(tb, ":memory:", nil)
+
+	tb.Cleanup(func() { store.Close() })
+
+	return store.Guide()
+}
+
+// dumpDatabase returns all rows of all tables of the database sorted
+func dumpDatabase(tb testing.TB, db *sql.DB) []string {
+
+	rows, err := db.Query(`SELECT name FROM sqlite_master WHERE type = 'table' ORDER BY name`)
+
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	var tables []string
+
+	for rows.Next() {
+
+		var name string
+
+		if err = rows.Scan(&name); err != nil {
+			tb.Fatal(err)
+		}
+
+		tables = append(tables, name)
+	}
+
+	rows.Close()
+
+	var dump []string
+
+	for _, table := range tables {
+
+		rows, err := db.Query("SELECT * FROM " + table)
+
+		if err != nil {
+			tb.Fatal(err)
+		}
+
+		columns, _ := rows.Columns()
+
+		for rows.Next() {
+
+			values := make([]interface{}, len(columns))
+			ptrs := make([]interface{}, len(columns))
+
+			for i := range values {
+				ptrs[i] = &values[i]
+			}
+
+			if err = rows.Scan(ptrs...); err != nil {
+				tb.Fatal(err)
+			}
+
+			dump = append(dump, fmt.Sprintf("%s %v", table, values))
+		}
+
+		rows.Close()
+	}
+
+	sort.Strings(dump)
+
+	return dump
+}
+
+func TestGuideReadParallel(t *testing.T) {
+
+	data := syntheticGuide(20, 50)
+	data = bytes.Replace(data, []byte(`start="20181027100000 +0000"`), []byte(`start="later"`), 3)
+
+	day := time.Date(2018, 10, 27, 0, 0, 0, 0, time.Local)
+
+	var dumps [2][]string
+
+	for i, workers := range [2]int{1, 8} {
+
+		g := newTestGuide(t)
+
+		report, err := g.Read(data, &xmltv.XMLTVParser{Workers: workers}, &GuideFilter{From: day, To: day.Add(12 * time.Hour)})
+
+		if err != nil {
+			t.Fatalf("Read() with %d workers = %v", workers, err)
+		}
+
+		if report.Skipped != 3 || report.Programmes == 0 || report.FilteredProgrammes == 0 {
+			t.Errorf("Read() with %d workers = %+v", workers, report)
+		}
+
+		dumps[i] = dumpDatabase(t, g.db)
+	}
+
+	if len(dumps[0]) != len(dumps[1]) {
+		t.Fatalf("parallel import stored %d rows, sequential %d", len(dumps[1]), len(dumps[0]))
+	}
+
+	for i := range dumps[0] {
+		if dumps[0][i] != dumps[1][i] {
+			t.Fatalf("parallel import differs: %s != %s", dumps[1][i], dumps[0][i])
+		}
+	}
+}
+
+const testPlaylist = `#EXTM3U url-tvg="http://example.com/guide.xml"
+#EXTINF:-1 tvg-name="Channel 1" group-title="News",Channel 1
+http://example.com/1
+#EXTINF:-1 tvg-name="Channel 2" group-title="News",Channel 2
+http://example.com/2
+`
+
+func TestGuideLoad(t *testing.T) {
+
+	g := newTestGuide(t)
+	p := &Playlist{db: g.db, stmts: g.stmts}
+
+	var tests = []struct {
+		playlist  string
+		guide     []byte
+		unchanged bool
+		channels  int
+	}{
+		{testPlaylist, syntheticGuide(5, 10), false, 2},
+		{testPlaylist, syntheticGuide(5, 10), true, 2},
+		{testPlaylist, syntheticGuide(5, 20), false, 2},
+		{strings.Replace(testPlaylist, "Channel 2", "Channel 3", -1), syntheticGuide(5, 20), false, 2},
+	}
+
+	for i, test := range tests {
+
+		if _, err := p.Load("playlist.m3u", []byte(test.playlist), &M3UPlaylistParser{}); err != nil {
+			t.Fatalf("#%d: Playlist.Load() = %v", i, err)
+		}
+
+		if p.Guide() != "http://example.com/guide.xml" {
+			t.Errorf("#%d: Playlist.Guide() = %q", i, p.Guide())
+		}
+
+		report, err := g.Load(p.Guide(), test.guide, &xmltv.XMLTVParser{}, &GuideFilter{Playlist: p})
+
+		if err != nil {
+			t.Fatalf("#%d: Guide.Load() = %v", i, err)
+		}
+
+		if report.Unchanged != test.unchanged {
+			t.Errorf("#%d: Guide.Load() unchanged = %v, want %v", i, report.Unchanged, test.unchanged)
+		}
+
+		var channels, sources int
+
+		if err = g.db.QueryRow(`SELECT COUNT(*) FROM channels`).Scan(&channels); err != nil {
+			t.Fatal(err)
+		}
+
+		if err = g.db.QueryRow(`SELECT COUNT(*) FROM sources`).Scan(&sources); err != nil {
+			t.Fatal(err)
+		}
+
+		items, err := p.Channels("News")
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if channels != test.channels || sources != 2 || len(items) != 2 {
+			t.Errorf("#%d: the database contains %d channels, %d sources, %d playlist items", i, channels, sources,
+				len(items))
+		}
+	}
+}
+
+func TestGuideLoadKeepsOtherSources(t *testing.T) {
+
+	g := newTestGuide(t)
+
+	if _, err := g.Load("first.xml", []byte(testQueryGuide), &xmltv.XMLTVParser{}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// the stop times of the past years are patched by the reading of their source only
+	if _, err := g.db.Exec(`UPDATE programme SET stop = start`); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := g.Load("second.xml", syntheticGuide(2, 5), &xmltv.XMLTVParser{}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var stops, indexes int
+
+	if err := g.db.QueryRow(`SELECT COUNT(stop) FROM programme AS p INNER JOIN sources AS s ON (s.sid = p.sid)
+		WHERE s.url = 'first.xml'`).Scan(&stops); err != nil || stops != 4 {
+		t.Errorf("the first source has %d stop times, %v, want 4", stops, err)
+	}
+
+	if err := g.db.QueryRow(`SELECT COUNT(*) FROM (` + cmdSelectGuideIndexes + `)`).Scan(&indexes); err != nil ||
+		indexes != len(guideIndexes) {
+		t.Errorf("the database has %d guide indexes, %v, want %d", indexes, err, len(guideIndexes))
+	}
+}
+
+const testQueryGuide = `<?xml version="1.0" encoding="UTF-8"?>
+<tv>
+<channel id="1"><display-name lang="ru">Первый</display-name><display-name lang="en">First</display-name></channel>
+<programme start="20181027180000 +0000" stop="20181027190000 +0000" channel="1">
+<title lang="ru">Новости</title><title lang="en">News</title><category lang="en">News</category></programme>
+<programme start="20181027190000 +0000" stop="20181027210000 +0000" channel="1">
+<title lang="en">Football</title><category lang="en">Sport</category></programme>
+<programme start="20181027210000 +0000" stop="20181028010000 +0000" channel="1">
+<title lang="ru">Кино</title><category lang="ru">Фильм</category></programme>
+<programme start="20181028010000 +0000" stop="20181028020000 +0000" channel="1">
+<title lang="ru">Ночные новости</title><category lang="en">NEWS</category></programme>
+</tv>`
+
+func TestGuideChannelGuideQuery(t *testing.T) {
+
+	g := newTestGuide(t)
+
+	// the stop times of the past years are patched on reading
+	year := time.Now().Year() + 1
+	data := strings.Replace(testQueryGuide, "2018", fmt.Sprint(year), -1)
+
+	if _, err := g.Read([]byte(data), &xmltv.XMLTVParser{}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	day := time.Date(year, 10, 27, 0, 0, 0, 0, time.UTC)
+
+	var tests = []struct {
+		cid    string
+		query  GuideQuery
+		titles string
+	}{
+		{"Первый", GuideQuery{Languages: []string{"ru"}}, "Новости|Football|Кино|Ночные новости"},
+		{"First", GuideQuery{Languages: []string{"en", "ru"}}, "News|Football|Кино|Ночные новости"},
+		{"First", GuideQuery{Languages: []string{"ru"}}, "Новости|Football|Кино|Ночные новости"},
+		{"Первый", GuideQuery{Languages: []string{"de", "en"}}, "News|Football|Кино|Ночные новости"},
+		{"First", GuideQuery{}, "Новости|Football|Кино|Ночные новости"},
+		{"First", GuideQuery{From: day.Add(19 * time.Hour), To: day.AddDate(0, 0, 1), Languages: []string{"en"}},
+			"Football|Кино"},
+		{"First", GuideQuery{Limit: 2, Offset: 1, Languages: []string{"en"}}, "Football|Кино"},
+		{"First", GuideQuery{Category: "news", Languages: []string{"en"}}, "News|Ночные новости"},
+		{"First", GuideQuery{Category: "фильм", Languages: []string{"en"}}, "Кино"},
+	}
+
+	for i, test := range tests {
+
+		programmes, err := g.ChannelGuideQuery(test.cid, &test.query)
+
+		if err != nil {
+			t.Fatalf("#%d: ChannelGuideQuery() = %v", i, err)
+		}
+
+		titles := make([]string, 0)
+
+		for _, p := range programmes {
+			titles = append(titles, p.Title)
+		}
+
+		if strings.Join(titles, "|") != test.titles {
+			t.Errorf("#%d: ChannelGuideQuery(%q) = %q, want %q", i, test.cid, strings.Join(titles, "|"), test.titles)
+		}
+	}
+}
+
+func BenchmarkGuideRead(b *testing.B) {
+	benchmarkGuideRead(b, 1)
+}
+
+func BenchmarkGuideReadParallel(b *testing.B) {
+	benchmarkGuideRead(b, runtime.NumCPU())
+}
+
+func benchmarkGuideRead(b *testing.B, workers int) {
+
+	data := syntheticGuide(50, 200)
+
+	b.SetBytes(int64(len(data)))
+
+	for i := 0; i < b.N; i++ {
+
+		b.StopTimer()
+		g := newTestGuide(b)
+		b.StartTimer()
+
+		if _, err := g.Read(data, &xmltv.XMLTVParser{Workers: workers}, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGuideAppend(b *testing.B) {
+
+	programmes := make([]*xmltv.XMLTVProgramme, 0)
+
+	parser := &xmltv.XMLTVParser{}
+	parser.OnProgramme = func(p *xmltv.XMLTVProgramme) error {
+
+		programmes = append(programmes, p)
+		return nil
+	}
+
+	if err := parser.Parse(syntheticGuide(50, 200)); err != nil {
+		b.Fatal(err)
+	}
+
+	for i := 0; i < b.N; i++ {
+
+		b.StopTimer()
+		g := newTestGuide(b)
+		b.StartTimer()
+
+		tx, err := g.db.Begin()
+
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		g.tx = tx
+
+		if err = g.beginBulkLoad(); err != nil {
+			b.Fatal(err)
+		}
+
+		for _, p := range programmes {
+			if err = g.appendProgramme(p); err != nil {
+				b.Fatal(err)
+			}
+		}
+
+		if err = g.endBulkLoad(); err != nil {
+			b.Fatal(err)
+		}
+
+		if err = tx.Commit(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestGuideFilterTrimsChannel(t *testing.T) {
+
+	f := &GuideFilter{ids: map[string]bool{"Channel 1": true}, channels: make(map[string]bool)}
+
+	// the channel is normalized before the filter, the programme attributes are not
+	ch := &xmltv.XMLTVChannel{ID: "ch1", DisplayName: []xmltv.XMLTVChannelDisplayName{{Value: "Channel 1"}}}
+
+	if !f.acceptChannel(ch) {
+		t.Fatal("acceptChannel() = false, want true")
+	}
+
+	for _, channel := range []string{"ch1", " ch1 ", "\n\tch1\n"} {
+		if !f.acceptProgramme(channel, "", "") {
+			t.Errorf("acceptProgramme(%q) = false, want true", channel)
+		}
+	}
+
+	if f.acceptProgramme("ch2", "", "") {
+		t.Error(`acceptProgramme("ch2") = true, want false`)
+	}
+}
@@ -0,0 +1,118 @@
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package playlists
+
+import (
+	"strings"
+)
+
+// Languages - the languages of the texts in the order of preference. The empty language stands for
+// the texts without the language, the texts in the languages not listed follow the listed ones
+type Languages []string
+
+// ParseLanguages parses the comma separated list of the languages. The empty language may be
+// quoted, for example: ru,en,""
+func ParseLanguages(s string) Languages {
+
+	langs := make(Languages, 0)
+
+	if strings.TrimSpace(s) == "" {
+		return langs
+	}
+
+	for _, lang := range strings.Split(s, ",") {
+		langs = append(langs, strings.Trim(strings.TrimSpace(lang), `"'`))
+	}
+
+	return langs
+}
+
+// String returns the comma separated list of the languages
+func (l Languages) String() string {
+
+	langs := make([]string, len(l))
+
+	for i, lang := range l {
+
+		if lang == "" {
+			lang = `""`
+		}
+
+		langs[i] = lang
+	}
+
+	return strings.Join(langs, ",")
+}
+
+// list returns the languages as the argument of the lang_rank function
+func (l Languages) list() string {
+	return strings.Join(l, ",")
+}
+
+// languageRank - lang_rank(list, lang) returns the position of the language in the list of the
+// languages or the length of the list if the language is not listed. The NULL language is the empty one
+func languageRank(list string, lang interface{}) int {
+
+	var s string
+
+	switch v := lang.(type) {
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	}
+
+	langs := strings.Split(list, ",")
+
+	for i, l := range langs {
+		if l == s {
+			return i
+		}
+	}
+
+	return len(langs)
+}
+
+// SetLanguages sets the preferred languages of the texts of the guide
+func (g *Guide) SetLanguages(langs Languages) {
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.languages = langs
+}
+
+// Languages returns the preferred languages of the texts of the guide, the most common language of
+// the guide is used if the languages are not set
+func (g *Guide) Languages() (Languages, error) {
+
+	g.mu.RLock()
+	langs := g.languages
+	g.mu.RUnlock()
+
+	if len(langs) > 0 {
+		return langs, nil
+	}
+
+	lang, err := g.DefaultProgrammeLanguage()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return Languages{lang}, nil
+}
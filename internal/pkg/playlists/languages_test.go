@@ -0,0 +1,105 @@
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package playlists
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	xmltv "go-tvguide/pkg/xmltv"
+)
+
+func TestParseLanguages(t *testing.T) {
+
+	var tests = []struct {
+		s     string
+		langs Languages
+	}{
+		{"", Languages{}},
+		{"ru", Languages{"ru"}},
+		{`ru, en, ""`, Languages{"ru", "en", ""}},
+		{"en,,de", Languages{"en", "", "de"}},
+	}
+
+	for _, test := range tests {
+
+		langs := ParseLanguages(test.s)
+
+		if !reflect.DeepEqual(langs, test.langs) {
+			t.Errorf("ParseLanguages(%q) = %q, want %q", test.s, langs, test.langs)
+		}
+
+		if s := ParseLanguages(langs.String()); !reflect.DeepEqual(s, test.langs) {
+			t.Errorf("ParseLanguages(%q) = %q, want %q", langs.String(), s, test.langs)
+		}
+	}
+}
+
+const testLanguagesGuide = `<?xml version="1.0" encoding="UTF-8"?>
+<tv>
+<channel id="1"><display-name lang="ru">Первый</display-name></channel>
+<programme start="20181027180000 +0000" stop="20181027190000 +0000" channel="1">
+<title lang="ru">Новости</title><title lang="en">News</title><title>Nachrichten</title>
+<sub-title lang="en">Evening</sub-title><desc lang="ru">Главное за день</desc>
+<category lang="ru">Новости</category><category lang="en">News</category><category lang="en">Current affairs</category>
+</programme>
+</tv>`
+
+func TestGuideProgrammeDescriptionLanguages(t *testing.T) {
+
+	g := newTestGuide(t)
+
+	year := time.Now().Year() + 1
+	data := strings.Replace(testLanguagesGuide, "2018", fmt.Sprint(year), -1)
+
+	if _, err := g.Read([]byte(data), &xmltv.XMLTVParser{}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	programmes, err := g.ChannelGuideQuery("Первый", &GuideQuery{})
+
+	if err != nil || len(programmes) != 1 {
+		t.Fatalf("ChannelGuideQuery() = %v, %v", programmes, err)
+	}
+
+	var tests = []struct {
+		langs Languages
+		want  string
+	}{
+		{Languages{"en", "ru"}, "News/Evening/Главное за день/Current affairs, News"},
+		{Languages{"ru"}, "Новости/Evening/Главное за день/Новости"},
+		{Languages{"", "en"}, "Nachrichten/Evening/Главное за день/Current affairs, News"},
+	}
+
+	for _, test := range tests {
+
+		pd, err := g.ProgrammeDescription(programmes[0].PID, test.langs)
+
+		if err != nil {
+			t.Fatalf("ProgrammeDescription(%q) = %v", test.langs, err)
+		}
+
+		got := strings.Join([]string{pd.Title, pd.SubTitle, pd.Description, pd.ProgrammeCategories()}, "/")
+
+		if got != test.want {
+			t.Errorf("ProgrammeDescription(%q) = %q, want %q", test.langs, got, test.want)
+		}
+	}
+}
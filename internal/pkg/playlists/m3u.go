@@ -19,10 +19,14 @@ package playlists
 import (
 	"bufio"
 	"errors"
+	"fmt"
 	"strings"
 	"unicode"
 )
 
+// errEmptyPlaylist - the playlist data is empty
+var errEmptyPlaylist = errors.New("M3UPlaylistParser: the playlist is empty")
+
 // M3UPlaylistParser - parser for m3u playlist format
 type M3UPlaylistParser struct {
 	guide string
@@ -59,7 +63,7 @@ func (parser *M3UPlaylistParser) AsyncParse(data []byte, onItem OnPlaylistItemEv
 	parser.guide = ""
 
 	if len(data) == 0 {
-		return errors.New("M3UPlaylistParser: the playlist is empty")
+		return &ParseError{Line: 1, Err: errEmptyPlaylist}
 	}
 
 	content := string(data)
@@ -67,8 +71,24 @@ func (parser *M3UPlaylistParser) AsyncParse(data []byte, onItem OnPlaylistItemEv
 
 	scanner := bufio.NewScanner(sreader)
 
+	// number - the number of the lines read, offset - the offset of the next line
+	var (
+		number int
+		offset int64
+	)
+
+	scanner.Split(func(data []byte, atEOF bool) (advance int, token []byte, err error) {
+
+		advance, token, err = bufio.ScanLines(data, atEOF)
+		offset += int64(advance)
+
+		return
+	})
+
 	for scanner.Scan() {
 
+		number++
+
 		line := printable(scanner.Text())
 
 		if len(line) > 0 {
@@ -102,6 +122,10 @@ func (parser *M3UPlaylistParser) AsyncParse(data []byte, onItem OnPlaylistItemEv
 		}
 	}
 
+	if err := scanner.Err(); err != nil {
+		return &ParseError{Line: number + 1, Offset: offset, Err: fmt.Errorf("M3UPlaylistParser: %w", err)}
+	}
+
 	return nil
 }
 
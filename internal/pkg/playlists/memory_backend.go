@@ -0,0 +1,439 @@
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package playlists
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	strutils "go-tvguide/internal/pkg/strutils"
+	xmltv "go-tvguide/pkg/xmltv"
+)
+
+// memoryTimeFormat - the format of the times of the programmes returned by the sqlite backend
+const memoryTimeFormat = "2006-01-02 15:04:05"
+
+// memoryProgramme - the programme kept by the memory backend
+type memoryProgramme struct {
+	pid         int
+	start, stop time.Time
+	programme   *xmltv.XMLTVProgramme
+}
+
+// memoryBackend keeps the playlist and the tv guide in memory. It needs neither cgo nor the database,
+// the results of its queries are the same as the ones of the sqlite backend. The backend is safe
+// for concurrent use, the playlist and the tv guide being read replace the stored ones at the end
+type memoryBackend struct {
+	// mu guards the stored playlist and tv guide
+	mu         sync.RWMutex
+	items      []*PlaylistItem
+	channels   []*xmltv.XMLTVChannel
+	programmes []*memoryProgramme
+	pids       map[int]*memoryProgramme
+
+	// pmu and gmu serialize the readings of the playlist and the tv guide, the fields below
+	// belong to the readings
+	pmu            sync.Mutex
+	gmu            sync.Mutex
+	pid            int
+	readItems      []*PlaylistItem
+	readChannels   []*xmltv.XMLTVChannel
+	readProgrammes []*memoryProgramme
+}
+
+// NewMemoryBackend returns the empty backend keeping the playlist and the tv guide in memory
+func NewMemoryBackend() IBackend {
+	return &memoryBackend{pids: make(map[int]*memoryProgramme)}
+}
+
+func (b *memoryBackend) BeginPlaylist() error {
+
+	b.pmu.Lock()
+	b.readItems = make([]*PlaylistItem, 0)
+
+	return nil
+}
+
+func (b *memoryBackend) AppendPlaylistItem(item *PlaylistItem) error {
+
+	if item == nil {
+		return errors.New("Playlist.AppendItem: cannot append an empty item")
+	}
+
+	i := *item
+	b.readItems = append(b.readItems, &i)
+
+	return nil
+}
+
+func (b *memoryBackend) EndPlaylist(failed error) error {
+
+	defer b.pmu.Unlock()
+
+	if failed == nil {
+		b.mu.Lock()
+		b.items = b.readItems
+		b.mu.Unlock()
+	}
+
+	b.readItems = nil
+
+	return failed
+}
+
+func (b *memoryBackend) Groups() ([]string, error) {
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	groups := make([]string, 0)
+	known := make(map[string]bool)
+
+	for _, item := range b.items {
+
+		if !known[item.GroupTitle] {
+			known[item.GroupTitle] = true
+			groups = append(groups, item.GroupTitle)
+		}
+	}
+
+	return groups, nil
+}
+
+func (b *memoryBackend) Channels(group string) ([]*PlaylistItem, error) {
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	items := make([]*PlaylistItem, 0)
+
+	for _, item := range b.items {
+
+		if item.GroupTitle == group {
+			i := *item
+			items = append(items, &i)
+		}
+	}
+
+	return items, nil
+}
+
+func (b *memoryBackend) BeginGuide() error {
+
+	b.gmu.Lock()
+
+	b.readChannels = make([]*xmltv.XMLTVChannel, 0)
+	b.readProgrammes = make([]*memoryProgramme, 0)
+
+	return nil
+}
+
+func (b *memoryBackend) AppendChannel(c *xmltv.XMLTVChannel) error {
+
+	if c == nil {
+		return errors.New("Guide.AppendChannel: cannot append an empty channel")
+	}
+
+	ch := *c
+	b.readChannels = append(b.readChannels, &ch)
+
+	return nil
+}
+
+func (b *memoryBackend) AppendProgramme(p *xmltv.XMLTVProgramme) error {
+
+	start, stop, err := p.Times()
+
+	if err != nil {
+		return err
+	}
+
+	b.pid++
+
+	programme := *p
+	b.readProgrammes = append(b.readProgrammes, &memoryProgramme{pid: b.pid, start: start, stop: stop,
+		programme: &programme})
+
+	return nil
+}
+
+func (b *memoryBackend) EndGuide(failed error) error {
+
+	defer b.gmu.Unlock()
+
+	if failed == nil {
+
+		pids := make(map[int]*memoryProgramme, len(b.readProgrammes))
+
+		for _, p := range b.readProgrammes {
+			pids[p.pid] = p
+		}
+
+		b.mu.Lock()
+		b.channels, b.programmes, b.pids = b.readChannels, b.readProgrammes, pids
+		b.mu.Unlock()
+	}
+
+	b.readChannels, b.readProgrammes = nil, nil
+
+	return failed
+}
+
+func (b *memoryBackend) ChannelGuideQuery(cid string, q *GuideQuery) ([]*Programme, error) {
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	chguide := make([]*Programme, 0)
+
+	// the channel is matched by its display name in any language
+	ids := make(map[string]bool)
+
+	for _, c := range b.channels {
+		for _, dn := range c.DisplayName {
+			if dn.Value == cid {
+				ids[c.ID] = true
+			}
+		}
+	}
+
+	from, to := searchTime(q.From), searchTime(q.To)
+	selected := make([]*memoryProgramme, 0)
+
+	for _, p := range b.programmes {
+
+		start := p.start.UTC().Format(memoryTimeFormat)
+
+		if !ids[p.programme.Channel] || (from != "" && start < from) || (to != "" && start >= to) {
+			continue
+		}
+
+		if q.Category != "" && !p.hasCategory(q.Category) {
+			continue
+		}
+
+		selected = append(selected, p)
+	}
+
+	sort.SliceStable(selected, func(i, j int) bool {
+		return selected[i].start.Before(selected[j].start)
+	})
+
+	if q.Offset > 0 {
+
+		if q.Offset >= len(selected) {
+			return chguide, nil
+		}
+
+		selected = selected[q.Offset:]
+	}
+
+	if q.Limit > 0 && q.Limit < len(selected) {
+		selected = selected[:q.Limit]
+	}
+
+	langs := q.Languages.list()
+
+	for _, p := range selected {
+
+		start, stop, err := p.times()
+
+		if err != nil {
+			return make([]*Programme, 0), err
+		}
+
+		title := ""
+
+		if i := bestLanguage(langs, len(p.programme.Title), func(i int) string {
+			return p.programme.Title[i].Lang
+		}); i >= 0 {
+			title = p.programme.Title[i].Value
+		}
+
+		chguide = append(chguide, &Programme{p.pid, start, stop, title})
+	}
+
+	return chguide, nil
+}
+
+func (b *memoryBackend) ProgrammeDescription(pid int, langs Languages) (*ProgrammeDescription, error) {
+
+	pd := &ProgrammeDescription{}
+	pd.PID = pid
+
+	b.mu.RLock()
+	p, ok := b.pids[pid]
+	b.mu.RUnlock()
+
+	if !ok {
+		return pd, fmt.Errorf("%w: programme %d", ErrNotFound, pid)
+	}
+
+	start, stop, err := p.times()
+
+	if err != nil {
+		return pd, err
+	}
+
+	pd.Start, pd.Stop = start, stop
+
+	list, x := langs.list(), p.programme
+
+	if i := bestLanguage(list, len(x.Title), func(i int) string { return x.Title[i].Lang }); i >= 0 {
+		pd.Title = x.Title[i].Value
+	}
+
+	if i := bestLanguage(list, len(x.Desc), func(i int) string { return x.Desc[i].Lang }); i >= 0 {
+		pd.Description = x.Desc[i].Value
+	}
+
+	if i := bestLanguage(list, len(x.SubTitle), func(i int) string { return x.SubTitle[i].Lang }); i >= 0 {
+		pd.SubTitle = x.SubTitle[i].Value
+	}
+
+	// the categories and the countries in the most preferred language available
+	values := make([]string, 0)
+
+	if i := bestLanguage(list, len(x.Categories), func(i int) string { return x.Categories[i].Lang }); i >= 0 {
+		for _, c := range x.Categories {
+			if c.Lang == x.Categories[i].Lang {
+				values = append(values, c.Value)
+			}
+		}
+	}
+
+	pd.Category = distinctStrings(values)
+
+	countries := make([]xmltv.XMLTVProgrammeCountry, 0)
+
+	for _, c := range x.Country {
+		if c.Value != "" {
+			countries = append(countries, c)
+		}
+	}
+
+	values = make([]string, 0)
+
+	if i := bestLanguage(list, len(countries), func(i int) string { return countries[i].Lang }); i >= 0 {
+		for _, c := range countries {
+			if c.Lang == countries[i].Lang {
+				values = append(values, c.Value)
+			}
+		}
+	}
+
+	pd.Country = distinctStrings(values)
+
+	for _, director := range x.Credits.Directors {
+		if director != "" {
+			d := director
+			pd.Directors = append(pd.Directors, &d)
+		}
+	}
+
+	for _, actor := range x.Credits.Actors {
+		if actor.Name != "" {
+			pd.Actors = append(pd.Actors, &ProgrammeActor{Actor: actor.Name, Role: actor.Role})
+		}
+	}
+
+	ratings := make(map[ProgrammeRating]bool)
+
+	for _, r := range x.Rating {
+
+		rating := ProgrammeRating{System: r.System, Rating: r.Value.Value}
+
+		if rating.System != "" && rating.Rating != "" && !ratings[rating] {
+			ratings[rating] = true
+			pd.Rating = append(pd.Rating, &rating)
+		}
+	}
+
+	sort.Slice(pd.Rating, func(i, j int) bool {
+
+		if pd.Rating[i].System != pd.Rating[j].System {
+			return pd.Rating[i].System < pd.Rating[j].System
+		}
+
+		return pd.Rating[i].Rating < pd.Rating[j].Rating
+	})
+
+	return pd, nil
+}
+
+// times returns the start and stop times of the programme as the sqlite backend does
+func (p *memoryProgramme) times() (start, stop time.Time, err error) {
+
+	var sstop sql.NullString
+
+	if !p.stop.IsZero() {
+		sstop = sql.NullString{String: p.stop.Local().Format(memoryTimeFormat), Valid: true}
+	}
+
+	return programmeTimes(p.start.Local().Format(memoryTimeFormat), sstop)
+}
+
+// hasCategory tells whether the programme has the category, case and accent insensitive
+func (p *memoryProgramme) hasCategory(category string) bool {
+
+	category = strutils.Fold(category)
+
+	for _, c := range p.programme.Categories {
+		if strutils.Fold(c.Value) == category {
+			return true
+		}
+	}
+
+	return false
+}
+
+// bestLanguage returns the index of the first of n texts in the most preferred language
+// of the list or -1 if there are no texts
+func bestLanguage(list string, n int, lang func(i int) string) int {
+
+	best, rank := -1, 0
+
+	for i := 0; i < n; i++ {
+		if r := languageRank(list, lang(i)); best < 0 || r < rank {
+			best, rank = i, r
+		}
+	}
+
+	return best
+}
+
+// distinctStrings returns the sorted distinct values, nil if there are no values
+func distinctStrings(values []string) []*string {
+
+	sort.Strings(values)
+
+	var result []*string
+
+	for i, v := range values {
+
+		if i == 0 || v != values[i-1] {
+			s := v
+			result = append(result, &s)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,219 @@
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package playlists
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	cmdCreateTableSchemaVersion = `CREATE TABLE IF NOT EXISTS schema_version(version INTEGER)`
+	cmdSelectSchemaVersion      = `SELECT version FROM schema_version`
+	cmdDeleteSchemaVersion      = `DELETE FROM schema_version`
+	cmdInsertSchemaVersion      = `INSERT INTO schema_version(version) VALUES(?)`
+
+	cmdSelectTableExists = `SELECT COUNT(*) FROM sqlite_master WHERE (type = 'table') AND (name = ?)`
+)
+
+// ErrSchemaTooNew - the database has been created by a newer version of the application
+var ErrSchemaTooNew = errors.New("the database schema is newer than supported")
+
+// migration upgrades the database schema to the version. The migration requiring the module
+// of sqlite sets the version only if sqlite is built without the module, the objects of the module
+// are created by the opening of the database with the module then
+type migration struct {
+	version     int
+	description string
+	commands    []string
+	requires    string
+}
+
+// migrations upgrade the database schema in order of versions. The released migrations
+// must not be changed, any change of the schema needs a new migration
+var migrations = [...]migration{
+	{1, "playlist and tv guide tables", []string{cmdCreateTablePlaylist, cmdCreateIndexPlaylistCID,
+		cmdCreateTableChannels, cmdCreateIndexChannelsCID, cmdCreateIndexChannelsChannelID,
+		cmdCreateTableChannelDisplayNames, cmdCreateIndexChannelDisplayNamesCID,
+		cmdCreateChannelURLTable, cmdCreateIndexChannelURLCID,
+		cmdCreateTableProgramme, cmdCreateIndexProgrammePID, cmdCreateIndexProgrammeChannelID,
+		cmdCreateTableProgrammeTitles, cmdCreateIndexProgrammeTitlesPID,
+		cmdCreateTableProgrammeSubTitle, cmdCreateIndexProgrammeSubTitlePID,
+		cmdCreateTableProgrammeDesc, cmdCreateIndexProgrammeDescPID,
+		cmdCreateTableProgrammeDates, cmdCreateIndexProgrammeDatesPID,
+		cmdCreateTableProgrammeCategories, cmdCreateIndexProgrammeCategoriesPID,
+		cmdCreateTableProgrammeKeywords, cmdCreateIndexProgrammeKeywordsPID,
+		cmdCreateTableProgrammeLanguage, cmdCreateIndexProgrammeLanguagePID,
+		cmdCreateTableProgrammeOriginalLanguage, cmdCreateIndexProgrammeOriginalLanguagePID,
+		cmdCreateTableProgrammeCountries, cmdCreateIndexProgrammeCountriesPID,
+		cmdCreateTableProgrammeDirectors, cmdCreateIndexProgrammeDirectorsPID,
+		cmdCreateTableProgrammeWriters, cmdCreateIndexProgrammeWritersPID,
+		cmdCreateTableProgrammeAdapters, cmdCreateIndexProgrammeAdaptersPID,
+		cmdCreateTableProgrammeProducers, cmdCreateIndexProgrammeProducersPID,
+		cmdCreateTableProgrammeComposers, cmdCreateIndexProgrammeComposersPID,
+		cmdCreateTableProgrammeEditors, cmdCreateIndexProgrammeEditorsPID,
+		cmdCreateTableProgrammePresenters, cmdCreateIndexProgrammePresentersPID,
+		cmdCreateTableProgrammeCommentators, cmdCreateIndexProgrammeCommentatorsPID,
+		cmdCreateTableProgrammeGuests, cmdCreateIndexProgrammeGuestsPID,
+		cmdCreateTableProgrammeActors, cmdCreateIndexProgrammeActorsPID,
+		cmdCreateTableProgrammeLength, cmdCreateIndexProgrammeLengthPID,
+		cmdCreateTableProgrammeIcon, cmdCreateIndexProgrammeIconPID,
+		cmdCreateTableProgrammeEpisodeNum, cmdCreateIndexProgrammeEpisodeNumPID,
+		cmdCreateTableProgrammeVideo, cmdCreateIndexProgrammeVideoPID,
+		cmdCreateTableProgrammeAudio, cmdCreateIndexProgrammeAudioPID,
+		cmdCreateTableProgrammePreviouslyShown, cmdCreateIndexProgrammePreviouslyShownPID,
+		cmdCreateTableProgrammePremiere, cmdCreateIndexProgrammePremierePID,
+		cmdCreateTableProgrammeLastChance, cmdCreateIndexProgrammeLastChancePID,
+		cmdCreateTableProgrammeSubtitles, cmdCreateIndexProgrammeSubtitlesPID, cmdCreateIndexProgrammeSubtitlesType,
+		cmdCreateTableProgrammeRating, cmdCreateIndexProgrammeRatingPID, cmdCreateIndexProgrammeRatingSystem,
+		cmdCreateTableProgrammeStarRating, cmdCreateIndexProgrammeStarRatingPID, cmdCreateIndexProgrammeStarRatingSystem,
+		cmdCreateTableProgrammeReview, cmdCreateIndexProgrammeReviewPID,
+		cmdCreateTableProgrammeLangStat}, ""},
+	{2, "sources of playlists and tv guides", []string{cmdCreateTableSources, cmdCreateIndexSourcesURL,
+		`ALTER TABLE playlist ADD COLUMN sid INTEGER`,
+		`ALTER TABLE channels ADD COLUMN sid INTEGER`,
+		`ALTER TABLE programme ADD COLUMN sid INTEGER`,
+		`ALTER TABLE programme_lang_stat ADD COLUMN sid INTEGER`,
+		`UPDATE playlist SET sid = 0`,
+		`UPDATE channels SET sid = 0`,
+		`UPDATE programme SET sid = 0`,
+		`UPDATE programme_lang_stat SET sid = 0`,
+		cmdCreateIndexPlaylistSID, cmdCreateIndexChannelsSID, cmdCreateIndexProgrammeSID}, ""},
+	{3, "programme search index", []string{cmdCreateProgrammeSearch}, "fts5"},
+}
+
+// schemaVersion returns the version of the database schema. Databases created before
+// the versioning are recognized by their tables, versioned is false for them
+func schemaVersion(db *sql.DB) (version int, versioned bool, err error) {
+
+	exists := func(table string) (bool, error) {
+
+		var count int
+
+		err := db.QueryRow(cmdSelectTableExists, table).Scan(&count)
+		return count > 0, err
+	}
+
+	if versioned, err = exists("schema_version"); err != nil || versioned {
+
+		if err == nil {
+			if err = db.QueryRow(cmdSelectSchemaVersion).Scan(&version); err == sql.ErrNoRows {
+				err = nil
+			}
+		}
+
+		return
+	}
+
+	for _, legacy := range [2]struct {
+		table   string
+		version int
+	}{{"sources", 2}, {"playlist", 1}} {
+
+		var ok bool
+
+		if ok, err = exists(legacy.table); err != nil || ok {
+			return legacy.version, false, err
+		}
+	}
+
+	return
+}
+
+// migrate upgrades the database schema to the latest version. The database with a newer schema is refused
+func migrate(db *sql.DB) (err error) {
+
+	version, versioned, err := schemaVersion(db)
+
+	if err != nil {
+		return
+	}
+
+	latest := migrations[len(migrations)-1].version
+
+	if version > latest {
+		return fmt.Errorf("%w: version %d, supported %d", ErrSchemaTooNew, version, latest)
+	}
+
+	// the legacy database of the latest schema needs the version only
+	if version == latest && !versioned {
+		m := migration{version: version, description: "schema version"}
+		return m.apply(db)
+	}
+
+	for _, m := range migrations {
+
+		if m.version <= version {
+			continue
+		}
+
+		if err = m.apply(db); err != nil {
+			return fmt.Errorf("migration to the schema version %d (%s): %w", m.version, m.description, err)
+		}
+	}
+
+	return
+}
+
+// apply runs the migration and sets the schema version in the single transaction
+func (m *migration) apply(db *sql.DB) (err error) {
+
+	tx, err := db.Begin()
+
+	if err != nil {
+		return
+	}
+
+	defer func() {
+
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+
+		err = tx.Commit()
+	}()
+
+	for _, command := range m.commands {
+		if _, err = tx.Exec(command); err != nil {
+
+			if m.requires != "" && isMissingModule(err, m.requires) {
+				err = nil
+				break
+			}
+
+			return
+		}
+	}
+
+	for _, command := range [2]string{cmdCreateTableSchemaVersion, cmdDeleteSchemaVersion} {
+		if _, err = tx.Exec(command); err != nil {
+			return
+		}
+	}
+
+	_, err = tx.Exec(cmdInsertSchemaVersion, m.version)
+
+	return
+}
+
+// isMissingModule tells whether the error is caused by the module sqlite is built without
+func isMissingModule(err error, module string) bool {
+	return strings.Contains(err.Error(), "no such module: "+module)
+}
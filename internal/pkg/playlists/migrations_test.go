@@ -0,0 +1,205 @@
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package playlists
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// openTestDatabase returns an isolated in-memory database filled by the SQL script, if specified
+func openTestDatabase(t *testing.T, script string) *sql.DB {
+
+	tdb, err := openDB(":memory:")
+
+	if errors.Is(err, ErrNoSQLite) {
+		t.Skip(err)
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { tdb.Close() })
+
+	if script == "" {
+		return tdb
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join("testdata", script))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = tdb.Exec(string(data)); err != nil {
+		t.Fatal(err)
+	}
+
+	return tdb
+}
+
+// schemaOf returns the columns of the tables of the database. The indexes of the tv guide
+// are not compared as they are rebuilt by the guide reading
+func schemaOf(t *testing.T, tdb *sql.DB) []string {
+
+	rows, err := tdb.Query(`SELECT name FROM sqlite_master WHERE type = 'table' ORDER BY name`)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var objects, tables []string
+
+	for rows.Next() {
+
+		var name string
+
+		if err = rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+
+		tables = append(tables, name)
+	}
+
+	rows.Close()
+
+	for _, table := range tables {
+
+		rows, err := tdb.Query(`SELECT name, type FROM pragma_table_info(?) ORDER BY name`, table)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for rows.Next() {
+
+			var name, kind string
+
+			if err = rows.Scan(&name, &kind); err != nil {
+				t.Fatal(err)
+			}
+
+			objects = append(objects, fmt.Sprintf("column %s.%s %s", table, name, kind))
+		}
+
+		rows.Close()
+	}
+
+	return objects
+}
+
+func TestMigrate(t *testing.T) {
+
+	latest := migrations[len(migrations)-1].version
+
+	fresh := openTestDatabase(t, "")
+
+	if err := migrate(fresh); err != nil {
+		t.Fatalf("migrate() = %v", err)
+	}
+
+	var tests = []struct {
+		script  string
+		version int
+	}{
+		{"schema_v1.sql", 1},
+		{"schema_v2.sql", 2},
+	}
+
+	for _, test := range tests {
+
+		tdb := openTestDatabase(t, test.script)
+
+		if version, versioned, err := schemaVersion(tdb); err != nil || version != test.version || versioned {
+			t.Errorf("%s: schemaVersion() = %d, %v, %v", test.script, version, versioned, err)
+		}
+
+		for i := 0; i < 2; i++ {
+			if err := migrate(tdb); err != nil {
+				t.Fatalf("%s: migrate() = %v", test.script, err)
+			}
+		}
+
+		if version, versioned, err := schemaVersion(tdb); err != nil || version != latest || !versioned {
+			t.Errorf("%s: schemaVersion() after migration = %d, %v, %v", test.script, version, versioned, err)
+		}
+
+		if got, want := schemaOf(t, tdb), schemaOf(t, fresh); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: migrated schema\n%v\nwant\n%v", test.script, got, want)
+		}
+
+		var title string
+
+		stmts := newStmtCache(tdb)
+		defer stmts.close()
+
+		g := &Guide{db: tdb, stmts: stmts, sid: int64(test.version-1) * 2}
+
+		if err := tdb.QueryRow(`SELECT pt.title FROM programme AS p
+			INNER JOIN programme_titles AS pt ON (pt.pid = p.pid) WHERE p.sid = ?`, g.sid).Scan(&title); err != nil || title != "News" {
+			t.Errorf("%s: programme title = %q, %v", test.script, title, err)
+		}
+
+		if lang, err := g.DefaultProgrammeLanguage(); err != nil || lang != "ru" {
+			t.Errorf("%s: DefaultProgrammeLanguage() = %q, %v", test.script, lang, err)
+		}
+	}
+}
+
+func TestMigrateNewer(t *testing.T) {
+
+	tdb := openTestDatabase(t, "")
+
+	if err := migrate(tdb); err != nil {
+		t.Fatalf("migrate() = %v", err)
+	}
+
+	if _, err := tdb.Exec(`UPDATE schema_version SET version = version + 1`); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := migrate(tdb); !errors.Is(err, ErrSchemaTooNew) {
+		t.Errorf("migrate() = %v, want %v", err, ErrSchemaTooNew)
+	}
+}
+
+func TestMigrationRequires(t *testing.T) {
+
+	tdb := openTestDatabase(t, "")
+
+	m := migration{1, "missing module", []string{`CREATE VIRTUAL TABLE t USING no_module(a)`}, "no_module"}
+
+	if err := m.apply(tdb); err != nil {
+		t.Fatalf("apply() = %v", err)
+	}
+
+	if version, versioned, err := schemaVersion(tdb); err != nil || version != 1 || !versioned {
+		t.Errorf("schemaVersion() = %d, %v, %v", version, versioned, err)
+	}
+
+	m.requires = ""
+
+	if err := m.apply(tdb); err == nil {
+		t.Error("apply() without the required module = nil, want error")
+	}
+}
@@ -0,0 +1,163 @@
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package playlists
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// The channels of the playlist are matched with the channels of the guide by any display name. The current
+// and the next programmes are found by the correlated subqueries, the programme without stop time lasts
+// until the local midnight
+
+const cmdSelectNowNext = `WITH pc AS (
+		SELECT pl.rowid AS position, pl.id, pl.channels_group, pl.channel, pl.source
+			, (SELECT c.channel_id FROM channels AS c
+				INNER JOIN channel_display_names AS cdn ON (cdn.cid = c.cid) AND (cdn.display_name = pl.id)
+				WHERE c.sid = ?2
+				ORDER BY c.cid LIMIT 1) AS channel_id
+		FROM playlist AS pl
+		WHERE pl.sid = ?3
+	), pn AS (
+		SELECT pc.*
+			, (SELECT p.pid FROM programme AS p
+				WHERE (p.sid = ?2) AND (p.channel_id = pc.channel_id) AND (datetime(p.start) <= ?4)
+					AND (ifnull(datetime(p.stop), datetime(p.start, 'localtime', 'start of day', '+1 day', 'utc')) > ?4)
+				ORDER BY p.start DESC LIMIT 1) AS now_pid
+			, (SELECT p.pid FROM programme AS p
+				WHERE (p.sid = ?2) AND (p.channel_id = pc.channel_id) AND (datetime(p.start) > ?4)
+				ORDER BY p.start LIMIT 1) AS next_pid
+		FROM pc
+		WHERE pc.channel_id IS NOT NULL
+	)
+	SELECT pn.id, pn.channels_group, pn.channel, pn.source
+		, n.pid, datetime(n.start, 'localtime'), datetime(n.stop, 'localtime')
+		, ifnull((SELECT pt.title FROM programme_titles AS pt WHERE pt.pid = n.pid
+			ORDER BY lang_rank(?1, pt.lang) LIMIT 1), '')
+		, ifnull(min(max((julianday(?4) - julianday(n.start))
+			/ (julianday(ifnull(n.stop, datetime(n.start, 'localtime', 'start of day', '+1 day', 'utc')))
+			- julianday(n.start)), 0.0), 1.0), 0.0)
+		, x.pid, datetime(x.start, 'localtime'), datetime(x.stop, 'localtime')
+		, ifnull((SELECT pt.title FROM programme_titles AS pt WHERE pt.pid = x.pid
+			ORDER BY lang_rank(?1, pt.lang) LIMIT 1), '')
+	FROM pn
+		LEFT JOIN programme AS n ON (n.pid = pn.now_pid)
+		LEFT JOIN programme AS x ON (x.pid = pn.next_pid)
+	ORDER BY pn.position`
+
+// ErrNoPlaylist - the guide has no playlist to match the channels with
+var ErrNoPlaylist = errors.New("Guide: the playlist is not specified")
+
+// ChannelNowNext contains the current and the next programmes of the playlist channel
+type ChannelNowNext struct {
+	Channel *PlaylistItem `json:"channel"`
+	// Now is nil if nothing is on the channel
+	Now *Programme `json:"now"`
+	// Next is nil if the guide has no more programmes of the channel
+	Next *Programme `json:"next"`
+	// Progress - the passed part of the current programme from 0 to 1
+	Progress float64 `json:"progress"`
+}
+
+// SetPlaylist sets the playlist which channels are matched with the channels of the guide
+func (g *Guide) SetPlaylist(p *Playlist) {
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.playlist = p
+}
+
+// currentPlaylist returns the playlist of the guide
+func (g *Guide) currentPlaylist() *Playlist {
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return g.playlist
+}
+
+// NowNext returns the current and the next programmes at the time t for every playlist channel
+// found in the guide. The channels are in the playlist order
+func (g *Guide) NowNext(t time.Time, langs Languages) ([]*ChannelNowNext, error) {
+
+	items := make([]*ChannelNowNext, 0)
+
+	playlist := g.currentPlaylist()
+
+	if playlist == nil {
+		return items, ErrNoPlaylist
+	}
+
+	rows, err := g.stmts.query(cmdSelectNowNext, langs.list(), g.source(), playlist.source(), searchTime(t))
+
+	if err != nil {
+		return items, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+
+		var (
+			item           = &ChannelNowNext{Channel: &PlaylistItem{}}
+			npid, xpid     sql.NullInt64
+			nstart, xstart sql.NullString
+			nstop, xstop   sql.NullString
+			ntitle, xtitle string
+		)
+
+		if err = rows.Scan(&item.Channel.ID, &item.Channel.GroupTitle, &item.Channel.Name, &item.Channel.URL,
+			&npid, &nstart, &nstop, &ntitle, &item.Progress, &xpid, &xstart, &xstop, &xtitle); err != nil {
+			return make([]*ChannelNowNext, 0), err
+		}
+
+		if item.Now, err = nowNextProgramme(npid, nstart, nstop, ntitle); err != nil {
+			return make([]*ChannelNowNext, 0), err
+		}
+
+		if item.Next, err = nowNextProgramme(xpid, xstart, xstop, xtitle); err != nil {
+			return make([]*ChannelNowNext, 0), err
+		}
+
+		items = append(items, item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return make([]*ChannelNowNext, 0), err
+	}
+
+	return items, nil
+}
+
+// nowNextProgramme returns the programme selected by the left join or nil
+func nowNextProgramme(pid sql.NullInt64, sstart, sstop sql.NullString, title string) (*Programme, error) {
+
+	if !pid.Valid {
+		return nil, nil
+	}
+
+	start, stop, err := programmeTimes(sstart.String, sstop)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Programme{int(pid.Int64), start, stop, title}, nil
+}
@@ -0,0 +1,107 @@
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package playlists
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	xmltv "go-tvguide/pkg/xmltv"
+)
+
+const testNowNextPlaylist = `#EXTM3U
+#EXTINF:-1 tvg-name="Sport" group-title="Sport",Sport
+http://example.com/sport
+#EXTINF:-1 tvg-name="Unknown" group-title="News",Unknown
+http://example.com/unknown
+#EXTINF:-1 tvg-name="News" group-title="News",News
+http://example.com/news
+`
+
+const testNowNextGuide = `<?xml version="1.0" encoding="UTF-8"?>
+<tv>
+<channel id="1"><display-name lang="en">News</display-name></channel>
+<channel id="2"><display-name lang="en">Sport</display-name></channel>
+<programme start="20181027180000 +0000" stop="20181027190000 +0000" channel="1"><title lang="en">Evening news</title></programme>
+<programme start="20181027190000 +0000" stop="20181027200000 +0000" channel="1"><title lang="en">Weather</title></programme>
+<programme start="20181027200000 +0000" stop="20181027210000 +0000" channel="1"><title lang="en">Late news</title></programme>
+<programme start="20181027200000 +0000" stop="20181027220000 +0000" channel="2"><title lang="en">Football</title></programme>
+</tv>`
+
+func TestGuideNowNext(t *testing.T) {
+
+	g := newTestGuide(t)
+	p := &Playlist{db: g.db, stmts: g.stmts}
+
+	if err := p.Read([]byte(testNowNextPlaylist), &M3UPlaylistParser{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the stop times of the past years are patched on reading
+	year := time.Now().Year() + 1
+	data := strings.Replace(testNowNextGuide, "2018", fmt.Sprint(year), -1)
+
+	if _, err := g.Read([]byte(data), &xmltv.XMLTVParser{}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := g.NowNext(time.Now(), Languages{"en"}); err != ErrNoPlaylist {
+		t.Errorf("NowNext() without playlist = %v, want %v", err, ErrNoPlaylist)
+	}
+
+	g.SetPlaylist(p)
+
+	title := func(p *Programme) string {
+
+		if p == nil {
+			return "-"
+		}
+
+		return p.Title
+	}
+
+	var tests = []struct {
+		t    time.Time
+		want string
+	}{
+		{time.Date(year, 10, 27, 19, 15, 0, 0, time.UTC), "Sport: - / Football 0.00|News: Weather / Late news 0.25"},
+		{time.Date(year, 10, 27, 21, 0, 0, 0, time.UTC), "Sport: Football / - 0.50|News: - / - 0.00"},
+		{time.Date(year, 10, 27, 17, 0, 0, 0, time.UTC), "Sport: - / Football 0.00|News: - / Evening news 0.00"},
+	}
+
+	for _, test := range tests {
+
+		items, err := g.NowNext(test.t, Languages{"en"})
+
+		if err != nil {
+			t.Fatalf("NowNext(%v) = %v", test.t, err)
+		}
+
+		got := make([]string, 0)
+
+		for _, item := range items {
+			got = append(got, fmt.Sprintf("%s: %s / %s %.2f", item.Channel.Name, title(item.Now), title(item.Next),
+				item.Progress))
+		}
+
+		if strings.Join(got, "|") != test.want {
+			t.Errorf("NowNext(%v) = %q, want %q", test.t, strings.Join(got, "|"), test.want)
+		}
+	}
+}
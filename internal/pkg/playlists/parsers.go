@@ -16,6 +16,12 @@
 
 package playlists
 
+import (
+	"bytes"
+
+	xmltv "go-tvguide/pkg/xmltv"
+)
+
 // OnPlaylistItemEvent - an event that occurs when another playlist item is parsed
 type OnPlaylistItemEvent func(item *PlaylistItem) error
 
@@ -26,3 +32,17 @@ type IPlaylistParser interface {
 	Guide() string
 	Items() []*PlaylistItem
 }
+
+// IGuideParser - common tv guide parser interface
+type IGuideParser interface {
+	Events() *xmltv.GuideEvents
+	Parse(data []byte) error
+}
+
+// isXML tells whether the data looks like XML, the empty data is the empty guide
+func isXML(data []byte) bool {
+
+	data = bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+
+	return len(data) == 0 || data[0] == '<'
+}
@@ -0,0 +1,200 @@
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package playlists
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	strutils "go-tvguide/internal/pkg/strutils"
+)
+
+// CreditRoles - roles of the programme credits in the order of XMLTV
+var CreditRoles = []string{"director", "actor", "writer", "adapter", "producer", "composer", "editor",
+	"presenter", "commentator", "guest"}
+
+// creditTables - tables and columns of the credit roles, the actors have the character column
+var creditTables = map[string][2]string{
+	"director":    {"programme_directors", "director"},
+	"actor":       {"programme_actors", "actor"},
+	"writer":      {"programme_writers", "writer"},
+	"adapter":     {"programme_adapters", "adapter"},
+	"producer":    {"programme_producers", "producer"},
+	"composer":    {"programme_composers", "composer"},
+	"editor":      {"programme_editors", "editor"},
+	"presenter":   {"programme_presenters", "presenter"},
+	"commentator": {"programme_commentators", "commentator"},
+	"guest":       {"programme_guests", "guest"},
+}
+
+const (
+	cmdSelectProgrammesByPerson = `SELECT p.pid, datetime(p.start, 'localtime') AS start
+		, datetime(p.stop, 'localtime') AS stop
+		, ifnull((SELECT pt.title FROM programme_titles AS pt WHERE pt.pid = p.pid
+			ORDER BY lang_rank(?1, pt.lang) LIMIT 1), '') AS title
+		, p.channel_id, ifnull((SELECT cdn.display_name FROM channels AS c
+			INNER JOIN channel_display_names AS cdn ON (cdn.cid = c.cid)
+			WHERE (c.channel_id = p.channel_id) AND (c.sid = p.sid)
+			ORDER BY lang_rank(?1, cdn.lang) LIMIT 1), '') AS channel
+		, cr.name, cr.role, cr.character
+	FROM (%s) AS cr
+		INNER JOIN programme AS p ON (p.pid = cr.pid)
+	WHERE (p.sid = ?2) AND (instr(fold(cr.name), ?3) > 0)
+		AND ((?4 = '') OR (ifnull(datetime(p.stop), datetime(p.start)) > ?4))
+		AND ((?5 = '') OR (datetime(p.start) < ?5))
+	ORDER BY p.start, p.channel_id, cr.role_order, cr.name
+	LIMIT ?6`
+
+	// maxPersonResults limits the number of the programmes found by the person
+	maxPersonResults = 500
+)
+
+// PersonProgramme - the programme featuring the person
+type PersonProgramme struct {
+	Programme
+	ChannelID   string `json:"channel_id"`
+	ChannelName string `json:"channel_name"`
+	Person      string `json:"person"`
+	Role        string `json:"role"`
+	Character   string `json:"character"`
+}
+
+// PersonCredit returns the person and the role, and the character for the actors
+func (p *PersonProgramme) PersonCredit() string {
+
+	if p.Character != "" {
+		return p.Person + ", " + p.Role + " (" + p.Character + ")"
+	}
+
+	return p.Person + ", " + p.Role
+}
+
+// creditsQuery returns the query of the credits of the roles as the rows of pid, name, role, character
+// and the order of the role
+func creditsQuery(roles []string) (string, error) {
+
+	if len(roles) == 0 {
+		roles = CreditRoles
+	}
+
+	queries := make([]string, 0, len(roles))
+	used := make(map[string]bool, len(roles))
+
+	for _, role := range roles {
+
+		role = strings.ToLower(strings.TrimSpace(role))
+		t, ok := creditTables[role]
+
+		if !ok {
+			return "", fmt.Errorf("%w: unknown credit role %q, expected one of %s", ErrInvalidArgument, role,
+				strings.Join(CreditRoles, ", "))
+		}
+
+		if used[role] {
+			continue
+		}
+
+		used[role] = true
+
+		character := "''"
+
+		if role == "actor" {
+			character = "ifnull(role, '')"
+		}
+
+		queries = append(queries, fmt.Sprintf(
+			"SELECT pid, %s AS name, '%s' AS role, %s AS character, %d AS role_order FROM %s",
+			t[1], role, character, roleOrder(role), t[0]))
+	}
+
+	return strings.Join(queries, "\n\t\tUNION ALL "), nil
+}
+
+// roleOrder returns the position of the role in CreditRoles
+func roleOrder(role string) int {
+
+	for i, r := range CreditRoles {
+		if r == role {
+			return i
+		}
+	}
+
+	return len(CreditRoles)
+}
+
+// ProgrammesByPerson returns the programmes featuring the person in any of the roles ordered by the start time.
+// The name matches any part of the credited names case and accent insensitive. The empty roles mean all
+// credit roles, zero from and to mean no time limits
+func (g *Guide) ProgrammesByPerson(name string, roles []string, from, to time.Time) ([]*PersonProgramme, error) {
+
+	programmes := make([]*PersonProgramme, 0)
+
+	credits, err := creditsQuery(roles)
+
+	if err != nil {
+		return programmes, err
+	}
+
+	fname := strutils.Fold(strings.TrimSpace(name))
+
+	if fname == "" {
+		return programmes, nil
+	}
+
+	langs, err := g.Languages()
+
+	if err != nil {
+		return programmes, err
+	}
+
+	rows, err := g.stmts.query(fmt.Sprintf(cmdSelectProgrammesByPerson, credits), langs.list(), g.source(), fname,
+		searchTime(from), searchTime(to), maxPersonResults)
+
+	if err != nil {
+		return programmes, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+
+		var (
+			p      PersonProgramme
+			sstart string
+			sstop  sql.NullString
+		)
+
+		if err = rows.Scan(&p.PID, &sstart, &sstop, &p.Title, &p.ChannelID, &p.ChannelName,
+			&p.Person, &p.Role, &p.Character); err != nil {
+			return make([]*PersonProgramme, 0), err
+		}
+
+		if p.Start, p.Stop, err = programmeTimes(sstart, sstop); err != nil {
+			return make([]*PersonProgramme, 0), err
+		}
+
+		programmes = append(programmes, &p)
+	}
+
+	if err = rows.Err(); err != nil {
+		return make([]*PersonProgramme, 0), err
+	}
+
+	return programmes, nil
+}
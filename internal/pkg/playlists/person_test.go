@@ -0,0 +1,92 @@
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package playlists
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	xmltv "go-tvguide/pkg/xmltv"
+)
+
+const testPersonGuide = `<?xml version="1.0" encoding="UTF-8"?>
+<tv>
+<channel id="1"><display-name lang="en">Movies</display-name></channel>
+<channel id="2"><display-name lang="en">Talk</display-name></channel>
+<programme start="20181027180000 +0000" stop="20181027200000 +0000" channel="1">
+<title lang="en">The Piano</title><credits><director>Jane Campion</director>
+<actor role="Ada">Holly Hunter</actor><actor role="Baines">Harvey Keitel</actor></credits></programme>
+<programme start="20181027210000 +0000" stop="20181027220000 +0000" channel="2">
+<title lang="en">Late Show</title><credits><presenter>Pedro Almodóvar</presenter>
+<guest>Harvey Keitel</guest></credits></programme>
+<programme start="20181028190000 +0000" stop="20181028210000 +0000" channel="1">
+<title lang="en">Volver</title><credits><director>Pedro Almodovar</director>
+<writer>PEDRO ALMODÓVAR</writer></credits></programme>
+</tv>`
+
+func TestGuideProgrammesByPerson(t *testing.T) {
+
+	g := newTestGuide(t)
+
+	if _, err := g.Read([]byte(testPersonGuide), &xmltv.XMLTVParser{}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	day := time.Date(2018, 10, 27, 0, 0, 0, 0, time.UTC)
+
+	var tests = []struct {
+		name     string
+		roles    []string
+		from, to time.Time
+		found    []string
+	}{
+		{"harvey keitel", nil, time.Time{}, time.Time{}, []string{"The Piano/actor/Baines", "Late Show/guest/"}},
+		{"almodovar", nil, time.Time{}, time.Time{},
+			[]string{"Late Show/presenter/", "Volver/director/", "Volver/writer/"}},
+		{"ALMODÓVAR", []string{"director", "writer"}, time.Time{}, time.Time{},
+			[]string{"Volver/director/", "Volver/writer/"}},
+		{"almodovar", nil, day.AddDate(0, 0, 1), time.Time{}, []string{"Volver/director/", "Volver/writer/"}},
+		{"keitel", []string{"guest"}, day, day.Add(21 * time.Hour), []string{}},
+		{"campion", []string{"actor"}, time.Time{}, time.Time{}, []string{}},
+		{" ", nil, time.Time{}, time.Time{}, []string{}},
+	}
+
+	for _, test := range tests {
+
+		programmes, err := g.ProgrammesByPerson(test.name, test.roles, test.from, test.to)
+
+		if err != nil {
+			t.Errorf("ProgrammesByPerson(%q) = %v", test.name, err)
+			continue
+		}
+
+		found := make([]string, 0)
+
+		for _, p := range programmes {
+			found = append(found, p.Title+"/"+p.Role+"/"+p.Character)
+		}
+
+		if strings.Join(found, "|") != strings.Join(test.found, "|") {
+			t.Errorf("ProgrammesByPerson(%q, %q) = %q, want %q", test.name, test.roles, found, test.found)
+		}
+	}
+
+	if _, err := g.ProgrammesByPerson("keitel", []string{"stuntman"}, time.Time{}, time.Time{}); err == nil {
+		t.Error("ProgrammesByPerson() with unknown role, want error")
+	}
+}
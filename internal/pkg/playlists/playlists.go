@@ -20,193 +20,370 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"log/slog"
+	"sync"
+	"time"
 )
 
 // PlaylistItem contains info about tv channel (URL, name, etc)
 type PlaylistItem struct {
-	Name       string
-	GroupTitle string
-	URL        string
-	ID         string
+	Name       string `json:"name"`
+	GroupTitle string `json:"group_title"`
+	URL        string `json:"url"`
+	ID         string `json:"id"`
 }
 
-// Playlist content
+// Playlist content. The playlist is safe for concurrent use, the readers see the playlist read
+// earlier until the new one is committed
 type Playlist struct {
 	pdb
-	db                     *sql.DB
+	db    *sql.DB
+	stmts *stmtCache
+	log   *slog.Logger
+
+	// mu guards the source of the stored playlist and the counters of its reading
+	mu    sync.RWMutex
+	sid   int64
+	hash  string
+	guide string
+	stats PlaylistStats
+
+	// wmu serializes the readings of the playlist, the fields below belong to the reading
+	wmu                    sync.Mutex
 	tx                     *sql.Tx
 	stmtInsertPlaylistItem *sql.Stmt
+	readSID                int64
+	readHash               string
+	readGuide              string
+	readItems              int
 }
 
-var (
-	pl *Playlist
-)
+// Read reads content of the playlist
+func (p *Playlist) Read(data []byte, parser IPlaylistParser) (err error) {
+	return p.read(data, parser, nil)
+}
+
+// Load reads content of the playlist from the source with the specified URL. The playlist stored
+// earlier is used if the content of the source has not changed
+func (p *Playlist) Load(url string, data []byte, parser IPlaylistParser) (unchanged bool, err error) {
+
+	s, err := findSource(p.db, sourcePlaylist, url)
+
+	if err != nil {
+		return
+	}
+
+	if s.id != 0 && s.hash == hashOf(data) {
 
-// CurrentPlaylist returns playlist object
-func CurrentPlaylist() *Playlist {
+		p.logger().Info("playlist is up to date", "url", url)
+		p.publish(s.id, s.hash, s.guide)
+		p.setStats(PlaylistStats{Bytes: int64(len(data)), Unchanged: true})
 
-	if pl == nil {
-		pl = &Playlist{db: db}
+		return true, nil
 	}
 
-	return pl
+	err = p.read(data, parser, s)
+
+	return
 }
 
-// Read reads content of the playlist
-func (p *Playlist) Read(data []byte, parser IPlaylistParser) (err error) {
+// Guide returns the address of the tv guide specified by the playlist
+func (p *Playlist) Guide() string {
 
-	tx, err := p.db.Begin()
+	p.mu.RLock()
+	defer p.mu.RUnlock()
 
-	if err != nil {
+	return p.guide
+}
+
+// source returns the identifier of the source of the stored playlist
+func (p *Playlist) source() int64 {
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.sid
+}
+
+// Stats returns the counters of the last reading of the playlist
+func (p *Playlist) Stats() PlaylistStats {
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.stats
+}
+
+func (p *Playlist) setStats(stats PlaylistStats) {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.stats = stats
+}
+
+// logger returns the logger of the playlist
+func (p *Playlist) logger() *slog.Logger {
+
+	if p.log == nil {
+		return discardLogger
+	}
+
+	return p.log
+}
+
+// contentHash returns the hash of the content of the stored playlist
+func (p *Playlist) contentHash() string {
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.hash
+}
+
+// publish makes the playlist of the source visible to the readers
+func (p *Playlist) publish(sid int64, hash, guide string) {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.sid, p.hash, p.guide = sid, hash, guide
+}
+
+func (p *Playlist) read(data []byte, parser IPlaylistParser, s *source) (err error) {
+
+	if parser == nil {
+		return ErrUnknownFormat
+	}
+
+	started := time.Now()
+
+	if err = p.begin(s); err != nil {
 		return
 	}
 
 	defer func() {
+
+		items := p.readItems
+		err = p.end(err)
+
 		if err != nil {
-			tx.Rollback()
+			p.logger().Error("playlist reading failed", "error", err)
 			return
 		}
 
-		tx.Commit()
+		stats := PlaylistStats{Bytes: int64(len(data)), Items: items, Duration: time.Since(started)}
+		p.setStats(stats)
+
+		p.logger().Info("playlist read", "items", stats.Items, "bytes", stats.Bytes, "duration", stats.Duration)
 	}()
 
-	p.tx = tx
-	p.stmtInsertPlaylistItem, err = tx.Prepare(cmdInsertPlaylistItem)
+	p.readHash = hashOf(data)
 
-	if err != nil {
+	if err = parser.AsyncParse(data, p.appendItem); err != nil {
 		return
 	}
 
-	callback := func(item *PlaylistItem) error {
-		return p.appendItem(item)
+	p.readGuide = parser.Guide()
+
+	if s != nil {
+		s.hash, s.guide = p.readHash, p.readGuide
+		err = saveSource(p.tx, s)
 	}
 
-	err = parser.AsyncParse(data, callback)
+	return
+}
+
+// begin starts the transaction replacing the playlist of the source or the playlist read without
+// the source. The transaction is the staging area of the playlist, the other readings wait until it ends
+func (p *Playlist) begin(s *source) (err error) {
+
+	p.wmu.Lock()
+
+	tx, err := p.db.Begin()
 
 	if err != nil {
+		p.wmu.Unlock()
 		return
 	}
 
-	err = p.analyze(p.db, p.tx)
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			p.wmu.Unlock()
+		}
+	}()
+
+	p.tx = tx
+	p.readSID, p.readHash, p.readGuide, p.readItems = 0, "", "", 0
+
+	// the content read without the source is kept by the source 0
+	if s == nil {
+
+		if _, err = tx.Exec(cmdDeletePlaylistSource, 0); err != nil {
+			return
+		}
+	} else {
+
+		if _, err = tx.Exec(cmdDeletePlaylistSource, s.id); err != nil {
+			return
+		}
+
+		if err = saveSource(tx, s); err != nil {
+			return
+		}
+
+		p.readSID = s.id
+	}
+
+	p.stmtInsertPlaylistItem, err = tx.Prepare(cmdInsertPlaylistItem)
 
 	return
 }
 
-// Groups returns existing groups in the playlist
-func (p *Playlist) Groups() []string {
+// end commits the playlist read and makes it visible to the readers or rolls it back
+// if the reading has failed
+func (p *Playlist) end(failed error) (err error) {
 
-	var group string
+	defer p.wmu.Unlock()
 
-	g := make([]string, 0)
+	p.stmtInsertPlaylistItem.Close()
 
-	stmt, err := p.db.Prepare(cmdSelectGroups)
+	if err = failed; err == nil {
+		err = p.analyze(p.db, p.tx)
+	}
 
 	if err != nil {
-		return g
+		p.tx.Rollback()
+		return
 	}
 
-	defer stmt.Close()
+	if err = p.tx.Commit(); err != nil {
+		return
+	}
+
+	p.publish(p.readSID, p.readHash, p.readGuide)
 
-	rows, err := stmt.Query()
+	return
+}
+
+// Groups returns existing groups in the playlist
+func (p *Playlist) Groups() ([]string, error) {
+
+	g := make([]string, 0)
+
+	rows, err := p.stmts.query(cmdSelectGroups, p.source())
 
 	if err != nil {
-		return g
+		return g, err
 	}
 
+	defer rows.Close()
+
 	for rows.Next() {
-		err := rows.Scan(&group)
 
-		if err == nil {
-			g = append(g, group)
+		var group string
+
+		if err = rows.Scan(&group); err != nil {
+			return make([]string, 0), err
 		}
 
+		g = append(g, group)
 	}
 
-	err = rows.Err()
-
-	if err != nil {
-		return make([]string, 0)
+	if err = rows.Err(); err != nil {
+		return make([]string, 0), err
 	}
 
-	return g
+	return g, nil
 }
 
 // GroupCount returns number of groups in the playlist
-func (p *Playlist) GroupCount() int {
-
-	var count int
+func (p *Playlist) GroupCount() (count int, err error) {
 
-	stmt, err := p.db.Prepare(cmdSelectGroupCount)
+	stmt, err := p.stmts.prepare(cmdSelectGroupCount)
 
 	if err != nil {
-		return 0
+		return
 	}
 
-	defer stmt.Close()
+	err = stmt.QueryRow(p.source()).Scan(&count)
 
-	err = stmt.QueryRow().Scan(&count)
+	return
+}
+
+// Channels returns names of channels for the specified group
+func (p *Playlist) Channels(group string) ([]*PlaylistItem, error) {
+
+	items := make([]*PlaylistItem, 0)
+
+	rows, err := p.stmts.query(cmdSelectChannels, p.source(), group)
 
 	if err != nil {
-		return 0
+		return items, err
 	}
 
-	return count
-}
+	defer rows.Close()
 
-// Channels returns names of channels for the specified group
-func (p *Playlist) Channels(group string) []*PlaylistItem {
+	for rows.Next() {
 
-	var (
-		id       string
-		url      string
-		channel  string
-		gchannel string
-	)
+		item := &PlaylistItem{}
 
-	items := make([]*PlaylistItem, 0)
+		if err = rows.Scan(&item.ID, &item.GroupTitle, &item.Name, &item.URL); err != nil {
+			return make([]*PlaylistItem, 0), err
+		}
 
-	stmt, err := p.db.Prepare(cmdSelectChannels)
+		items = append(items, item)
+	}
 
-	if err != nil {
-		return items
+	if err = rows.Err(); err != nil {
+		return make([]*PlaylistItem, 0), err
 	}
 
-	defer stmt.Close()
+	return items, nil
+}
+
+// ids returns the set of channel identifiers of the playlist
+func (p *Playlist) ids() (map[string]bool, error) {
 
-	rows, err := stmt.Query(group)
+	ids := make(map[string]bool)
+
+	rows, err := p.stmts.query(cmdSelectPlaylistIDs, p.source())
 
 	if err != nil {
-		return items
+		return ids, err
 	}
 
+	defer rows.Close()
+
 	for rows.Next() {
-		err := rows.Scan(&id, &gchannel, &channel, &url)
 
-		if err == nil {
-			item := &PlaylistItem{ID: id, GroupTitle: gchannel, Name: channel, URL: url}
-			items = append(items, item)
-		}
-	}
+		var id string
 
-	err = rows.Err()
+		if err = rows.Scan(&id); err != nil {
+			return ids, err
+		}
 
-	if err != nil {
-		return make([]*PlaylistItem, 0)
+		ids[id] = true
 	}
 
-	return items
+	return ids, rows.Err()
 }
 
 // Channel returns info about the specified channel
 func (p *Playlist) Channel(index int, group string) (*PlaylistItem, error) {
 
-	pi := p.Channels(group)
+	pi, err := p.Channels(group)
+
+	if err != nil {
+		return nil, err
+	}
 
 	if index >= 0 && index < len(pi) {
 		return pi[index], nil
 	}
 
-	return nil, fmt.Errorf("Index (%d) out of bounds", index)
+	return nil, fmt.Errorf("%w: channel #%d of the group %q", ErrNotFound, index, group)
 }
 
 func (p *Playlist) appendItem(item *PlaylistItem) (err error) {
@@ -215,35 +392,31 @@ func (p *Playlist) appendItem(item *PlaylistItem) (err error) {
 		return errors.New("Playlist.AppendItem: cannot append an empty item")
 	}
 
-	_, err = p.stmtInsertPlaylistItem.Exec(&item.ID, &item.GroupTitle, &item.Name, &item.URL)
+	_, err = p.stmtInsertPlaylistItem.Exec(p.readSID, &item.ID, &item.GroupTitle, &item.Name, &item.URL)
 
 	if err != nil {
 		return
 	}
 
+	p.readItems++
+
 	return nil
 }
 
 // Group returns group name with specified index
 func (p *Playlist) Group(index int) (string, error) {
 
-	g := p.Groups()
+	g, err := p.Groups()
 
-	if index >= 0 && index < len(g) {
-		return g[index], nil
+	if err != nil {
+		return "", err
 	}
 
-	return "", fmt.Errorf("Index (%d) out of bounds", index)
-}
-
-// PlaylistParser return the parser fo appropriate playlist format
-func PlaylistParser(data []byte) IPlaylistParser {
-
-	if isM3U(data) {
-		return &M3UPlaylistParser{}
+	if index >= 0 && index < len(g) {
+		return g[index], nil
 	}
 
-	return nil
+	return "", fmt.Errorf("%w: group #%d", ErrNotFound, index)
 }
 
 func contains(l []string, s string) bool {
@@ -18,27 +18,28 @@ package playlists
 
 import (
 	"database/sql"
-	"log"
-
-	// append sqlite3 support for database/sql
-	_ "github.com/mattn/go-sqlite3"
 )
 
 const (
-	cmdCreateTablePlaylist    = `CREATE TABLE playlist(id TEXT, channels_group TEXT, channel TEXT, source TEXT)`
-	cmdCreateIndexPlaylistCID = `CREATE INDEX ix_playlist_channel_id ON playlist(id)`
+	cmdCreateTableSources    = `CREATE TABLE IF NOT EXISTS sources(sid INTEGER PRIMARY KEY, kind TEXT, url TEXT, hash TEXT, filter TEXT, guide TEXT, loaded DATETIME)`
+	cmdCreateIndexSourcesURL = `CREATE UNIQUE INDEX IF NOT EXISTS ix_sources_url ON sources(kind, url)`
+
+	cmdCreateTablePlaylist    = `CREATE TABLE IF NOT EXISTS playlist(id TEXT, channels_group TEXT, channel TEXT, source TEXT)`
+	cmdCreateIndexPlaylistCID = `CREATE INDEX IF NOT EXISTS ix_playlist_channel_id ON playlist(id)`
+	cmdCreateIndexPlaylistSID = `CREATE INDEX IF NOT EXISTS ix_playlist_sid ON playlist(sid)`
 
-	cmdCreateTableChannels          = `CREATE TABLE channels(cid INTEGER, channel_id TEXT)`
-	cmdCreateIndexChannelsCID       = `CREATE INDEX ix_channels_cid ON channels(cid)`
-	cmdCreateIndexChannelsChannelID = `CREATE INDEX ix_channels_channel_id ON channels(channel_id)`
+	cmdCreateTableChannels          = `CREATE TABLE IF NOT EXISTS channels(cid INTEGER, channel_id TEXT)`
+	cmdCreateIndexChannelsCID       = `CREATE INDEX IF NOT EXISTS ix_channels_cid ON channels(cid)`
+	cmdCreateIndexChannelsChannelID = `CREATE INDEX IF NOT EXISTS ix_channels_channel_id ON channels(channel_id)`
+	cmdCreateIndexChannelsSID       = `CREATE INDEX IF NOT EXISTS ix_channels_sid ON channels(sid)`
 
-	cmdCreateTableChannelDisplayNames    = `CREATE TABLE channel_display_names(cid INTEGER, lang TEXT, display_name TEXT)`
-	cmdCreateIndexChannelDisplayNamesCID = `CREATE INDEX ix_channel_display_names_cid ON channel_display_names(cid)`
+	cmdCreateTableChannelDisplayNames    = `CREATE TABLE IF NOT EXISTS channel_display_names(cid INTEGER, lang TEXT, display_name TEXT)`
+	cmdCreateIndexChannelDisplayNamesCID = `CREATE INDEX IF NOT EXISTS ix_channel_display_names_cid ON channel_display_names(cid)`
 
-	cmdCreateChannelURLTable    = `CREATE TABLE channel_urls(cid INTEGER, url TEXT)`
-	cmdCreateIndexChannelURLCID = `CREATE INDEX ix_channel_urls_cid ON channel_urls(cid)`
+	cmdCreateChannelURLTable    = `CREATE TABLE IF NOT EXISTS channel_urls(cid INTEGER, url TEXT)`
+	cmdCreateIndexChannelURLCID = `CREATE INDEX IF NOT EXISTS ix_channel_urls_cid ON channel_urls(cid)`
 
-	cmdCreateTableProgramme = `CREATE TABLE programme (
+	cmdCreateTableProgramme = `CREATE TABLE IF NOT EXISTS programme (
 	pid INTEGER,
 	channel_id TEXT,
 	start DATETIME,
@@ -50,149 +51,159 @@ const (
 	clump_idx TEXT		
 	)`
 
-	cmdCreateIndexProgrammePID       = `CREATE INDEX ix_programme_pid ON programme(pid)`
-	cmdCreateIndexProgrammeChannelID = `CREATE INDEX ix_programme_channel_id ON programme(channel_id)`
+	cmdCreateIndexProgrammePID       = `CREATE INDEX IF NOT EXISTS ix_programme_pid ON programme(pid)`
+	cmdCreateIndexProgrammeChannelID = `CREATE INDEX IF NOT EXISTS ix_programme_channel_id ON programme(channel_id)`
+	cmdCreateIndexProgrammeSID       = `CREATE INDEX IF NOT EXISTS ix_programme_sid ON programme(sid)`
 
-	cmdCreateTableProgrammeTitles    = `CREATE TABLE programme_titles(pid INTEGER, lang TEXT, title TEXT)`
-	cmdCreateIndexProgrammeTitlesPID = `CREATE INDEX ix_programme_titles_pid ON programme_titles(pid)`
+	cmdCreateTableProgrammeTitles    = `CREATE TABLE IF NOT EXISTS programme_titles(pid INTEGER, lang TEXT, title TEXT)`
+	cmdCreateIndexProgrammeTitlesPID = `CREATE INDEX IF NOT EXISTS ix_programme_titles_pid ON programme_titles(pid)`
 
-	cmdCreateTableProgrammeSubTitle    = `CREATE TABLE programme_sub_titles(pid INTEGER, lang TEXT, sub_title TEXT)`
-	cmdCreateIndexProgrammeSubTitlePID = `CREATE INDEX ix_programme_sub_titles_pid ON programme_sub_titles(pid)`
+	cmdCreateTableProgrammeSubTitle    = `CREATE TABLE IF NOT EXISTS programme_sub_titles(pid INTEGER, lang TEXT, sub_title TEXT)`
+	cmdCreateIndexProgrammeSubTitlePID = `CREATE INDEX IF NOT EXISTS ix_programme_sub_titles_pid ON programme_sub_titles(pid)`
 
-	cmdCreateTableProgrammeDesc    = `CREATE TABLE programme_desc(pid INTEGER, lang TEXT, desc TEXT)`
-	cmdCreateIndexProgrammeDescPID = `CREATE INDEX ix_programme_desc_pid ON programme_desc(pid)`
+	cmdCreateTableProgrammeDesc    = `CREATE TABLE IF NOT EXISTS programme_desc(pid INTEGER, lang TEXT, desc TEXT)`
+	cmdCreateIndexProgrammeDescPID = `CREATE INDEX IF NOT EXISTS ix_programme_desc_pid ON programme_desc(pid)`
 
-	cmdCreateTableProgrammeDates    = `CREATE TABLE programme_dates(pid INTEGER, date TEXT)`
-	cmdCreateIndexProgrammeDatesPID = `CREATE INDEX ix_programme_dates_pid ON programme_dates(pid)`
+	cmdCreateTableProgrammeDates    = `CREATE TABLE IF NOT EXISTS programme_dates(pid INTEGER, date TEXT)`
+	cmdCreateIndexProgrammeDatesPID = `CREATE INDEX IF NOT EXISTS ix_programme_dates_pid ON programme_dates(pid)`
 
-	cmdCreateTableProgrammeCategories    = `CREATE TABLE programme_categories(pid INTEGER, lang TEXT, category TEXT)`
-	cmdCreateIndexProgrammeCategoriesPID = `CREATE INDEX ix_programme_categories_pid ON programme_categories(pid)`
+	cmdCreateTableProgrammeCategories    = `CREATE TABLE IF NOT EXISTS programme_categories(pid INTEGER, lang TEXT, category TEXT)`
+	cmdCreateIndexProgrammeCategoriesPID = `CREATE INDEX IF NOT EXISTS ix_programme_categories_pid ON programme_categories(pid)`
 
-	cmdCreateTableProgrammeKeywords    = `CREATE TABLE programme_keywords(pid INTEGER, lang TEXT, keyword TEXT)`
-	cmdCreateIndexProgrammeKeywordsPID = `CREATE INDEX ix_programme_keywords_pid ON programme_keywords(pid)`
+	cmdCreateTableProgrammeKeywords    = `CREATE TABLE IF NOT EXISTS programme_keywords(pid INTEGER, lang TEXT, keyword TEXT)`
+	cmdCreateIndexProgrammeKeywordsPID = `CREATE INDEX IF NOT EXISTS ix_programme_keywords_pid ON programme_keywords(pid)`
 
-	cmdCreateTableProgrammeLanguage    = `CREATE TABLE programme_languages(pid INTEGER, lang TEXT, language TEXT)`
-	cmdCreateIndexProgrammeLanguagePID = `CREATE INDEX ix_programme_languages_pid ON programme_languages(pid)`
+	cmdCreateTableProgrammeLanguage    = `CREATE TABLE IF NOT EXISTS programme_languages(pid INTEGER, lang TEXT, language TEXT)`
+	cmdCreateIndexProgrammeLanguagePID = `CREATE INDEX IF NOT EXISTS ix_programme_languages_pid ON programme_languages(pid)`
 
-	cmdCreateTableProgrammeOriginalLanguage    = `CREATE TABLE programme_original_languages(pid INTEGER, lang TEXT, language TEXT)`
-	cmdCreateIndexProgrammeOriginalLanguagePID = `CREATE INDEX ix_programme_original_languages_pid ON programme_original_languages(pid)`
+	cmdCreateTableProgrammeOriginalLanguage    = `CREATE TABLE IF NOT EXISTS programme_original_languages(pid INTEGER, lang TEXT, language TEXT)`
+	cmdCreateIndexProgrammeOriginalLanguagePID = `CREATE INDEX IF NOT EXISTS ix_programme_original_languages_pid ON programme_original_languages(pid)`
 
-	cmdCreateTableProgrammeCountries    = `CREATE TABLE programme_countries(pid INTEGER, lang TEXT, country TEXT)`
-	cmdCreateIndexProgrammeCountriesPID = `CREATE INDEX ix_programme_countries_pid ON programme_countries(pid)`
+	cmdCreateTableProgrammeCountries    = `CREATE TABLE IF NOT EXISTS programme_countries(pid INTEGER, lang TEXT, country TEXT)`
+	cmdCreateIndexProgrammeCountriesPID = `CREATE INDEX IF NOT EXISTS ix_programme_countries_pid ON programme_countries(pid)`
 
-	cmdCreateTableProgrammeDirectors    = `CREATE TABLE programme_directors(pid INTEGER, director TEXT)`
-	cmdCreateIndexProgrammeDirectorsPID = `CREATE INDEX ix_programme_directors_pid ON programme_directors(pid)`
+	cmdCreateTableProgrammeDirectors    = `CREATE TABLE IF NOT EXISTS programme_directors(pid INTEGER, director TEXT)`
+	cmdCreateIndexProgrammeDirectorsPID = `CREATE INDEX IF NOT EXISTS ix_programme_directors_pid ON programme_directors(pid)`
 
-	cmdCreateTableProgrammeWriters    = `CREATE TABLE programme_writers(pid INTEGER, writer TEXT)`
-	cmdCreateIndexProgrammeWritersPID = `CREATE INDEX ix_programme_writers_pid ON programme_writers(pid)`
+	cmdCreateTableProgrammeWriters    = `CREATE TABLE IF NOT EXISTS programme_writers(pid INTEGER, writer TEXT)`
+	cmdCreateIndexProgrammeWritersPID = `CREATE INDEX IF NOT EXISTS ix_programme_writers_pid ON programme_writers(pid)`
 
-	cmdCreateTableProgrammeAdapters    = `CREATE TABLE programme_adapters(pid INTEGER, adapter TEXT)`
-	cmdCreateIndexProgrammeAdaptersPID = `CREATE INDEX ix_programme_adapters_pid ON programme_adapters(pid)`
+	cmdCreateTableProgrammeAdapters    = `CREATE TABLE IF NOT EXISTS programme_adapters(pid INTEGER, adapter TEXT)`
+	cmdCreateIndexProgrammeAdaptersPID = `CREATE INDEX IF NOT EXISTS ix_programme_adapters_pid ON programme_adapters(pid)`
 
-	cmdCreateTableProgrammeProducers    = `CREATE TABLE programme_producers(pid INTEGER, producer TEXT)`
-	cmdCreateIndexProgrammeProducersPID = `CREATE INDEX ix_programme_producers_pid ON programme_producers(pid)`
+	cmdCreateTableProgrammeProducers    = `CREATE TABLE IF NOT EXISTS programme_producers(pid INTEGER, producer TEXT)`
+	cmdCreateIndexProgrammeProducersPID = `CREATE INDEX IF NOT EXISTS ix_programme_producers_pid ON programme_producers(pid)`
 
-	cmdCreateTableProgrammeComposers    = `CREATE TABLE programme_composers(pid INTEGER, composer TEXT)`
-	cmdCreateIndexProgrammeComposersPID = `CREATE INDEX ix_programme_composers_pid ON programme_composers(pid)`
+	cmdCreateTableProgrammeComposers    = `CREATE TABLE IF NOT EXISTS programme_composers(pid INTEGER, composer TEXT)`
+	cmdCreateIndexProgrammeComposersPID = `CREATE INDEX IF NOT EXISTS ix_programme_composers_pid ON programme_composers(pid)`
 
-	cmdCreateTableProgrammeEditors    = `CREATE TABLE programme_editors(pid INTEGER, editor TEXT)`
-	cmdCreateIndexProgrammeEditorsPID = `CREATE INDEX ix_programme_editors_pid ON programme_editors(pid)`
+	cmdCreateTableProgrammeEditors    = `CREATE TABLE IF NOT EXISTS programme_editors(pid INTEGER, editor TEXT)`
+	cmdCreateIndexProgrammeEditorsPID = `CREATE INDEX IF NOT EXISTS ix_programme_editors_pid ON programme_editors(pid)`
 
-	cmdCreateTableProgrammePresenters    = `CREATE TABLE programme_presenters(pid INTEGER, presenter TEXT)`
-	cmdCreateIndexProgrammePresentersPID = `CREATE INDEX ix_programme_presenters_pid ON programme_presenters(pid)`
+	cmdCreateTableProgrammePresenters    = `CREATE TABLE IF NOT EXISTS programme_presenters(pid INTEGER, presenter TEXT)`
+	cmdCreateIndexProgrammePresentersPID = `CREATE INDEX IF NOT EXISTS ix_programme_presenters_pid ON programme_presenters(pid)`
 
-	cmdCreateTableProgrammeCommentators    = `CREATE TABLE programme_commentators(pid INTEGER, commentator TEXT)`
-	cmdCreateIndexProgrammeCommentatorsPID = `CREATE INDEX ix_programme_commentators_pid ON programme_commentators(pid)`
+	cmdCreateTableProgrammeCommentators    = `CREATE TABLE IF NOT EXISTS programme_commentators(pid INTEGER, commentator TEXT)`
+	cmdCreateIndexProgrammeCommentatorsPID = `CREATE INDEX IF NOT EXISTS ix_programme_commentators_pid ON programme_commentators(pid)`
 
-	cmdCreateTableProgrammeGuests    = `CREATE TABLE programme_guests(pid INTEGER, guest TEXT)`
-	cmdCreateIndexProgrammeGuestsPID = `CREATE INDEX ix_programme_guests_pid ON programme_guests(pid)`
+	cmdCreateTableProgrammeGuests    = `CREATE TABLE IF NOT EXISTS programme_guests(pid INTEGER, guest TEXT)`
+	cmdCreateIndexProgrammeGuestsPID = `CREATE INDEX IF NOT EXISTS ix_programme_guests_pid ON programme_guests(pid)`
 
-	cmdCreateTableProgrammeActors    = `CREATE TABLE programme_actors(pid INTEGER, actor TEXT, role TEXT)`
-	cmdCreateIndexProgrammeActorsPID = `CREATE INDEX ix_programme_actors_pid ON programme_actors(pid)`
+	cmdCreateTableProgrammeActors    = `CREATE TABLE IF NOT EXISTS programme_actors(pid INTEGER, actor TEXT, role TEXT)`
+	cmdCreateIndexProgrammeActorsPID = `CREATE INDEX IF NOT EXISTS ix_programme_actors_pid ON programme_actors(pid)`
 
-	cmdCreateTableProgrammeLength    = `CREATE TABLE programme_length(pid INTEGER, value TEXT, units TEXT)`
-	cmdCreateIndexProgrammeLengthPID = `CREATE INDEX ix_programme_length_pid ON programme_length(pid)`
+	cmdCreateTableProgrammeLength    = `CREATE TABLE IF NOT EXISTS programme_length(pid INTEGER, value TEXT, units TEXT)`
+	cmdCreateIndexProgrammeLengthPID = `CREATE INDEX IF NOT EXISTS ix_programme_length_pid ON programme_length(pid)`
 
-	cmdCreateTableProgrammeIcon    = `CREATE TABLE programme_icon(pid INTEGER, src TEXT, width TEXT, height TEXT)`
-	cmdCreateIndexProgrammeIconPID = `CREATE INDEX ix_programme_icon_pid ON programme_icon(pid)`
+	cmdCreateTableProgrammeIcon    = `CREATE TABLE IF NOT EXISTS programme_icon(pid INTEGER, src TEXT, width TEXT, height TEXT)`
+	cmdCreateIndexProgrammeIconPID = `CREATE INDEX IF NOT EXISTS ix_programme_icon_pid ON programme_icon(pid)`
 
-	cmdCreateTableProgrammeEpisodeNum    = `CREATE TABLE programme_episode_num(pid INTEGER, system TEXT, episode_num TEXT)`
-	cmdCreateIndexProgrammeEpisodeNumPID = `CREATE INDEX ix_programme_episode_num_pid ON programme_episode_num(pid)`
+	cmdCreateTableProgrammeEpisodeNum    = `CREATE TABLE IF NOT EXISTS programme_episode_num(pid INTEGER, system TEXT, episode_num TEXT)`
+	cmdCreateIndexProgrammeEpisodeNumPID = `CREATE INDEX IF NOT EXISTS ix_programme_episode_num_pid ON programme_episode_num(pid)`
 
-	cmdCreateTableProgrammeVideo    = `CREATE TABLE programme_video(pid INTEGER, present TEXT, colour TEXT, aspect TEXT, quality TEXT)`
-	cmdCreateIndexProgrammeVideoPID = `CREATE INDEX ix_programme_video_pid ON programme_video(pid)`
+	cmdCreateTableProgrammeVideo    = `CREATE TABLE IF NOT EXISTS programme_video(pid INTEGER, present TEXT, colour TEXT, aspect TEXT, quality TEXT)`
+	cmdCreateIndexProgrammeVideoPID = `CREATE INDEX IF NOT EXISTS ix_programme_video_pid ON programme_video(pid)`
 
-	cmdCreateTableProgrammeAudio    = `CREATE TABLE programme_audio(pid INTEGER, present TEXT, stereo TEXT)`
-	cmdCreateIndexProgrammeAudioPID = `CREATE INDEX ix_programme_audio_pid ON programme_audio(pid)`
+	cmdCreateTableProgrammeAudio    = `CREATE TABLE IF NOT EXISTS programme_audio(pid INTEGER, present TEXT, stereo TEXT)`
+	cmdCreateIndexProgrammeAudioPID = `CREATE INDEX IF NOT EXISTS ix_programme_audio_pid ON programme_audio(pid)`
 
-	cmdCreateTableProgrammePreviouslyShown    = `CREATE TABLE programme_previously_shown(pid INTEGER, start TEXT, channel TEXT)`
-	cmdCreateIndexProgrammePreviouslyShownPID = `CREATE INDEX ix_programme_previously_shown_pid ON programme_previously_shown(pid)`
+	cmdCreateTableProgrammePreviouslyShown    = `CREATE TABLE IF NOT EXISTS programme_previously_shown(pid INTEGER, start TEXT, channel TEXT)`
+	cmdCreateIndexProgrammePreviouslyShownPID = `CREATE INDEX IF NOT EXISTS ix_programme_previously_shown_pid ON programme_previously_shown(pid)`
 
-	cmdCreateTableProgrammePremiere    = `CREATE TABLE programme_premiere(pid INTEGER, lang TEXT, premiere TEXT)`
-	cmdCreateIndexProgrammePremierePID = `CREATE INDEX ix_programme_premiere_pid ON programme_premiere(pid)`
+	cmdCreateTableProgrammePremiere    = `CREATE TABLE IF NOT EXISTS programme_premiere(pid INTEGER, lang TEXT, premiere TEXT)`
+	cmdCreateIndexProgrammePremierePID = `CREATE INDEX IF NOT EXISTS ix_programme_premiere_pid ON programme_premiere(pid)`
 
-	cmdCreateTableProgrammeLastChance    = `CREATE TABLE programme_last_chance(pid INTEGER, lang TEXT, last_chance TEXT)`
-	cmdCreateIndexProgrammeLastChancePID = `CREATE INDEX ix_programme_last_chance_pid ON programme_last_chance(pid)`
+	cmdCreateTableProgrammeLastChance    = `CREATE TABLE IF NOT EXISTS programme_last_chance(pid INTEGER, lang TEXT, last_chance TEXT)`
+	cmdCreateIndexProgrammeLastChancePID = `CREATE INDEX IF NOT EXISTS ix_programme_last_chance_pid ON programme_last_chance(pid)`
 
-	cmdCreateTableProgrammeSubtitles     = `CREATE TABLE programme_subtitles(pid INTEGER, type TEXT, lang TEXT, language TEXT)`
-	cmdCreateIndexProgrammeSubtitlesPID  = `CREATE INDEX ix_programme_subtitles_pid ON programme_subtitles(pid)`
-	cmdCreateIndexProgrammeSubtitlesType = `CREATE INDEX ix_programme_subtitles_type ON programme_subtitles(type, pid)`
+	cmdCreateTableProgrammeSubtitles     = `CREATE TABLE IF NOT EXISTS programme_subtitles(pid INTEGER, type TEXT, lang TEXT, language TEXT)`
+	cmdCreateIndexProgrammeSubtitlesPID  = `CREATE INDEX IF NOT EXISTS ix_programme_subtitles_pid ON programme_subtitles(pid)`
+	cmdCreateIndexProgrammeSubtitlesType = `CREATE INDEX IF NOT EXISTS ix_programme_subtitles_type ON programme_subtitles(type, pid)`
 
-	cmdCreateTableProgrammeRating       = `CREATE TABLE programme_rating(pid INTEGER, system TEXT, value TEXT, src TEXT, width TEXT, height TEXT)`
-	cmdCreateIndexProgrammeRatingPID    = `CREATE INDEX ix_programme_rating_pid ON programme_rating(pid)`
-	cmdCreateIndexProgrammeRatingSystem = `CREATE INDEX ix_programme_rating_system ON programme_rating(system, pid)`
+	cmdCreateTableProgrammeRating       = `CREATE TABLE IF NOT EXISTS programme_rating(pid INTEGER, system TEXT, value TEXT, src TEXT, width TEXT, height TEXT)`
+	cmdCreateIndexProgrammeRatingPID    = `CREATE INDEX IF NOT EXISTS ix_programme_rating_pid ON programme_rating(pid)`
+	cmdCreateIndexProgrammeRatingSystem = `CREATE INDEX IF NOT EXISTS ix_programme_rating_system ON programme_rating(system, pid)`
 
-	cmdCreateTableProgrammeStarRating       = `CREATE TABLE programme_star_rating(pid INTEGER, system TEXT, value TEXT, src TEXT, width TEXT, height TEXT)`
-	cmdCreateIndexProgrammeStarRatingPID    = `CREATE INDEX ix_programme_star_rating_pid ON programme_star_rating(pid)`
-	cmdCreateIndexProgrammeStarRatingSystem = `CREATE INDEX ix_programme_star_rating_system ON programme_star_rating(system, pid)`
+	cmdCreateTableProgrammeStarRating       = `CREATE TABLE IF NOT EXISTS programme_star_rating(pid INTEGER, system TEXT, value TEXT, src TEXT, width TEXT, height TEXT)`
+	cmdCreateIndexProgrammeStarRatingPID    = `CREATE INDEX IF NOT EXISTS ix_programme_star_rating_pid ON programme_star_rating(pid)`
+	cmdCreateIndexProgrammeStarRatingSystem = `CREATE INDEX IF NOT EXISTS ix_programme_star_rating_system ON programme_star_rating(system, pid)`
 
-	cmdCreateTableProgrammeReview    = `CREATE TABLE programme_review(pid INTEGER, type TEXT, source TEXT, reviewer TEXT, lang TEXT, value TEXT)`
-	cmdCreateIndexProgrammeReviewPID = `CREATE INDEX ix_programme_review_pid ON programme_review(pid)`
+	cmdCreateTableProgrammeReview    = `CREATE TABLE IF NOT EXISTS programme_review(pid INTEGER, type TEXT, source TEXT, reviewer TEXT, lang TEXT, value TEXT)`
+	cmdCreateIndexProgrammeReviewPID = `CREATE INDEX IF NOT EXISTS ix_programme_review_pid ON programme_review(pid)`
 
-	cmdCreateTableProgrammeLangStat = `CREATE TABLE programme_lang_stat(lang TEXT, lang_count INTEGER)`
+	cmdCreateTableProgrammeLangStat = `CREATE TABLE IF NOT EXISTS programme_lang_stat(lang TEXT, lang_count INTEGER)`
 
 	cmdAnalyze            = `ANALYZE`
-	cmdPatchProgrammeStop = `UPDATE programme SET stop = NULL WHERE CAST(strftime('%Y', stop) AS INTEGER) < ?`
+	cmdPatchProgrammeStop = `UPDATE programme SET stop = NULL WHERE (sid = ?) AND (CAST(strftime('%Y', stop) AS INTEGER) < ?)`
 )
 
 const (
 	cmdSelectGroups = `SELECT pl.channels_group FROM playlist AS pl
+	WHERE pl.sid = ?
 	GROUP BY pl.channels_group
 	ORDER BY rowid
 	`
 
 	cmdSelectGroupCount = `SELECT COUNT(*) AS cc FROM (
 		SELECT pl.channels_group FROM playlist AS pl
+		WHERE pl.sid = ?
 		GROUP BY pl.channels_group
 	) AS items
 	`
 
 	cmdSelectChannels = `SELECT pl.id, pl.channels_group, pl.channel, pl.source
 	FROM playlist AS pl 
-	WHERE pl.channels_group = ?
+	WHERE (pl.sid = ?) AND (pl.channels_group = ?)
 	ORDER BY rowid
 	`
 
+	cmdSelectPlaylistIDs = `SELECT DISTINCT pl.id FROM playlist AS pl WHERE pl.sid = ?`
+
 	cmdSelectProgrammeDescription = `SELECT p.pid, datetime(p.start, 'localtime') AS start
-		, datetime(p.stop, 'localtime') AS stop, ifnull(pt.title, '') AS title
-   		, ifnull(pd."desc", '') AS [desc], ifnull(ps.sub_title, '') AS sub_title
+		, datetime(p.stop, 'localtime') AS stop
+		, ifnull((SELECT pt.title FROM programme_titles AS pt WHERE pt.pid = p.pid
+			ORDER BY lang_rank(?1, pt.lang) LIMIT 1), '') AS title
+		, ifnull((SELECT pd."desc" FROM programme_desc AS pd WHERE pd.pid = p.pid
+			ORDER BY lang_rank(?1, pd.lang) LIMIT 1), '') AS [desc]
+		, ifnull((SELECT ps.sub_title FROM programme_sub_titles AS ps WHERE ps.pid = p.pid
+			ORDER BY lang_rank(?1, ps.lang) LIMIT 1), '') AS sub_title
 	FROM programme AS p
-    	LEFT JOIN programme_titles AS pt ON (pt.pid = p.pid) AND (pt.lang = ?)
-		LEFT JOIN programme_desc AS pd ON (pd.pid = p.pid) AND (pd.lang = ?)
-		LEFT JOIN programme_sub_titles AS ps ON (ps.pid = p.pid) and (ps.lang = ?)
-	WHERE (p.pid = ?)
+	WHERE (p.pid = ?2)
 	LIMIT 1
 	`
 
 	cmdSelectProgrammeCategories = `SELECT ifnull(pc.category, '') AS category
 	FROM programme_categories AS pc 
-	WHERE (pc.pid = ?) AND (pc.lang = ?)
+	WHERE (pc.pid = ?1) AND (ifnull(pc.lang, '') = (SELECT ifnull(l.lang, '') FROM programme_categories AS l
+		WHERE l.pid = ?1 ORDER BY lang_rank(?2, l.lang) LIMIT 1))
 	GROUP BY ifnull(pc.category, '')
 	`
 
 	cmdSelectProgrammeCountries = `SELECT ifnull(pc.country, '') AS country
 	FROM programme_countries AS pc
-	WHERE (pc.pid = ?) AND (pc.lang = ?) AND (ifnull(pc.country, '') <> '')
+	WHERE (pc.pid = ?1) AND (ifnull(pc.lang, '') = (SELECT ifnull(l.lang, '') FROM programme_countries AS l
+		WHERE (l.pid = ?1) AND (ifnull(l.country, '') <> '') ORDER BY lang_rank(?2, l.lang) LIMIT 1))
+		AND (ifnull(pc.country, '') <> '')
 	GROUP BY ifnull(pc.country, '')
 	`
 
@@ -214,187 +225,203 @@ const (
 )
 
 const (
-	cmdInsertPlaylistItem = `INSERT INTO playlist (id, channels_group, channel, source) VALUES(?, ?, ?, ?)`
-
-	cmdAppendChannelDisplayName = `INSERT INTO channel_display_names(cid, lang, display_name) VALUES(?, ?, ?)`
-
-	cmdAppendChannelURL     = `INSERT INTO channel_urls(cid, url) VALUES(?, ?)`
-	cmdAppendGuideChannel   = `INSERT INTO channels(channel_id) VALUES(?)`
-	cmdUpdateGuideChannelID = `UPDATE channels SET cid = ? WHERE rowid = ?`
-
-	cmdAppendGuideProgramme = `INSERT INTO programme(channel_id, start, stop, pdc_start,
-	vps_start, show_view, video_plus, clump_idx) VALUES(?, ?, ?, ?, ?, ?, ?, ?)`
-	cmdUpdateGuideProgrammePID = `UPDATE programme SET pid = ? where rowid = ?`
-
-	cmdAppendProgrammeTitle            = `INSERT INTO programme_titles(pid, lang, title) VALUES(?, ?, ?)`
-	cmdAppendProgrammeSubTitle         = `INSERT INTO programme_sub_titles(pid, lang, sub_title) VALUES(?, ?, ?)`
-	cmdAppendProgrammeDesc             = `INSERT INTO programme_desc(pid, lang, desc) VALUES(?, ?, ?)`
-	cmdAppendProgrammeDates            = `INSERT INTO programme_dates(pid, date) VALUES(?, ?)`
-	cmdAppendProgrammeCategories       = `INSERT INTO programme_categories(pid, lang, category) VALUES(?, ?, ?)`
-	cmdAppendProgrammeKeywords         = `INSERT INTO programme_keywords(pid, lang, keyword) VALUES(?, ?, ?)`
-	cmdAppendProgrammeLanguage         = `INSERT INTO programme_languages(pid, lang, language) VALUES(?, ?, ?)`
-	cmdAppendProgrammeOriginalLanguage = `INSERT INTO programme_original_languages(pid, lang, language) VALUES(?, ?, ?)`
-	cmdAppendProgrammeCountries        = `INSERT INTO programme_countries(pid, lang, country) VALUES(?, ?, ?)`
-	cmdAppendProgrammeDirectors        = `INSERT INTO programme_directors(pid, director) VALUES(?, ?)`
-	cmdAppendProgrammeWriters          = `INSERT INTO programme_writers(pid, writer) VALUES(?, ?)`
-	cmdAppendProgrammeAdapters         = `INSERT INTO programme_adapters(pid, adapter) VALUES(?, ?)`
-	cmdAppendProgrammeProducers        = `INSERT INTO programme_producers(pid, producer) VALUES(?, ?)`
-	cmdAppendProgrammeComposers        = `INSERT INTO programme_composers(pid, composer) VALUES(?, ?)`
-	cmdAppendProgrammeEditors          = `INSERT INTO programme_editors(pid, editor) VALUES(?, ?)`
-	cmdAppendProgrammePresenters       = `INSERT INTO programme_presenters(pid, presenter) VALUES(?, ?)`
-	cmdAppendProgrammeCommentators     = `INSERT INTO programme_commentators(pid, commentator) VALUES(?, ?)`
-	cmdAppendProgrammeGuests           = `INSERT INTO programme_guests(pid, guest) VALUES(?, ?)`
-	cmdAppendProgrammeActors           = `INSERT INTO programme_actors(pid, actor, role) VALUES(?, ?, ?)`
-	cmdAppendProgrammeLength           = `INSERT INTO programme_length(pid, value, units) VALUES(?, ?, ?)`
-	cmdAppendProgrammeIcon             = `INSERT INTO programme_icon(pid, src, width, height) VALUES(?, ?, ?, ?)`
-	cmdAppendProgrammeEpisodeNum       = `INSERT INTO programme_episode_num(pid, system, episode_num) VALUES(?, ?, ?)`
-	cmdAppendProgrammeVideo            = `INSERT INTO programme_video(pid, present, colour, aspect, quality) VALUES(?, ?, ?, ?, ?)`
-	cmdAppendProgrammeAudio            = `INSERT INTO programme_audio(pid, present, stereo) VALUES(?, ?, ?)`
-	cmdAppendProgrammePreviouslyShown  = `INSERT INTO programme_previously_shown(pid, start, channel) VALUES(?, ?, ?)`
-	cmdAppendProgrammePremiere         = `INSERT INTO programme_premiere(pid, lang, premiere) VALUES(?, ?, ?)`
-	cmdAppendProgrammeLastChance       = `INSERT INTO programme_last_chance(pid, lang, last_chance) VALUES(?, ?, ?)`
-	cmdAppendProgrammeSubtitles        = `INSERT INTO programme_subtitles(pid, type, lang, language) VALUES(?, ?, ?, ?)`
-	cmdAppendProgrammeRating           = `INSERT INTO programme_rating(pid, system, value, src, width, height) VALUES(?, ?, ?, ?, ?, ?)`
-	cmdAppendProgrammeStarRating       = `INSERT INTO programme_star_rating(pid, system, value, src, width, height) VALUES(?, ?, ?, ?, ?, ?)`
-	cmdAppendProgrammeReview           = `INSERT INTO programme_review(pid, type, source, reviewer, lang, value) VALUES(?, ?, ?, ?, ?, ?)`
-
-	cmdAppendProgrammeLangStat = `INSERT INTO programme_lang_stat(lang, lang_count)
-    SELECT l.lang, SUM(l.lang_count) AS lang_count FROM
+	cmdInsertPlaylistItem = `INSERT INTO playlist (sid, id, channels_group, channel, source) VALUES(?, ?, ?, ?, ?)`
+
+	cmdSelectMaxChannelID    = `SELECT ifnull(MAX(cid), 0) FROM channels`
+	cmdSelectMaxProgrammeID  = `SELECT ifnull(MAX(pid), 0) FROM programme`
+	cmdSelectProgrammeExists = `SELECT EXISTS(SELECT 1 FROM programme)`
+
+	cmdSelectGuideIndexes = `SELECT name FROM sqlite_master
+	WHERE (type = 'index') AND (tbl_name NOT IN ('sources', 'playlist')) AND (sql IS NOT NULL)`
+
+	cmdAppendProgrammeLangStat = `WITH sp AS (SELECT pid FROM programme WHERE sid = ?1)
+    INSERT INTO programme_lang_stat(sid, lang, lang_count)
+    SELECT ?1, l.lang, SUM(l.lang_count) AS lang_count FROM
     (
         SELECT pt.lang, count(pt.lang) AS lang_count FROM programme_titles as pt
+        WHERE pt.pid IN (SELECT pid FROM sp)
         GROUP BY pt.lang
 
         UNION
 
         SELECT pst.lang, count(pst.lang) AS lang_count FROM programme_sub_titles as pst
+        WHERE pst.pid IN (SELECT pid FROM sp)
         GROUP BY pst.lang
 
         UNION
 
         SELECT pd.lang, count(pd.lang) AS lang_count FROM programme_desc as pd
+        WHERE pd.pid IN (SELECT pid FROM sp)
         GROUP BY pd.lang
 
         UNION
 
         SELECT pc.lang, count(pc.lang) AS lang_count FROM programme_categories as pc
+        WHERE pc.pid IN (SELECT pid FROM sp)
         GROUP BY pc.lang
 
         UNION
 
         SELECT pk.lang, count(pk.lang) AS lang_count FROM programme_keywords as pk
+        WHERE pk.pid IN (SELECT pid FROM sp)
         GROUP BY pk.lang
 
         UNION
 
         SELECT pl.lang, count(pl.lang) AS lang_count FROM programme_languages as pl
+        WHERE pl.pid IN (SELECT pid FROM sp)
         GROUP BY pl.lang
 
         UNION
 
         SELECT pol.lang, count(pol.lang) AS lang_count FROM programme_original_languages as pol
+        WHERE pol.pid IN (SELECT pid FROM sp)
         GROUP BY pol.lang
 
         UNION
 
         SELECT pc.lang, count(pc.lang) AS lang_count FROM programme_countries as pc
+        WHERE pc.pid IN (SELECT pid FROM sp)
         GROUP BY pc.lang
 
         UNION
 
         SELECT pp.lang, count(pp.lang) AS lang_count FROM programme_premiere as pp
+        WHERE pp.pid IN (SELECT pid FROM sp)
         GROUP BY pp.lang
 
         UNION
 
         SELECT plc.lang, count(plc.lang) AS lang_count FROM programme_last_chance as plc
+        WHERE plc.pid IN (SELECT pid FROM sp)
         GROUP BY plc.lang
 
         UNION
 
         SELECT ps.lang, count(ps.lang) AS lang_count FROM programme_subtitles as ps
+        WHERE ps.pid IN (SELECT pid FROM sp)
         GROUP BY ps.lang
 
         UNION
 
         SELECT pr.lang, count(pr.lang) AS lang_count FROM programme_review as pr
+        WHERE pr.pid IN (SELECT pid FROM sp)
         GROUP BY pr.lang
     ) AS l
 	GROUP BY l.lang`
+
+	cmdDeleteProgrammeLangStat = `DELETE FROM programme_lang_stat WHERE sid = ?`
 )
 
 type pdb struct {
 }
 
-var db *sql.DB
-var dbname = getPlaylistDatabaseName()
-
-func init() {
+// guideIndexes are created after the bulk load of the tv guide
+var guideIndexes = [42]string{cmdCreateIndexChannelsCID, cmdCreateIndexChannelsChannelID, cmdCreateIndexChannelsSID,
+	cmdCreateIndexChannelDisplayNamesCID, cmdCreateIndexChannelURLCID,
+	cmdCreateIndexProgrammePID, cmdCreateIndexProgrammeChannelID, cmdCreateIndexProgrammeSID,
+	cmdCreateIndexProgrammeTitlesPID, cmdCreateIndexProgrammeSubTitlePID, cmdCreateIndexProgrammeDescPID,
+	cmdCreateIndexProgrammeDatesPID, cmdCreateIndexProgrammeCategoriesPID, cmdCreateIndexProgrammeKeywordsPID,
+	cmdCreateIndexProgrammeLanguagePID, cmdCreateIndexProgrammeOriginalLanguagePID, cmdCreateIndexProgrammeCountriesPID,
+	cmdCreateIndexProgrammeDirectorsPID, cmdCreateIndexProgrammeWritersPID, cmdCreateIndexProgrammeAdaptersPID,
+	cmdCreateIndexProgrammeProducersPID, cmdCreateIndexProgrammeComposersPID, cmdCreateIndexProgrammeEditorsPID,
+	cmdCreateIndexProgrammePresentersPID, cmdCreateIndexProgrammeCommentatorsPID, cmdCreateIndexProgrammeGuestsPID,
+	cmdCreateIndexProgrammeActorsPID, cmdCreateIndexProgrammeLengthPID, cmdCreateIndexProgrammeIconPID,
+	cmdCreateIndexProgrammeEpisodeNumPID, cmdCreateIndexProgrammeVideoPID, cmdCreateIndexProgrammeAudioPID,
+	cmdCreateIndexProgrammePreviouslyShownPID, cmdCreateIndexProgrammePremierePID, cmdCreateIndexProgrammeLastChancePID,
+	cmdCreateIndexProgrammeSubtitlesPID, cmdCreateIndexProgrammeSubtitlesType,
+	cmdCreateIndexProgrammeRatingPID, cmdCreateIndexProgrammeRatingSystem,
+	cmdCreateIndexProgrammeStarRatingPID, cmdCreateIndexProgrammeStarRatingSystem,
+	cmdCreateIndexProgrammeReviewPID}
+
+// guideTables contains columns of the tv guide tables filled while reading
+var guideTables = map[string][]string{
+	"channels":                     {"cid", "sid", "channel_id"},
+	"channel_display_names":        {"cid", "lang", "display_name"},
+	"channel_urls":                 {"cid", "url"},
+	"programme":                    {"pid", "sid", "channel_id", "start", "stop", "pdc_start", "vps_start", "show_view", "video_plus", "clump_idx"},
+	"programme_titles":             {"pid", "lang", "title"},
+	"programme_sub_titles":         {"pid", "lang", "sub_title"},
+	"programme_desc":               {"pid", "lang", "desc"},
+	"programme_dates":              {"pid", "date"},
+	"programme_categories":         {"pid", "lang", "category"},
+	"programme_keywords":           {"pid", "lang", "keyword"},
+	"programme_languages":          {"pid", "lang", "language"},
+	"programme_original_languages": {"pid", "lang", "language"},
+	"programme_countries":          {"pid", "lang", "country"},
+	"programme_directors":          {"pid", "director"},
+	"programme_writers":            {"pid", "writer"},
+	"programme_adapters":           {"pid", "adapter"},
+	"programme_producers":          {"pid", "producer"},
+	"programme_composers":          {"pid", "composer"},
+	"programme_editors":            {"pid", "editor"},
+	"programme_presenters":         {"pid", "presenter"},
+	"programme_commentators":       {"pid", "commentator"},
+	"programme_guests":             {"pid", "guest"},
+	"programme_actors":             {"pid", "actor", "role"},
+	"programme_length":             {"pid", "value", "units"},
+	"programme_icon":               {"pid", "src", "width", "height"},
+	"programme_episode_num":        {"pid", "system", "episode_num"},
+	"programme_video":              {"pid", "present", "colour", "aspect", "quality"},
+	"programme_audio":              {"pid", "present", "stereo"},
+	"programme_previously_shown":   {"pid", "start", "channel"},
+	"programme_premiere":           {"pid", "lang", "premiere"},
+	"programme_last_chance":        {"pid", "lang", "last_chance"},
+	"programme_subtitles":          {"pid", "type", "lang", "language"},
+	"programme_rating":             {"pid", "system", "value", "src", "width", "height"},
+	"programme_star_rating":        {"pid", "system", "value", "src", "width", "height"},
+	"programme_review":             {"pid", "type", "source", "reviewer", "lang", "value"},
+}
 
-	var err error
+// dropGuideIndexes drops indexes of the tv guide tables before the bulk load
+func dropGuideIndexes(tx *sql.Tx) error {
 
-	db, err = sql.Open("sqlite3", dbname)
+	rows, err := tx.Query(cmdSelectGuideIndexes)
 
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
-	err = createDatabaseStructure(db)
+	names := make([]string, 0)
 
-	if err != nil {
-		log.Fatal(err)
+	for rows.Next() {
+
+		var name string
+
+		if err = rows.Scan(&name); err != nil {
+
+			rows.Close()
+			return err
+		}
+
+		names = append(names, name)
+	}
+
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if _, err = tx.Exec(`DROP INDEX IF EXISTS ` + name); err != nil {
+			return err
+		}
 	}
+
+	return nil
 }
 
-func createDatabaseStructure(db *sql.DB) (err error) {
-
-	objects := [78]string{cmdCreateTablePlaylist, cmdCreateIndexPlaylistCID,
-		cmdCreateTableChannels, cmdCreateIndexChannelsCID, cmdCreateIndexChannelsChannelID,
-		cmdCreateTableChannelDisplayNames, cmdCreateIndexChannelDisplayNamesCID,
-		cmdCreateChannelURLTable, cmdCreateIndexChannelURLCID,
-		cmdCreateTableProgramme, cmdCreateIndexProgrammePID, cmdCreateIndexProgrammeChannelID,
-		cmdCreateTableProgrammeTitles, cmdCreateIndexProgrammeTitlesPID,
-		cmdCreateTableProgrammeSubTitle, cmdCreateIndexProgrammeSubTitlePID,
-		cmdCreateTableProgrammeDesc, cmdCreateIndexProgrammeDescPID,
-		cmdCreateTableProgrammeDates, cmdCreateIndexProgrammeDatesPID,
-		cmdCreateTableProgrammeCategories, cmdCreateIndexProgrammeCategoriesPID,
-		cmdCreateTableProgrammeKeywords, cmdCreateIndexProgrammeKeywordsPID,
-		cmdCreateTableProgrammeLanguage, cmdCreateIndexProgrammeLanguagePID,
-		cmdCreateTableProgrammeOriginalLanguage, cmdCreateIndexProgrammeOriginalLanguagePID,
-		cmdCreateTableProgrammeCountries, cmdCreateIndexProgrammeCountriesPID,
-		cmdCreateTableProgrammeDirectors, cmdCreateIndexProgrammeDirectorsPID,
-		cmdCreateTableProgrammeWriters, cmdCreateIndexProgrammeWritersPID,
-		cmdCreateTableProgrammeAdapters, cmdCreateIndexProgrammeAdaptersPID,
-		cmdCreateTableProgrammeProducers, cmdCreateIndexProgrammeProducersPID,
-		cmdCreateTableProgrammeComposers, cmdCreateIndexProgrammeComposersPID,
-		cmdCreateTableProgrammeEditors, cmdCreateIndexProgrammeEditorsPID,
-		cmdCreateTableProgrammePresenters, cmdCreateIndexProgrammePresentersPID,
-		cmdCreateTableProgrammeCommentators, cmdCreateIndexProgrammeCommentatorsPID,
-		cmdCreateTableProgrammeGuests, cmdCreateIndexProgrammeGuestsPID,
-		cmdCreateTableProgrammeActors, cmdCreateIndexProgrammeActorsPID,
-		cmdCreateTableProgrammeLength, cmdCreateIndexProgrammeLengthPID,
-		cmdCreateTableProgrammeIcon, cmdCreateIndexProgrammeIconPID,
-		cmdCreateTableProgrammeEpisodeNum, cmdCreateIndexProgrammeEpisodeNumPID,
-		cmdCreateTableProgrammeVideo, cmdCreateIndexProgrammeVideoPID,
-		cmdCreateTableProgrammeAudio, cmdCreateIndexProgrammeAudioPID,
-		cmdCreateTableProgrammePreviouslyShown, cmdCreateIndexProgrammePreviouslyShownPID,
-		cmdCreateTableProgrammePremiere, cmdCreateIndexProgrammePremierePID,
-		cmdCreateTableProgrammeLastChance, cmdCreateIndexProgrammeLastChancePID,
-		cmdCreateTableProgrammeSubtitles, cmdCreateIndexProgrammeSubtitlesPID, cmdCreateIndexProgrammeSubtitlesType,
-		cmdCreateTableProgrammeRating, cmdCreateIndexProgrammeRatingPID, cmdCreateIndexProgrammeRatingSystem,
-		cmdCreateTableProgrammeStarRating, cmdCreateIndexProgrammeStarRatingPID, cmdCreateIndexProgrammeStarRatingSystem,
-		cmdCreateTableProgrammeReview, cmdCreateIndexProgrammeReviewPID,
-		cmdCreateTableProgrammeLangStat}
-
-	for _, dbobj := range objects {
-
-		_, err = db.Exec(dbobj)
-
-		if err != nil {
-			return
+// createGuideIndexes creates indexes of the tv guide tables after the bulk load
+func createGuideIndexes(tx *sql.Tx) error {
+
+	for _, command := range guideIndexes {
+		if _, err := tx.Exec(command); err != nil {
+			return err
 		}
 	}
 
-	return
+	return nil
 }
 
 func (p *pdb) analyze(db *sql.DB, tx *sql.Tx) (err error) {
@@ -19,7 +19,6 @@
 package playlists
 
 import (
-	"os"
 	"path"
 
 	osext "github.com/kardianos/osext"
@@ -27,14 +26,6 @@ import (
 
 func getPlaylistDatabaseName() string {
 
-	var filename string
-
 	dir, _ := osext.ExecutableFolder()
-	filename = path.Join(dir, "playlist.db3")
-
-	if _, err := os.Stat(filename); err != os.ErrNotExist {
-		os.Remove(filename)
-	}
-
-	return filename
+	return path.Join(dir, "playlist.db3")
 }
@@ -20,10 +20,10 @@ import "time"
 
 // Programme contains info about tv programme
 type Programme struct {
-	PID   int
-	Start time.Time
-	Stop  time.Time
-	Title string
+	PID   int       `json:"pid"`
+	Start time.Time `json:"start"`
+	Stop  time.Time `json:"stop"`
+	Title string    `json:"title"`
 }
 
 // StartHour returns the hour of the TV program start
@@ -25,26 +25,26 @@ import (
 
 // ProgrammeRating - programme rating
 type ProgrammeRating struct {
-	System string
-	Rating string
+	System string `json:"system"`
+	Rating string `json:"rating"`
 }
 
 // ProgrammeActor - actor
 type ProgrammeActor struct {
-	Actor string
-	Role  string
+	Actor string `json:"actor"`
+	Role  string `json:"role"`
 }
 
 // ProgrammeDescription - description of the programme
 type ProgrammeDescription struct {
 	Programme
-	SubTitle    string
-	Description string
-	Category    []*string
-	Country     []*string
-	Directors   []*string
-	Actors      []*ProgrammeActor
-	Rating      []*ProgrammeRating
+	SubTitle    string             `json:"sub_title"`
+	Description string             `json:"description"`
+	Category    []*string          `json:"category"`
+	Country     []*string          `json:"country"`
+	Directors   []*string          `json:"directors"`
+	Actors      []*ProgrammeActor  `json:"actors"`
+	Rating      []*ProgrammeRating `json:"rating"`
 }
 
 // ProgrammeTimeDescription return text description of the programme start and stop times and duration
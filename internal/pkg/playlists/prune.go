@@ -0,0 +1,199 @@
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package playlists
+
+import (
+	"database/sql"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// the source is abandoned when it has not been loaded since the cutoff, its playlist, channels and
+	// programmes are dropped with it. The content read without the source (sid 0) has no source to abandon
+	cmdSelectPrunedSources = `SELECT sid FROM sources WHERE datetime(loaded) < ?1`
+
+	// the programme has expired when it ended before the cutoff, the programme without stop time
+	// when it started before the cutoff
+	cmdSelectPrunedProgrammes = `SELECT pid FROM programme
+	WHERE (datetime(ifnull(stop, start)) < ?1) OR (sid IN (` + cmdSelectPrunedSources + `))`
+	cmdSelectPrunedChannels = `SELECT cid FROM channels WHERE sid IN (` + cmdSelectPrunedSources + `)`
+
+	cmdDeletePrunedSearch    = `DELETE FROM programme_search WHERE pid NOT IN (SELECT pid FROM programme)`
+	cmdDeletePrunedLangStat  = `DELETE FROM programme_lang_stat WHERE sid IN (` + cmdSelectPrunedSources + `)`
+	cmdDeletePrunedPlaylists = `DELETE FROM playlist WHERE sid IN (` + cmdSelectPrunedSources + `)`
+	cmdDeletePrunedSources   = `DELETE FROM sources WHERE datetime(loaded) < ?1`
+	cmdSelectGuideSources    = `SELECT sid FROM sources WHERE kind = ?`
+
+	cmdVacuum = `VACUUM`
+)
+
+// PruneReport contains the result of the database pruning
+type PruneReport struct {
+	// Programmes - the number of the deleted programmes
+	Programmes int64 `json:"programmes"`
+	// Channels - the number of the deleted channels
+	Channels int64 `json:"channels"`
+}
+
+// prune deletes the programmes expired before the cutoff and the sources not loaded since the cutoff
+// with all their details in the single transaction
+func prune(db *sql.DB, cutoff time.Time, vacuum bool) (report *PruneReport, err error) {
+
+	report = &PruneReport{}
+	at := searchTime(cutoff)
+
+	tx, err := db.Begin()
+
+	if err != nil {
+		return
+	}
+
+	defer func() {
+
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+
+		if err = tx.Commit(); err == nil && vacuum {
+			_, err = db.Exec(cmdVacuum)
+		}
+	}()
+
+	tables := make([]string, 0, len(guideTables))
+
+	for table := range guideTables {
+		tables = append(tables, table)
+	}
+
+	sort.Strings(tables)
+
+	// details first, then the channels and programmes themselves
+	for _, table := range tables {
+
+		switch {
+		case table == "channels" || table == "programme":
+			continue
+		case strings.HasPrefix(table, "channel_"):
+			_, err = tx.Exec(`DELETE FROM `+table+` WHERE cid IN (`+cmdSelectPrunedChannels+`)`, at)
+		default:
+			_, err = tx.Exec(`DELETE FROM `+table+` WHERE pid IN (`+cmdSelectPrunedProgrammes+`)`, at)
+		}
+
+		if err != nil {
+			return
+		}
+	}
+
+	res, err := tx.Exec(`DELETE FROM channels WHERE cid IN (`+cmdSelectPrunedChannels+`)`, at)
+
+	if err != nil {
+		return
+	}
+
+	if report.Channels, err = res.RowsAffected(); err != nil {
+		return
+	}
+
+	if res, err = tx.Exec(`DELETE FROM programme WHERE pid IN (`+cmdSelectPrunedProgrammes+`)`, at); err != nil {
+		return
+	}
+
+	if report.Programmes, err = res.RowsAffected(); err != nil {
+		return
+	}
+
+	if err = pruneSearchIndex(tx); err != nil {
+		return
+	}
+
+	if err = pruneLangStat(tx, at); err != nil {
+		return
+	}
+
+	for _, command := range [2]string{cmdDeletePrunedPlaylists, cmdDeletePrunedSources} {
+		if _, err = tx.Exec(command, at); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// pruneSearchIndex deletes the pruned programmes from the search index if the index exists
+func pruneSearchIndex(tx *sql.Tx) (err error) {
+
+	var count int
+
+	if err = tx.QueryRow(cmdSelectProgrammeSearchExists).Scan(&count); err != nil || count == 0 {
+		return
+	}
+
+	_, err = tx.Exec(cmdDeletePrunedSearch)
+
+	return
+}
+
+// pruneLangStat counts the languages of the remaining programmes of the tv guide sources and
+// of the guide read without the source again
+func pruneLangStat(tx *sql.Tx, at string) (err error) {
+
+	if _, err = tx.Exec(cmdDeletePrunedLangStat, at); err != nil {
+		return
+	}
+
+	rows, err := tx.Query(cmdSelectGuideSources, sourceGuide)
+
+	if err != nil {
+		return
+	}
+
+	sids := []int64{0}
+
+	for rows.Next() {
+
+		var sid int64
+
+		if err = rows.Scan(&sid); err != nil {
+			rows.Close()
+			return
+		}
+
+		sids = append(sids, sid)
+	}
+
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	for _, sid := range sids {
+
+		if _, err = tx.Exec(cmdDeleteProgrammeLangStat, sid); err != nil {
+			return
+		}
+
+		if _, err = tx.Exec(cmdAppendProgrammeLangStat, sid); err != nil {
+			return
+		}
+	}
+
+	return
+}
@@ -0,0 +1,115 @@
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package playlists
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	xmltv "go-tvguide/pkg/xmltv"
+)
+
+func TestPrune(t *testing.T) {
+
+	g := newTestGuide(t)
+	now := time.Now()
+
+	at := func(days, hours int) string {
+		return now.AddDate(0, 0, days).Add(time.Duration(hours) * time.Hour).UTC().Format("20060102150405 -0700")
+	}
+
+	data := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<tv>
+<channel id="1"><display-name lang="en">News</display-name></channel>
+<programme start="%s" stop="%s" channel="1"><title lang="en">Old</title><desc lang="en">Old news</desc></programme>
+<programme start="%s" channel="1"><title lang="en">Open</title></programme>
+<programme start="%s" stop="%s" channel="1"><title lang="en">Recent</title></programme>
+<programme start="%s" stop="%s" channel="1"><title lang="en">Tomorrow</title></programme>
+</tv>`, at(-10, 0), at(-10, 1), at(-9, 0), at(-2, 0), at(-2, 1), at(1, 0), at(1, 1))
+
+	if _, err := g.Load("guide.xml", []byte(data), &xmltv.XMLTVParser{}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// the guide read without the source is kept until its programmes expire
+	data = fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<tv>
+<channel id="2"><display-name lang="en">Sport</display-name></channel>
+<programme start="%s" stop="%s" channel="2"><title lang="en">Match</title></programme>
+<programme start="%s" stop="%s" channel="2"><title lang="en">Live</title></programme>
+</tv>`, at(-8, 0), at(-8, 1), at(0, 1), at(0, 2))
+
+	if _, err := g.Read([]byte(data), &xmltv.XMLTVParser{}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// the guide and the playlist of the sources not loaded for a month
+	for _, command := range []string{
+		`INSERT INTO sources(sid, kind, url, loaded) VALUES(100, 'guide', 'old.xml', datetime('now', '-30 days'))`,
+		`INSERT INTO sources(sid, kind, url, loaded) VALUES(101, 'playlist', 'old.m3u', datetime('now', '-30 days'))`,
+		`INSERT INTO channels(cid, sid, channel_id) VALUES(1000, 100, 'legacy')`,
+		`INSERT INTO channel_display_names(cid, lang, display_name) VALUES(1000, 'en', 'Legacy')`,
+		`INSERT INTO programme(pid, sid, channel_id, start) VALUES(1000, 100, 'legacy', datetime('now', '+1 day'))`,
+		`INSERT INTO programme_titles(pid, lang, title) VALUES(1000, 'en', 'Legacy')`,
+		`INSERT INTO programme_lang_stat(sid, lang, lang_count) VALUES(100, 'en', 1)`,
+		`INSERT INTO playlist(sid, id, channels_group, channel, source) VALUES(101, 'Legacy', 'News', 'Legacy', 'http://example.com')`,
+	} {
+		if _, err := g.db.Exec(command); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	report, err := prune(g.db, now.AddDate(0, 0, -7), true)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Programmes != 4 || report.Channels != 1 {
+		t.Errorf("prune() = %+v, want 4 programmes and 1 channel", report)
+	}
+
+	var tests = []struct {
+		query string
+		count int
+	}{
+		{`SELECT COUNT(*) FROM programme`, 3},
+		{`SELECT COUNT(*) FROM programme WHERE sid = 0`, 1},
+		{`SELECT COUNT(*) FROM programme_titles`, 3},
+		{`SELECT COUNT(*) FROM programme_desc`, 0},
+		{`SELECT COUNT(*) FROM channels`, 2},
+		{`SELECT COUNT(*) FROM channels WHERE sid = 0`, 1},
+		{`SELECT COUNT(*) FROM channel_display_names`, 2},
+		{`SELECT ifnull(SUM(lang_count), 0) FROM programme_lang_stat`, 3},
+		{`SELECT COUNT(*) FROM sources`, 1},
+		{`SELECT COUNT(*) FROM playlist`, 0},
+	}
+
+	for i, test := range tests {
+
+		var count int
+
+		if err = g.db.QueryRow(test.query).Scan(&count); err != nil {
+			t.Fatalf("#%d: %v", i, err)
+		}
+
+		if count != test.count {
+			t.Errorf("#%d: %s = %d, want %d", i, test.query, count, test.count)
+		}
+	}
+}
@@ -0,0 +1,354 @@
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package playlists
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	xmltv "go-tvguide/pkg/xmltv"
+)
+
+const (
+	cmdSelectRepairProgrammes = `SELECT p.pid, p.channel_id, datetime(p.start) AS start, datetime(p.stop) AS stop
+	FROM programme AS p
+	WHERE p.sid = ?
+	ORDER BY p.channel_id, p.start, p.pid`
+
+	cmdUpdateProgrammeTimes = `UPDATE programme SET start = ?, stop = ? WHERE pid = ?`
+)
+
+// OverlapPolicy - the repair of the overlapping programmes of the channel
+type OverlapPolicy int
+
+const (
+	// OverlapKeep - the overlapping programmes are kept as is
+	OverlapKeep OverlapPolicy = iota
+	// OverlapTrim - the previous programme stops when the next one starts
+	OverlapTrim
+	// OverlapSplit - both programmes are cut in the middle of the overlap
+	OverlapSplit
+)
+
+var overlapPolicies = [...]string{"keep", "trim", "split"}
+
+// ParseOverlapPolicy returns the overlap policy by its name: keep, trim or split
+func ParseOverlapPolicy(s string) (OverlapPolicy, error) {
+
+	for i, name := range overlapPolicies {
+		if strings.EqualFold(s, name) {
+			return OverlapPolicy(i), nil
+		}
+	}
+
+	return OverlapKeep, fmt.Errorf("%w: unknown overlap policy %q, expected one of %s", ErrInvalidArgument, s,
+		strings.Join(overlapPolicies[:], ", "))
+}
+
+func (p OverlapPolicy) String() string {
+
+	if p >= 0 && int(p) < len(overlapPolicies) {
+		return overlapPolicies[p]
+	}
+
+	return fmt.Sprintf("OverlapPolicy(%d)", int(p))
+}
+
+// RepairPolicy selects the fixes of the programmes applied after the tv guide is read.
+// The zero policy changes nothing
+type RepairPolicy struct {
+	// FillStop - the missing stop time is filled from the start of the next programme
+	FillStop bool
+	// Overlaps - the repair of the overlapping programmes
+	Overlaps OverlapPolicy
+	// DropDuplicates - only the first of the programmes of the channel with the same start is kept
+	DropDuplicates bool
+	// MaxDuration - the longer programmes are cut to this duration, zero means no limit
+	MaxDuration time.Duration
+}
+
+// kinds of the repairs
+const (
+	RepairStop      = "stop"
+	RepairOverlap   = "overlap"
+	RepairDuplicate = "duplicate"
+	RepairDuration  = "duration"
+)
+
+// Repair describes the fix of the programme
+type Repair struct {
+	Kind    string
+	Channel string
+	PID     int64
+	Start   time.Time
+	Detail  string
+}
+
+func (r *Repair) String() string {
+	return fmt.Sprintf("%s: channel %q, programme at %s: %s", r.Kind, r.Channel,
+		r.Start.Local().Format("2006-01-02 15:04"), r.Detail)
+}
+
+// signature describes the policy to tell whether the stored guide has been repaired with the same policy
+func (p *RepairPolicy) signature() string {
+
+	if p == nil || *p == (RepairPolicy{}) {
+		return ""
+	}
+
+	return fmt.Sprintf(";repair=%t,%s,%t,%v", p.FillStop, p.Overlaps, p.DropDuplicates, p.MaxDuration)
+}
+
+// SetRepairPolicy sets the fixes of the programmes applied while reading the guide
+func (g *Guide) SetRepairPolicy(p *RepairPolicy) {
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.repair = p
+}
+
+// repairPolicy returns the repair policy of the guide
+func (g *Guide) repairPolicy() *RepairPolicy {
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return g.repair
+}
+
+func (r *ImportReport) appendRepair(repair *Repair) {
+
+	r.Repaired++
+
+	if len(r.Repairs) < maxReportErrors {
+		r.Repairs = append(r.Repairs, repair)
+	}
+}
+
+// repairProgramme - the programme of the channel being repaired
+type repairProgramme struct {
+	pid         int64
+	start, stop time.Time
+	changed     bool
+}
+
+// repairProgrammes applies the repair policy to the programmes of the current guide source
+func (g *Guide) repairProgrammes(report *ImportReport) (err error) {
+
+	policy := g.repairPolicy()
+
+	if policy == nil || *policy == (RepairPolicy{}) {
+		return
+	}
+
+	rows, err := g.tx.Query(cmdSelectRepairProgrammes, g.readSID)
+
+	if err != nil {
+		return
+	}
+
+	var (
+		channel    string
+		programmes []*repairProgramme
+		repairs    []func() error
+	)
+
+	flush := func() {
+
+		if len(programmes) > 0 {
+			repairs = append(repairs, g.repairChannel(report, channel, programmes)...)
+		}
+	}
+
+	for rows.Next() {
+
+		var (
+			p      repairProgramme
+			ch     string
+			sstart string
+			sstop  sql.NullString
+		)
+
+		if err = rows.Scan(&p.pid, &ch, &sstart, &sstop); err != nil {
+			rows.Close()
+			return
+		}
+
+		if p.start, err = xmltv.TimeOfProgramme(sstart); err != nil {
+			rows.Close()
+			return
+		}
+
+		if sstop.Valid {
+			if p.stop, err = xmltv.TimeOfProgramme(sstop.String); err != nil {
+				rows.Close()
+				return
+			}
+		}
+
+		if ch != channel {
+			flush()
+			channel, programmes = ch, nil
+		}
+
+		programmes = append(programmes, &p)
+	}
+
+	flush()
+
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	// the changes are written after the programmes are read
+	for _, repair := range repairs {
+		if err = repair(); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// repairChannel fixes the programmes of the channel ordered by the start time and returns
+// the functions writing the changes to the database
+func (g *Guide) repairChannel(report *ImportReport, channel string, programmes []*repairProgramme) []func() error {
+
+	policy := g.repairPolicy()
+	writes := make([]func() error, 0)
+
+	record := func(kind string, p *repairProgramme, format string, args ...interface{}) {
+		report.appendRepair(&Repair{Kind: kind, Channel: channel, PID: p.pid, Start: p.start,
+			Detail: fmt.Sprintf(format, args...)})
+	}
+
+	if policy.DropDuplicates {
+
+		kept := make([]*repairProgramme, 0, len(programmes))
+
+		for _, p := range programmes {
+
+			if n := len(kept); n > 0 && kept[n-1].start.Equal(p.start) {
+
+				record(RepairDuplicate, p, "dropped the duplicate of the programme %d", kept[n-1].pid)
+
+				pid := p.pid
+				writes = append(writes, func() error { return deleteProgramme(g.tx, pid) })
+
+				continue
+			}
+
+			kept = append(kept, p)
+		}
+
+		programmes = kept
+	}
+
+	for i, p := range programmes {
+
+		if i+1 == len(programmes) {
+			break
+		}
+
+		next := programmes[i+1]
+
+		if policy.FillStop && p.stop.IsZero() && next.start.After(p.start) {
+
+			p.stop, p.changed = next.start, true
+			record(RepairStop, p, "the stop time is set to the start of the next programme")
+		}
+
+		if p.stop.IsZero() || !p.stop.After(next.start) || !next.start.After(p.start) {
+			continue
+		}
+
+		switch policy.Overlaps {
+		case OverlapTrim:
+
+			record(RepairOverlap, p, "trimmed by %v to the start of the next programme", p.stop.Sub(next.start))
+			p.stop, p.changed = next.start, true
+
+		case OverlapSplit:
+
+			middle := next.start.Add(p.stop.Sub(next.start) / 2)
+
+			if next.stop.IsZero() || next.stop.After(middle) {
+
+				record(RepairOverlap, p, "the overlap of %v is split with the next programme", p.stop.Sub(next.start))
+				p.stop, next.start, p.changed, next.changed = middle, middle, true, true
+			} else {
+
+				record(RepairOverlap, p, "trimmed by %v to the start of the next programme", p.stop.Sub(next.start))
+				p.stop, p.changed = next.start, true
+			}
+		}
+	}
+
+	for _, p := range programmes {
+
+		if policy.MaxDuration > 0 && !p.stop.IsZero() && p.stop.Sub(p.start) > policy.MaxDuration {
+
+			record(RepairDuration, p, "the duration of %v is cut to %v", p.stop.Sub(p.start), policy.MaxDuration)
+			p.stop, p.changed = p.start.Add(policy.MaxDuration), true
+		}
+
+		if p.changed {
+
+			var stop interface{}
+
+			if !p.stop.IsZero() {
+				stop = p.stop
+			}
+
+			pid, start := p.pid, p.start
+			writes = append(writes, func() error {
+				_, err := g.tx.Exec(cmdUpdateProgrammeTimes, start, stop, pid)
+				return err
+			})
+		}
+	}
+
+	return writes
+}
+
+// deleteProgramme deletes the programme and its details
+func deleteProgramme(tx *sql.Tx, pid int64) (err error) {
+
+	tables := make([]string, 0, len(guideTables))
+
+	for table := range guideTables {
+		if table != "channels" && table != "programme" && !strings.HasPrefix(table, "channel_") {
+			tables = append(tables, table)
+		}
+	}
+
+	sort.Strings(tables)
+
+	for _, table := range append(tables, "programme") {
+		if _, err = tx.Exec(`DELETE FROM `+table+` WHERE pid = ?`, pid); err != nil {
+			return
+		}
+	}
+
+	return
+}
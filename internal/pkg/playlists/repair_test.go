@@ -0,0 +1,103 @@
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package playlists
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	xmltv "go-tvguide/pkg/xmltv"
+)
+
+const testRepairGuide = `<?xml version="1.0" encoding="UTF-8"?>
+<tv>
+<channel id="1"><display-name lang="en">News</display-name></channel>
+<programme start="20181027180000 +0000" channel="1"><title lang="en">News</title></programme>
+<programme start="20181027190000 +0000" stop="20181027203000 +0000" channel="1"><title lang="en">Film</title></programme>
+<programme start="20181027190000 +0000" stop="20181027203000 +0000" channel="1"><title lang="en">Film</title></programme>
+<programme start="20181027200000 +0000" stop="20181028200000 +0000" channel="1"><title lang="en">Marathon</title></programme>
+</tv>`
+
+func TestGuideRepair(t *testing.T) {
+
+	// the stop times of the past years are patched on reading
+	year := time.Now().Year() + 1
+	data := strings.Replace(testRepairGuide, "2018", fmt.Sprint(year), -1)
+
+	var tests = []struct {
+		policy     *RepairPolicy
+		programmes string
+		repairs    string
+	}{
+		{nil, "18:00-|19:00-20:30|19:00-20:30|20:00-20:00", ""},
+		{&RepairPolicy{FillStop: true}, "18:00-19:00|19:00-20:30|19:00-20:30|20:00-20:00", "stop"},
+		{&RepairPolicy{DropDuplicates: true, Overlaps: OverlapTrim}, "18:00-|19:00-20:00|20:00-20:00",
+			"duplicate|overlap"},
+		{&RepairPolicy{DropDuplicates: true, Overlaps: OverlapSplit, MaxDuration: 6 * time.Hour},
+			"18:00-|19:00-20:15|20:15-02:15", "duplicate|overlap|duration"},
+	}
+
+	for i, test := range tests {
+
+		g := newTestGuide(t)
+		g.SetRepairPolicy(test.policy)
+
+		report, err := g.Read([]byte(data), &xmltv.XMLTVParser{}, nil)
+
+		if err != nil {
+			t.Fatalf("#%d: Read() = %v", i, err)
+		}
+
+		rows, err := g.db.Query(`SELECT strftime('%H:%M', start), ifnull(strftime('%H:%M', stop), '')
+			FROM programme ORDER BY start, pid`)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		times := make([]string, 0)
+
+		for rows.Next() {
+
+			var start, stop string
+
+			if err = rows.Scan(&start, &stop); err != nil {
+				t.Fatal(err)
+			}
+
+			times = append(times, start+"-"+stop)
+		}
+
+		rows.Close()
+
+		kinds := make([]string, 0)
+
+		for _, r := range report.Repairs {
+			kinds = append(kinds, r.Kind)
+		}
+
+		if strings.Join(times, "|") != test.programmes {
+			t.Errorf("#%d: programmes = %q, want %q", i, strings.Join(times, "|"), test.programmes)
+		}
+
+		if strings.Join(kinds, "|") != test.repairs || report.Repaired != len(kinds) {
+			t.Errorf("#%d: repairs = %q (%d), want %q", i, kinds, report.Repaired, test.repairs)
+		}
+	}
+}
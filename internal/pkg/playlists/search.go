@@ -0,0 +1,261 @@
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package playlists
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// The search index is the FTS5 table rebuilt for the guide source on every reading like the other
+// indexes of the guide. It contains a row per programme and language of its texts. The table is
+// created by the migration of the schema if sqlite supports FTS5, otherwise by the first opening
+// of the database with FTS5
+
+const (
+	cmdCreateProgrammeSearch = `CREATE VIRTUAL TABLE IF NOT EXISTS programme_search USING fts5(
+		title, sub_title, description, credits, keywords,
+		pid UNINDEXED, sid UNINDEXED, lang UNINDEXED,
+		tokenize = 'unicode61 remove_diacritics 2')`
+
+	cmdSelectProgrammeSearchExists = `SELECT COUNT(*) FROM sqlite_master WHERE (type = 'table') AND (name = 'programme_search')`
+
+	cmdDeleteProgrammeSearch = `DELETE FROM programme_search WHERE sid = ?`
+
+	cmdIndexProgrammeSearch = `INSERT INTO programme_search(pid, sid, lang, title, sub_title, description, credits, keywords)
+	SELECT p.pid, p.sid, l.lang
+		, (SELECT group_concat(pt.title, ' ') FROM programme_titles AS pt WHERE (pt.pid = p.pid) AND (pt.lang = l.lang))
+		, (SELECT group_concat(pst.sub_title, ' ') FROM programme_sub_titles AS pst WHERE (pst.pid = p.pid) AND (pst.lang = l.lang))
+		, (SELECT group_concat(pd."desc", ' ') FROM programme_desc AS pd WHERE (pd.pid = p.pid) AND (pd.lang = l.lang))
+		, (SELECT group_concat(c.name, ' ') FROM (
+			SELECT actor AS name FROM programme_actors WHERE pid = p.pid
+			UNION ALL SELECT director FROM programme_directors WHERE pid = p.pid
+			UNION ALL SELECT writer FROM programme_writers WHERE pid = p.pid
+			UNION ALL SELECT adapter FROM programme_adapters WHERE pid = p.pid
+			UNION ALL SELECT producer FROM programme_producers WHERE pid = p.pid
+			UNION ALL SELECT composer FROM programme_composers WHERE pid = p.pid
+			UNION ALL SELECT editor FROM programme_editors WHERE pid = p.pid
+			UNION ALL SELECT presenter FROM programme_presenters WHERE pid = p.pid
+			UNION ALL SELECT commentator FROM programme_commentators WHERE pid = p.pid
+			UNION ALL SELECT guest FROM programme_guests WHERE pid = p.pid) AS c)
+		, (SELECT group_concat(pk.keyword, ' ') FROM programme_keywords AS pk WHERE (pk.pid = p.pid) AND (pk.lang = l.lang))
+	FROM programme AS p
+		INNER JOIN (
+			SELECT pid, lang FROM programme_titles
+			UNION SELECT pid, lang FROM programme_sub_titles
+			UNION SELECT pid, lang FROM programme_desc
+		) AS l ON (l.pid = p.pid)`
+
+	cmdAppendProgrammeSearch = cmdIndexProgrammeSearch + ` WHERE p.sid = ?`
+
+	// the index has a row per language of the programme, the programme is returned once with
+	// the columns of its best ranked row. LIMIT -1 keeps the ranking subquery from being flattened
+	// into the grouping, the FTS5 functions cannot be used in the aggregate query
+	cmdSearchProgrammes = `SELECT pid, start, stop, title, channel_id, channel, snippet, min(rank) FROM (
+		SELECT p.pid, datetime(p.start, 'localtime') AS start
+			, datetime(p.stop, 'localtime') AS stop
+			, coalesce(ps.title, (SELECT pt.title FROM programme_titles AS pt WHERE pt.pid = p.pid
+				ORDER BY lang_rank(?, pt.lang) LIMIT 1), '') AS title
+			, p.channel_id, ifnull((SELECT cdn.display_name FROM channels AS c
+				INNER JOIN channel_display_names AS cdn ON (cdn.cid = c.cid)
+				WHERE (c.channel_id = p.channel_id) AND (c.sid = p.sid)
+				ORDER BY lang_rank(?, cdn.lang) LIMIT 1), '') AS channel
+			, snippet(programme_search, -1, ?, ?, '...', 16) AS snippet
+			, bm25(programme_search, 10.0, 5.0, 1.0, 3.0, 3.0) AS rank
+			, p.start AS sort_start
+		FROM programme_search AS ps
+			INNER JOIN programme AS p ON (p.pid = ps.pid)
+		WHERE (programme_search MATCH ?) AND (ps.sid = ?) AND ((? = '') OR (ps.lang = ?))
+			AND ((? = '') OR (ifnull(datetime(p.stop), datetime(p.start)) > ?))
+			AND ((? = '') OR (datetime(p.start) < ?))
+		LIMIT -1)
+	GROUP BY pid
+	ORDER BY min(rank), sort_start
+	LIMIT ?`
+)
+
+const (
+	// SnippetOpen and SnippetClose surround the matched words in the snippets of the search results
+	SnippetOpen  = "["
+	SnippetClose = "]"
+
+	// maxSearchResults limits the number of the search results
+	maxSearchResults = 100
+)
+
+// ErrSearchUnavailable - sqlite library is built without FTS5 extension
+var ErrSearchUnavailable = errors.New("Guide.Search: full-text search is not supported by the database")
+
+// SearchResult contains the programme found by the search
+type SearchResult struct {
+	Programme
+	ChannelID   string `json:"channel_id"`
+	ChannelName string `json:"channel_name"`
+	Snippet     string `json:"snippet"`
+}
+
+// createSearchIndex creates the search index skipped by the migration of the schema if sqlite
+// supports FTS5 now and indexes the stored guides
+func createSearchIndex(db *sql.DB) (err error) {
+
+	var count int
+
+	if err = db.QueryRow(cmdSelectProgrammeSearchExists).Scan(&count); err != nil || count > 0 {
+		return
+	}
+
+	tx, err := db.Begin()
+
+	if err != nil {
+		return
+	}
+
+	defer func() {
+
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+
+		err = tx.Commit()
+	}()
+
+	if _, err = tx.Exec(cmdCreateProgrammeSearch); err != nil {
+
+		if isMissingModule(err, "fts5") {
+			err = nil
+		}
+
+		return
+	}
+
+	_, err = tx.Exec(cmdIndexProgrammeSearch)
+
+	return
+}
+
+// updateSearchIndex rebuilds the search index of the current guide source. The index
+// is skipped if the database has no search table
+func (g *Guide) updateSearchIndex() (err error) {
+
+	var count int
+
+	if err = g.tx.QueryRow(cmdSelectProgrammeSearchExists).Scan(&count); err != nil || count == 0 {
+		return
+	}
+
+	if _, err = g.tx.Exec(cmdDeleteProgrammeSearch, g.readSID); err != nil {
+		return
+	}
+
+	_, err = g.tx.Exec(cmdAppendProgrammeSearch, g.readSID)
+
+	return
+}
+
+// Search returns the programmes matching all words of the query ranked by relevance. Titles weigh
+// more than sub-titles, credits, keywords and descriptions. The empty lang means any language,
+// zero from and to mean no time limits. The titles in the matched language are preferred, otherwise
+// the titles are resolved through the languages of the guide
+func (g *Guide) Search(query, lang string, from, to time.Time) ([]*SearchResult, error) {
+
+	results := make([]*SearchResult, 0)
+
+	match := searchQuery(query)
+
+	if match == "" {
+		return results, nil
+	}
+
+	var count int
+
+	if err := g.db.QueryRow(cmdSelectProgrammeSearchExists).Scan(&count); err != nil {
+		return results, err
+	}
+
+	if count == 0 {
+		return results, ErrSearchUnavailable
+	}
+
+	languages, err := g.Languages()
+
+	if err != nil {
+		return results, err
+	}
+
+	sfrom, sto, langs := searchTime(from), searchTime(to), languages.list()
+
+	rows, err := g.stmts.query(cmdSearchProgrammes, langs, langs, SnippetOpen, SnippetClose, match, g.source(), lang, lang,
+		sfrom, sfrom, sto, sto, maxSearchResults)
+
+	if err != nil {
+		return results, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+
+		var (
+			r      SearchResult
+			sstart string
+			sstop  sql.NullString
+			rank   float64
+		)
+
+		if err = rows.Scan(&r.PID, &sstart, &sstop, &r.Title, &r.ChannelID, &r.ChannelName, &r.Snippet, &rank); err != nil {
+			return make([]*SearchResult, 0), err
+		}
+
+		if r.Start, r.Stop, err = programmeTimes(sstart, sstop); err != nil {
+			return make([]*SearchResult, 0), err
+		}
+
+		results = append(results, &r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return make([]*SearchResult, 0), err
+	}
+
+	return results, nil
+}
+
+// searchQuery converts the words of the query to FTS5 strings, so the special characters
+// of the query syntax are matched literally
+func searchQuery(query string) string {
+
+	words := strings.Fields(query)
+
+	for i, word := range words {
+		words[i] = `"` + strings.Replace(word, `"`, `""`, -1) + `"`
+	}
+
+	return strings.Join(words, " ")
+}
+
+// searchTime formats the time as it is compared with the times of the programmes
+func searchTime(t time.Time) string {
+
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
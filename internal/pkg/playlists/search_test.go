@@ -0,0 +1,127 @@
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package playlists
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	xmltv "go-tvguide/pkg/xmltv"
+)
+
+const testSearchGuide = `<?xml version="1.0" encoding="UTF-8"?>
+<tv>
+<channel id="1"><display-name lang="en">Sport</display-name></channel>
+<channel id="2"><display-name lang="en">News</display-name></channel>
+<programme start="20181027180000 +0000" stop="20181027200000 +0000" channel="2">
+<title lang="en">Evening news</title><desc lang="en">Results of the Champions League matches</desc></programme>
+<programme start="20181027190000 +0000" stop="20181027210000 +0000" channel="1">
+<title lang="en">Football. Champions League</title><desc lang="en">Real Madrid - Barcelona</desc>
+<credits><commentator>José Mourinho</commentator></credits></programme>
+<programme start="20181028190000 +0000" stop="20181028210000 +0000" channel="1">
+<title lang="ru">Футбол. Лига чемпионов</title><keyword lang="ru">спорт</keyword></programme>
+<programme start="20181029120000 +0000" stop="20181029140000 +0000" channel="1">
+<title lang="en">Monza Grand Prix</title><title lang="de">Großer Preis von Monza</title></programme>
+</tv>`
+
+func TestGuideSearch(t *testing.T) {
+
+	g := newTestGuide(t)
+
+	if _, err := g.Read([]byte(testSearchGuide), &xmltv.XMLTVParser{}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	day := time.Date(2018, 10, 27, 0, 0, 0, 0, time.UTC)
+
+	var tests = []struct {
+		query    string
+		lang     string
+		from, to time.Time
+		titles   []string
+	}{
+		{"champions league", "", time.Time{}, time.Time{}, []string{"Football. Champions League", "Evening news"}},
+		{"champions league", "en", day, day.Add(19 * time.Hour), []string{"Evening news"}},
+		{"mourinho", "", time.Time{}, time.Time{}, []string{"Football. Champions League"}},
+		{"jose", "", time.Time{}, time.Time{}, []string{"Football. Champions League"}},
+		{"ЛИГА", "", day.AddDate(0, 0, 1), time.Time{}, []string{"Футбол. Лига чемпионов"}},
+		{"спорт", "en", time.Time{}, time.Time{}, []string{}},
+		{`league" OR "news`, "", time.Time{}, time.Time{}, []string{}},
+		{"  ", "", time.Time{}, time.Time{}, []string{}},
+		{"monza", "de", time.Time{}, time.Time{}, []string{"Großer Preis von Monza"}},
+	}
+
+	for _, test := range tests {
+
+		results, err := g.Search(test.query, test.lang, test.from, test.to)
+
+		if err != nil {
+			t.Errorf("Search(%q) = %v", test.query, err)
+			continue
+		}
+
+		titles := make([]string, 0)
+
+		for _, r := range results {
+			titles = append(titles, r.Title)
+		}
+
+		if strings.Join(titles, "|") != strings.Join(test.titles, "|") {
+			t.Errorf("Search(%q) = %q, want %q", test.query, titles, test.titles)
+		}
+	}
+
+	// the programme matched by the titles in both languages is found once
+	if results, err := g.Search("monza", "", time.Time{}, time.Time{}); err != nil || len(results) != 1 {
+		t.Errorf("Search(monza) = %d results, %v, want 1", len(results), err)
+	}
+
+	results, err := g.Search("barcelona", "", time.Time{}, time.Time{})
+
+	if err != nil || len(results) != 1 {
+		t.Fatalf("Search() = %v, %v", results, err)
+	}
+
+	if r := results[0]; r.ChannelID != "1" || r.ChannelName != "Sport" || r.Snippet != "Real Madrid - [Barcelona]" {
+		t.Errorf("Search() = %q %q %q", r.ChannelID, r.ChannelName, r.Snippet)
+	}
+}
+
+func TestCreateSearchIndex(t *testing.T) {
+
+	g := newTestGuide(t)
+
+	if _, err := g.Read([]byte(testSearchGuide), &xmltv.XMLTVParser{}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// the table skipped by the migration without FTS5 is created and filled by the next opening
+	if _, err := g.db.Exec(`DROP TABLE programme_search`); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := createSearchIndex(g.db); err != nil {
+			t.Fatalf("createSearchIndex() = %v", err)
+		}
+	}
+
+	if results, err := g.Search("mourinho", "", time.Time{}, time.Time{}); err != nil || len(results) != 1 {
+		t.Errorf("Search() after createSearchIndex() = %d results, %v", len(results), err)
+	}
+}
@@ -0,0 +1,135 @@
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package playlists
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"time"
+)
+
+// kinds of the sources
+const (
+	sourcePlaylist = "playlist"
+	sourceGuide    = "guide"
+)
+
+const (
+	cmdSelectSource = `SELECT sid, ifnull(hash, ''), ifnull(filter, ''), ifnull(guide, '') FROM sources
+	WHERE (kind = ?) AND (url = ?)`
+	cmdInsertSource = `INSERT INTO sources(kind, url, hash, filter, guide, loaded) VALUES(?, ?, ?, ?, ?, ?)`
+	cmdUpdateSource = `UPDATE sources SET hash = ?, filter = ?, guide = ?, loaded = ? WHERE sid = ?`
+
+	cmdDeletePlaylistSource = `DELETE FROM playlist WHERE sid = ?`
+)
+
+// source describes the loaded playlist or tv guide. The source is identified by its kind and URL,
+// the hash of its content and the filter signature tell whether it has to be read again
+type source struct {
+	id     int64
+	kind   string
+	url    string
+	hash   string
+	filter string
+	guide  string
+}
+
+// querier is implemented by both sql.DB and sql.Tx
+type querier interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+func hashOf(data []byte) string {
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// findSource returns the source with the specified kind and URL or a new unsaved source
+func findSource(q querier, kind, url string) (s *source, err error) {
+
+	s = &source{kind: kind, url: url}
+
+	err = q.QueryRow(cmdSelectSource, kind, url).Scan(&s.id, &s.hash, &s.filter, &s.guide)
+
+	if err == sql.ErrNoRows {
+		err = nil
+	}
+
+	return
+}
+
+// saveSource inserts the new source or updates the existing one
+func saveSource(tx *sql.Tx, s *source) (err error) {
+
+	if s.id != 0 {
+		_, err = tx.Exec(cmdUpdateSource, s.hash, s.filter, s.guide, time.Now(), s.id)
+		return
+	}
+
+	res, err := tx.Exec(cmdInsertSource, s.kind, s.url, s.hash, s.filter, s.guide, time.Now())
+
+	if err != nil {
+		return
+	}
+
+	s.id, err = res.LastInsertId()
+
+	return
+}
+
+// deleteGuideSource deletes channels and programmes of the tv guide source
+func deleteGuideSource(tx *sql.Tx, sid int64) (err error) {
+
+	tables := make([]string, 0, len(guideTables))
+
+	for table := range guideTables {
+		tables = append(tables, table)
+	}
+
+	sort.Strings(tables)
+
+	// details first, then the channels and programmes themselves
+	for _, table := range tables {
+
+		switch {
+		case table == "channels" || table == "programme":
+			continue
+		case strings.HasPrefix(table, "channel_"):
+			_, err = tx.Exec(`DELETE FROM `+table+` WHERE cid IN (SELECT cid FROM channels WHERE sid = ?)`, sid)
+		default:
+			_, err = tx.Exec(`DELETE FROM `+table+` WHERE pid IN (SELECT pid FROM programme WHERE sid = ?)`, sid)
+		}
+
+		if err != nil {
+			return
+		}
+	}
+
+	for _, command := range [3]string{`DELETE FROM channels WHERE sid = ?`, `DELETE FROM programme WHERE sid = ?`,
+		cmdDeleteProgrammeLangStat} {
+
+		if _, err = tx.Exec(command, sid); err != nil {
+			return
+		}
+	}
+
+	return
+}
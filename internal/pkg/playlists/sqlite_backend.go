@@ -0,0 +1,76 @@
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package playlists
+
+import (
+	xmltv "go-tvguide/pkg/xmltv"
+)
+
+// sqliteBackend keeps the playlist and the tv guide in the sqlite database of the store
+type sqliteBackend struct {
+	playlist *Playlist
+	guide    *Guide
+}
+
+// Backend returns the backend keeping the playlist and the tv guide in the database of the store
+func (s *Store) Backend() IBackend {
+	return &sqliteBackend{playlist: s.Playlist(), guide: s.Guide()}
+}
+
+func (b *sqliteBackend) BeginPlaylist() error {
+	return b.playlist.begin(nil)
+}
+
+func (b *sqliteBackend) AppendPlaylistItem(item *PlaylistItem) error {
+	return b.playlist.appendItem(item)
+}
+
+func (b *sqliteBackend) EndPlaylist(failed error) error {
+	return b.playlist.end(failed)
+}
+
+func (b *sqliteBackend) Groups() ([]string, error) {
+	return b.playlist.Groups()
+}
+
+func (b *sqliteBackend) Channels(group string) ([]*PlaylistItem, error) {
+	return b.playlist.Channels(group)
+}
+
+func (b *sqliteBackend) BeginGuide() error {
+	return b.guide.begin(nil)
+}
+
+func (b *sqliteBackend) AppendChannel(c *xmltv.XMLTVChannel) error {
+	return b.guide.appendChannel(c)
+}
+
+func (b *sqliteBackend) AppendProgramme(p *xmltv.XMLTVProgramme) error {
+	return b.guide.appendProgramme(p)
+}
+
+func (b *sqliteBackend) EndGuide(failed error) error {
+	return b.guide.end(&ImportReport{}, failed)
+}
+
+func (b *sqliteBackend) ChannelGuideQuery(cid string, q *GuideQuery) ([]*Programme, error) {
+	return b.guide.ChannelGuideQuery(cid, q)
+}
+
+func (b *sqliteBackend) ProgrammeDescription(pid int, langs Languages) (*ProgrammeDescription, error) {
+	return b.guide.ProgrammeDescription(pid, langs)
+}
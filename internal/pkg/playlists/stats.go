@@ -0,0 +1,77 @@
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package playlists
+
+import (
+	"log/slog"
+	"time"
+)
+
+// ImportStats contains the counters of the tv guide reading
+type ImportStats struct {
+	// Bytes - the size of the tv guide data
+	Bytes int64 `json:"bytes"`
+	// Rows - the number of the rows inserted into the tables of the guide
+	Rows map[string]int64 `json:"rows"`
+	// Parse - the time of the parsing and inserting, Finish - the time of the indexing, the repair
+	// of the programmes and the commit
+	Parse  time.Duration `json:"parse_ns"`
+	Finish time.Duration `json:"finish_ns"`
+}
+
+// Total returns the total time of the reading
+func (s *ImportStats) Total() time.Duration {
+	return s.Parse + s.Finish
+}
+
+// PlaylistStats contains the counters of the playlist reading
+type PlaylistStats struct {
+	// Bytes - the size of the playlist data
+	Bytes int64 `json:"bytes"`
+	// Items - the number of the playlist items
+	Items int `json:"items"`
+	// Duration - the time of the reading
+	Duration time.Duration `json:"duration_ns"`
+	// Unchanged is set when the playlist has been read already with the same content
+	Unchanged bool `json:"unchanged"`
+}
+
+// discardLogger drops the records, it is the logger of the store by default
+var discardLogger = slog.New(slog.DiscardHandler)
+
+// logImport writes the counters of the tv guide reading to the log
+func logImport(log *slog.Logger, report *ImportReport, err error) {
+
+	if err != nil {
+		log.Error("tv guide reading failed", "error", err, "bytes", report.Stats.Bytes,
+			"duration", report.Stats.Total())
+		return
+	}
+
+	log.Info("tv guide read", "channels", report.Channels, "programmes", report.Programmes,
+		"skipped", report.Skipped, "filtered_channels", report.FilteredChannels,
+		"filtered_programmes", report.FilteredProgrammes, "repaired", report.Repaired,
+		"bytes", report.Stats.Bytes, "parse", report.Stats.Parse, "finish", report.Stats.Finish)
+
+	for table, rows := range report.Stats.Rows {
+		log.Debug("tv guide rows inserted", "table", table, "rows", rows)
+	}
+
+	for _, e := range report.Errors {
+		log.Debug("tv guide element skipped", "error", e)
+	}
+}
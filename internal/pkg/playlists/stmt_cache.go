@@ -0,0 +1,78 @@
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package playlists
+
+import (
+	"database/sql"
+	"sync"
+)
+
+// stmtCache keeps the prepared statements of the queries. The statements are prepared once
+// and are safe for concurrent use by the readers of the playlists and guides
+type stmtCache struct {
+	db    *sql.DB
+	mu    sync.Mutex
+	stmts map[string]*sql.Stmt
+}
+
+func newStmtCache(db *sql.DB) *stmtCache {
+	return &stmtCache{db: db, stmts: make(map[string]*sql.Stmt)}
+}
+
+// prepare returns the prepared statement of the query, the statement must not be closed
+func (c *stmtCache) prepare(query string) (stmt *sql.Stmt, err error) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if stmt = c.stmts[query]; stmt != nil {
+		return
+	}
+
+	if stmt, err = c.db.Prepare(query); err != nil {
+		return
+	}
+
+	c.stmts[query] = stmt
+
+	return
+}
+
+// query executes the prepared query that returns rows
+func (c *stmtCache) query(query string, args ...interface{}) (*sql.Rows, error) {
+
+	stmt, err := c.prepare(query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return stmt.Query(args...)
+}
+
+// close closes the prepared statements
+func (c *stmtCache) close() {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, stmt := range c.stmts {
+		stmt.Close()
+	}
+
+	c.stmts = make(map[string]*sql.Stmt)
+}
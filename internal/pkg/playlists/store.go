@@ -0,0 +1,156 @@
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package playlists
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// driverName - sqlite3 driver with the functions of the application
+const driverName = "sqlite3_tvguide"
+
+// ErrNoSQLite - the application is built without cgo, only the memory backend is available
+var ErrNoSQLite = errors.New("the sqlite database needs the build with cgo")
+
+// Options - options of the store
+type Options struct {
+	// MaxOpenConns - the maximum number of the open connections to the database, zero means no limit.
+	// The in-memory database always uses the single connection
+	MaxOpenConns int
+	// Logger - the log of the readings of the playlists and guides and of the database maintenance,
+	// nil means no logging
+	Logger *slog.Logger
+}
+
+// walParams - the file database is kept in the write-ahead log mode, the readers are not blocked
+// by the reading of the playlist or the guide then and the writer waits for the locks
+const walParams = "_journal_mode=WAL&_busy_timeout=5000"
+
+// Store - the database of playlists and tv guides. The store, its playlists and guides are safe
+// for concurrent use
+type Store struct {
+	db    *sql.DB
+	stmts *stmtCache
+	log   *slog.Logger
+}
+
+// Open opens the database of playlists and tv guides, the database structure is created or upgraded
+// if needed. The empty dsn means the default database of the build, the nil options mean the defaults
+func Open(dsn string, opts *Options) (s *Store, err error) {
+
+	if dsn == "" {
+		dsn = getPlaylistDatabaseName()
+	}
+
+	if dsn != ":memory:" {
+
+		separator := "?"
+
+		if strings.Contains(dsn, "?") {
+			separator = "&"
+		}
+
+		dsn += separator + walParams
+	}
+
+	db, err := openDB(dsn)
+
+	if err != nil {
+		return
+	}
+
+	if opts != nil && opts.MaxOpenConns > 0 && dsn != ":memory:" {
+		db.SetMaxOpenConns(opts.MaxOpenConns)
+	}
+
+	if err = migrate(db); err == nil {
+		err = createSearchIndex(db)
+	}
+
+	if err != nil {
+		db.Close()
+		return
+	}
+
+	log := discardLogger
+
+	if opts != nil && opts.Logger != nil {
+		log = opts.Logger
+	}
+
+	log.Debug("database opened", "dsn", dsn)
+
+	return &Store{db: db, stmts: newStmtCache(db), log: log}, nil
+}
+
+// openDB opens the database with the functions of the application
+func openDB(dsn string) (db *sql.DB, err error) {
+
+	if err = registerDriver(); err != nil {
+		return
+	}
+
+	if db, err = sql.Open(driverName, dsn); err != nil {
+		return
+	}
+
+	// every connection to the in-memory database opens a new empty database
+	if dsn == ":memory:" {
+		db.SetMaxOpenConns(1)
+	}
+
+	return
+}
+
+// Close closes the database. The playlists and guides of the store must not be used after that
+func (s *Store) Close() error {
+
+	s.stmts.close()
+	return s.db.Close()
+}
+
+// Playlist returns a new playlist stored in the database
+func (s *Store) Playlist() *Playlist {
+	return &Playlist{db: s.db, stmts: s.stmts, log: s.log}
+}
+
+// Guide returns a new tv guide stored in the database
+func (s *Store) Guide() *Guide {
+	return &Guide{db: s.db, stmts: s.stmts, log: s.log}
+}
+
+// Prune deletes the programmes ended more than the specified number of days ago and the sources
+// not loaded since then with their playlists, channels and programmes from the database. The database
+// is compacted if vacuum is set
+func (s *Store) Prune(days int, now time.Time, vacuum bool) (report *PruneReport, err error) {
+
+	started := time.Now()
+
+	if report, err = prune(s.db, now.AddDate(0, 0, -days), vacuum); err != nil {
+		s.log.Error("database pruning failed", "error", err)
+		return
+	}
+
+	s.log.Info("database pruned", "programmes", report.Programmes, "channels", report.Channels,
+		"vacuum", vacuum, "duration", time.Since(started))
+
+	return
+}
@@ -0,0 +1,52 @@
+//go:build cgo
+// +build cgo
+
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package playlists
+
+import (
+	"database/sql"
+	"sync"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+
+	strutils "go-tvguide/internal/pkg/strutils"
+)
+
+var driverOnce sync.Once
+
+// registerDriver registers the sqlite3 driver with the functions of the application
+func registerDriver() error {
+
+	driverOnce.Do(func() {
+
+		sql.Register(driverName, &sqlite3.SQLiteDriver{
+			ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+
+				// fold(s) - the string for the case and accent insensitive comparison
+				if err := conn.RegisterFunc("fold", strutils.Fold, true); err != nil {
+					return err
+				}
+
+				return conn.RegisterFunc("lang_rank", languageRank, true)
+			},
+		})
+	})
+
+	return nil
+}
@@ -0,0 +1,73 @@
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package playlists
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+
+	dir := t.TempDir()
+
+	open := func(name string) *Store {
+		return openTestStore(t, filepath.Join(dir, name), &Options{MaxOpenConns: 2})
+	}
+
+	first, second := open("first.db3"), open("second.db3")
+
+	defer second.Close()
+
+	if _, err := first.Playlist().Load("playlist.m3u", []byte(testNowNextPlaylist), &M3UPlaylistParser{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := second.Playlist().Load("other.m3u", []byte("#EXTM3U\n"), &M3UPlaylistParser{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := first.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the playlist is kept by the database of the first store only
+	first = open("first.db3")
+
+	defer first.Close()
+
+	p := first.Playlist()
+	unchanged, err := p.Load("playlist.m3u", []byte(testNowNextPlaylist), &M3UPlaylistParser{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count, err := p.GroupCount(); !unchanged || count != 2 {
+		t.Errorf("reopened store: unchanged = %v, groups = %d, %v, want true and 2", unchanged, count, err)
+	}
+
+	if _, err := second.Playlist().Load("playlist.m3u", []byte(testNowNextPlaylist), &M3UPlaylistParser{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var count int
+
+	if err = second.db.QueryRow(`SELECT COUNT(*) FROM sources`).Scan(&count); err != nil || count != 2 {
+		t.Errorf("second store sources = %d, %v, want 2", count, err)
+	}
+}
@@ -0,0 +1,97 @@
+PRAGMA foreign_keys=OFF;
+BEGIN TRANSACTION;
+CREATE TABLE playlist(id TEXT, channels_group TEXT, channel TEXT, source TEXT);
+INSERT INTO playlist VALUES('One','News','One','http://example.com/1');
+CREATE TABLE channels(cid INTEGER, channel_id TEXT);
+INSERT INTO channels VALUES(1,'1');
+CREATE TABLE channel_display_names(cid INTEGER, lang TEXT, display_name TEXT);
+INSERT INTO channel_display_names VALUES(1,'ru','One');
+CREATE TABLE channel_urls(cid INTEGER, url TEXT);
+CREATE TABLE programme (
+	pid INTEGER,
+	channel_id TEXT,
+	start DATETIME,
+	stop DATETIME,
+	pdc_start TEXT,
+	vps_start TEXT,
+	show_view TEXT,
+	video_plus TEXT,
+	clump_idx TEXT		
+	);
+INSERT INTO programme VALUES(1,'1','2018-10-27 03:00:00+03:00','2018-10-27 04:00:00+03:00','','','','','');
+CREATE TABLE programme_titles(pid INTEGER, lang TEXT, title TEXT);
+INSERT INTO programme_titles VALUES(1,'ru','News');
+CREATE TABLE programme_sub_titles(pid INTEGER, lang TEXT, sub_title TEXT);
+CREATE TABLE programme_desc(pid INTEGER, lang TEXT, desc TEXT);
+CREATE TABLE programme_dates(pid INTEGER, date TEXT);
+CREATE TABLE programme_categories(pid INTEGER, lang TEXT, category TEXT);
+CREATE TABLE programme_keywords(pid INTEGER, lang TEXT, keyword TEXT);
+CREATE TABLE programme_languages(pid INTEGER, lang TEXT, language TEXT);
+CREATE TABLE programme_original_languages(pid INTEGER, lang TEXT, language TEXT);
+CREATE TABLE programme_countries(pid INTEGER, lang TEXT, country TEXT);
+CREATE TABLE programme_directors(pid INTEGER, director TEXT);
+CREATE TABLE programme_writers(pid INTEGER, writer TEXT);
+CREATE TABLE programme_adapters(pid INTEGER, adapter TEXT);
+CREATE TABLE programme_producers(pid INTEGER, producer TEXT);
+CREATE TABLE programme_composers(pid INTEGER, composer TEXT);
+CREATE TABLE programme_editors(pid INTEGER, editor TEXT);
+CREATE TABLE programme_presenters(pid INTEGER, presenter TEXT);
+CREATE TABLE programme_commentators(pid INTEGER, commentator TEXT);
+CREATE TABLE programme_guests(pid INTEGER, guest TEXT);
+CREATE TABLE programme_actors(pid INTEGER, actor TEXT, role TEXT);
+CREATE TABLE programme_length(pid INTEGER, value TEXT, units TEXT);
+CREATE TABLE programme_icon(pid INTEGER, src TEXT, width TEXT, height TEXT);
+CREATE TABLE programme_episode_num(pid INTEGER, system TEXT, episode_num TEXT);
+CREATE TABLE programme_video(pid INTEGER, present TEXT, colour TEXT, aspect TEXT, quality TEXT);
+CREATE TABLE programme_audio(pid INTEGER, present TEXT, stereo TEXT);
+CREATE TABLE programme_previously_shown(pid INTEGER, start TEXT, channel TEXT);
+CREATE TABLE programme_premiere(pid INTEGER, lang TEXT, premiere TEXT);
+CREATE TABLE programme_last_chance(pid INTEGER, lang TEXT, last_chance TEXT);
+CREATE TABLE programme_subtitles(pid INTEGER, type TEXT, lang TEXT, language TEXT);
+CREATE TABLE programme_rating(pid INTEGER, system TEXT, value TEXT, src TEXT, width TEXT, height TEXT);
+CREATE TABLE programme_star_rating(pid INTEGER, system TEXT, value TEXT, src TEXT, width TEXT, height TEXT);
+CREATE TABLE programme_review(pid INTEGER, type TEXT, source TEXT, reviewer TEXT, lang TEXT, value TEXT);
+CREATE TABLE programme_lang_stat(lang TEXT, lang_count INTEGER);
+INSERT INTO programme_lang_stat VALUES('ru',1);
+CREATE INDEX ix_playlist_channel_id ON playlist(id);
+CREATE INDEX ix_channels_cid ON channels(cid);
+CREATE INDEX ix_channels_channel_id ON channels(channel_id);
+CREATE INDEX ix_channel_display_names_cid ON channel_display_names(cid);
+CREATE INDEX ix_channel_urls_cid ON channel_urls(cid);
+CREATE INDEX ix_programme_pid ON programme(pid);
+CREATE INDEX ix_programme_channel_id ON programme(channel_id);
+CREATE INDEX ix_programme_titles_pid ON programme_titles(pid);
+CREATE INDEX ix_programme_sub_titles_pid ON programme_sub_titles(pid);
+CREATE INDEX ix_programme_desc_pid ON programme_desc(pid);
+CREATE INDEX ix_programme_dates_pid ON programme_dates(pid);
+CREATE INDEX ix_programme_categories_pid ON programme_categories(pid);
+CREATE INDEX ix_programme_keywords_pid ON programme_keywords(pid);
+CREATE INDEX ix_programme_languages_pid ON programme_languages(pid);
+CREATE INDEX ix_programme_original_languages_pid ON programme_original_languages(pid);
+CREATE INDEX ix_programme_countries_pid ON programme_countries(pid);
+CREATE INDEX ix_programme_directors_pid ON programme_directors(pid);
+CREATE INDEX ix_programme_writers_pid ON programme_writers(pid);
+CREATE INDEX ix_programme_adapters_pid ON programme_adapters(pid);
+CREATE INDEX ix_programme_producers_pid ON programme_producers(pid);
+CREATE INDEX ix_programme_composers_pid ON programme_composers(pid);
+CREATE INDEX ix_programme_editors_pid ON programme_editors(pid);
+CREATE INDEX ix_programme_presenters_pid ON programme_presenters(pid);
+CREATE INDEX ix_programme_commentators_pid ON programme_commentators(pid);
+CREATE INDEX ix_programme_guests_pid ON programme_guests(pid);
+CREATE INDEX ix_programme_actors_pid ON programme_actors(pid);
+CREATE INDEX ix_programme_length_pid ON programme_length(pid);
+CREATE INDEX ix_programme_icon_pid ON programme_icon(pid);
+CREATE INDEX ix_programme_episode_num_pid ON programme_episode_num(pid);
+CREATE INDEX ix_programme_video_pid ON programme_video(pid);
+CREATE INDEX ix_programme_audio_pid ON programme_audio(pid);
+CREATE INDEX ix_programme_previously_shown_pid ON programme_previously_shown(pid);
+CREATE INDEX ix_programme_premiere_pid ON programme_premiere(pid);
+CREATE INDEX ix_programme_last_chance_pid ON programme_last_chance(pid);
+CREATE INDEX ix_programme_subtitles_pid ON programme_subtitles(pid);
+CREATE INDEX ix_programme_subtitles_type ON programme_subtitles(type, pid);
+CREATE INDEX ix_programme_rating_pid ON programme_rating(pid);
+CREATE INDEX ix_programme_rating_system ON programme_rating(system, pid);
+CREATE INDEX ix_programme_star_rating_pid ON programme_star_rating(pid);
+CREATE INDEX ix_programme_star_rating_system ON programme_star_rating(system, pid);
+CREATE INDEX ix_programme_review_pid ON programme_review(pid);
+COMMIT;
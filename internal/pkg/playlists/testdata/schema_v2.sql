@@ -0,0 +1,63 @@
+PRAGMA foreign_keys=OFF;
+BEGIN TRANSACTION;
+CREATE TABLE sources(sid INTEGER PRIMARY KEY, kind TEXT, url TEXT, hash TEXT, filter TEXT, guide TEXT, loaded DATETIME);
+INSERT INTO sources VALUES(1,'playlist','/tmp/pl.m3u','hash','','http://example.com/guide.xml','2018-10-27 00:00:00');
+INSERT INTO sources VALUES(2,'guide','http://example.com/guide.xml','hash','','','2018-10-27 00:00:00');
+CREATE TABLE playlist(sid INTEGER, id TEXT, channels_group TEXT, channel TEXT, source TEXT);
+INSERT INTO playlist VALUES(1,'One','News','One','http://example.com/1');
+CREATE TABLE channels(cid INTEGER, sid INTEGER, channel_id TEXT);
+INSERT INTO channels VALUES(1,2,'1');
+CREATE TABLE channel_display_names(cid INTEGER, lang TEXT, display_name TEXT);
+INSERT INTO channel_display_names VALUES(1,'ru','One');
+CREATE TABLE channel_urls(cid INTEGER, url TEXT);
+CREATE TABLE programme (
+	pid INTEGER,
+	sid INTEGER,
+	channel_id TEXT,
+	start DATETIME,
+	stop DATETIME,
+	pdc_start TEXT,
+	vps_start TEXT,
+	show_view TEXT,
+	video_plus TEXT,
+	clump_idx TEXT		
+	);
+INSERT INTO programme VALUES(1,2,'1','2018-10-27 03:00:00+03:00','2018-10-27 04:00:00+03:00','','','','','');
+CREATE TABLE programme_titles(pid INTEGER, lang TEXT, title TEXT);
+INSERT INTO programme_titles VALUES(1,'ru','News');
+CREATE TABLE programme_sub_titles(pid INTEGER, lang TEXT, sub_title TEXT);
+CREATE TABLE programme_desc(pid INTEGER, lang TEXT, desc TEXT);
+CREATE TABLE programme_dates(pid INTEGER, date TEXT);
+CREATE TABLE programme_categories(pid INTEGER, lang TEXT, category TEXT);
+CREATE TABLE programme_keywords(pid INTEGER, lang TEXT, keyword TEXT);
+CREATE TABLE programme_languages(pid INTEGER, lang TEXT, language TEXT);
+CREATE TABLE programme_original_languages(pid INTEGER, lang TEXT, language TEXT);
+CREATE TABLE programme_countries(pid INTEGER, lang TEXT, country TEXT);
+CREATE TABLE programme_directors(pid INTEGER, director TEXT);
+CREATE TABLE programme_writers(pid INTEGER, writer TEXT);
+CREATE TABLE programme_adapters(pid INTEGER, adapter TEXT);
+CREATE TABLE programme_producers(pid INTEGER, producer TEXT);
+CREATE TABLE programme_composers(pid INTEGER, composer TEXT);
+CREATE TABLE programme_editors(pid INTEGER, editor TEXT);
+CREATE TABLE programme_presenters(pid INTEGER, presenter TEXT);
+CREATE TABLE programme_commentators(pid INTEGER, commentator TEXT);
+CREATE TABLE programme_guests(pid INTEGER, guest TEXT);
+CREATE TABLE programme_actors(pid INTEGER, actor TEXT, role TEXT);
+CREATE TABLE programme_length(pid INTEGER, value TEXT, units TEXT);
+CREATE TABLE programme_icon(pid INTEGER, src TEXT, width TEXT, height TEXT);
+CREATE TABLE programme_episode_num(pid INTEGER, system TEXT, episode_num TEXT);
+CREATE TABLE programme_video(pid INTEGER, present TEXT, colour TEXT, aspect TEXT, quality TEXT);
+CREATE TABLE programme_audio(pid INTEGER, present TEXT, stereo TEXT);
+CREATE TABLE programme_previously_shown(pid INTEGER, start TEXT, channel TEXT);
+CREATE TABLE programme_premiere(pid INTEGER, lang TEXT, premiere TEXT);
+CREATE TABLE programme_last_chance(pid INTEGER, lang TEXT, last_chance TEXT);
+CREATE TABLE programme_subtitles(pid INTEGER, type TEXT, lang TEXT, language TEXT);
+CREATE TABLE programme_rating(pid INTEGER, system TEXT, value TEXT, src TEXT, width TEXT, height TEXT);
+CREATE TABLE programme_star_rating(pid INTEGER, system TEXT, value TEXT, src TEXT, width TEXT, height TEXT);
+CREATE TABLE programme_review(pid INTEGER, type TEXT, source TEXT, reviewer TEXT, lang TEXT, value TEXT);
+CREATE TABLE programme_lang_stat(sid INTEGER, lang TEXT, lang_count INTEGER);
+INSERT INTO programme_lang_stat VALUES(2,'ru',1);
+CREATE UNIQUE INDEX ix_sources_url ON sources(kind, url);
+CREATE INDEX ix_playlist_channel_id ON playlist(id);
+CREATE INDEX ix_playlist_sid ON playlist(sid);
+COMMIT;
@@ -0,0 +1,77 @@
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package strutils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// letters with diacritics and their base letters, the lower case only
+const (
+	foldFrom = "àáâãäåçèéêëìíîïñòóôõöùúûüýÿāăąćĉċčďēĕėęěĝğġģĥĩīĭįĵķĺļľńņňōŏőŕŗřśŝşšţťũūŭůűųŵŷźżžơưǎǐǒǔǖǘǚǜǟǡǧǩǫǭǰǵǹǻȁȃȅȇȉȋȍȏȑȓȕȗșțȟȧȩȫȭȯȱȳё"
+	foldTo   = "aaaaaaceeeeiiiinooooouuuuyyaaaccccdeeeeegggghiiiijklllnnnooorrrssssttuuuuuuwyzzzouaiouuuuuaagkoojgnaaaeeiioorruusthaeooooyе"
+)
+
+// foldSpecial - letters without canonical decomposition
+var foldSpecial = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'ł': "l", 'đ': "d", 'ð': "d", 'þ': "th", 'ı': "i",
+}
+
+var foldLetters = func() map[rune]rune {
+
+	from, to := []rune(foldFrom), []rune(foldTo)
+	letters := make(map[rune]rune, len(from))
+
+	for i, r := range from {
+		letters[r] = to[i]
+	}
+
+	return letters
+}()
+
+// Fold returns the string in lower case without diacritics, so the strings
+// can be compared case and accent insensitive
+func Fold(s string) string {
+
+	var sb strings.Builder
+
+	sb.Grow(len(s))
+
+	for _, r := range s {
+
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+
+		r = unicode.ToLower(r)
+
+		if f, ok := foldLetters[r]; ok {
+			sb.WriteRune(f)
+			continue
+		}
+
+		if f, ok := foldSpecial[r]; ok {
+			sb.WriteString(f)
+			continue
+		}
+
+		sb.WriteRune(r)
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,41 @@
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package strutils
+
+import "testing"
+
+func TestFold(t *testing.T) {
+
+	var tests = []struct {
+		input string
+		want  string
+	}{
+		{"José Mourinho", "jose mourinho"},
+		{"ZINEDINE ZIDANE", "zinedine zidane"},
+		{"Antonín Dvořák", "antonin dvorak"},
+		{"Łukasz Øster-Straße", "lukasz oster-strasse"},
+		{"Jose\u0301", "jose"},
+		{"Алёна Ёлкина", "алена елкина"},
+		{"", ""},
+	}
+
+	for _, test := range tests {
+		if got := Fold(test.input); got != test.want {
+			t.Errorf("Fold(%q) = %q, want %q", test.input, got, test.want)
+		}
+	}
+}
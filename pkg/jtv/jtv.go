@@ -0,0 +1,331 @@
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package jtv
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	xmltv "go-tvguide/pkg/xmltv"
+)
+
+// Description of JTV guide format.
+// The guide is a zip archive of file pairs per channel, the name of the files is the channel name.
+// The .ndx file contains the number of programmes (uint16) followed by 12 bytes records:
+// 2 unused bytes, start time of the programme (FILETIME) and offset of the title in the .pdt file (uint16).
+// The .pdt file starts with pdtSignature, each title is stored as its length (uint16) and cp1251 text.
+// All numbers are little-endian
+
+const (
+	pdtSignature = "JTV 3.x TV Program Data"
+	ndxRecordLen = 12
+
+	// xmltvTimeLayout - the layout of the programme times passed to the events
+	xmltvTimeLayout = "20060102150405 -0700"
+
+	// filetimeUnixOffset - the number of 100-nanosecond intervals between 1601-01-01 and 1970-01-01
+	filetimeUnixOffset = 116444736000000000
+)
+
+var zipSignature = []byte("PK\x03\x04")
+
+// IsJTV reports whether the data looks like JTV guide
+func IsJTV(data []byte) bool {
+	return bytes.HasPrefix(data, zipSignature)
+}
+
+// Parser is parser of tv guide with JTV format
+type Parser struct {
+	xmltv.GuideEvents
+
+	// Strict mode stops parsing on the first malformed channel or programme.
+	// Otherwise malformed elements are skipped and reported through OnError
+	Strict bool
+
+	// Location is the time zone of the programme times. The local time zone is used by default
+	Location *time.Location
+}
+
+type channelFiles struct {
+	ndx *zip.File
+	pdt *zip.File
+}
+
+type record struct {
+	offset int64
+	start  time.Time
+	title  string
+}
+
+// Parse parses JTV guide data
+func (parser *Parser) Parse(data []byte) (err error) {
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+
+	if err != nil {
+		return fmt.Errorf("JTVParser: %v", err)
+	}
+
+	var names []string
+
+	files := make(map[string]*channelFiles)
+
+	for _, f := range zr.File {
+
+		ext := strings.ToLower(path.Ext(f.Name))
+
+		if ext != ".ndx" && ext != ".pdt" {
+			continue
+		}
+
+		name := channelName(f)
+
+		cf, ok := files[name]
+
+		if !ok {
+			cf = &channelFiles{}
+			files[name] = cf
+			names = append(names, name)
+		}
+
+		if ext == ".ndx" {
+			cf.ndx = f
+		} else {
+			cf.pdt = f
+		}
+	}
+
+	if parser.OnHead != nil {
+		if err = parser.OnHead(&xmltv.XMLTVHead{SourceInfoName: "JTV"}); err != nil {
+			return
+		}
+	}
+
+	index := 0
+
+	for i, name := range names {
+
+		records, err := readChannel(files[name], parser.location())
+
+		if err != nil {
+
+			if err = parser.doError(&xmltv.ElementError{Index: i + 1, Element: "channel", Channel: name, Err: err}); err != nil {
+				return err
+			}
+
+			if records == nil {
+				continue
+			}
+		}
+
+		if parser.OnChannel != nil {
+
+			ch := &xmltv.XMLTVChannel{ID: name, DisplayName: []xmltv.XMLTVChannelDisplayName{{Value: name}}}
+
+			if err = parser.OnChannel(ch); err != nil {
+				return err
+			}
+		}
+
+		for j, r := range records {
+
+			index++
+
+			if r.start.IsZero() {
+
+				if err = parser.doError(&xmltv.ElementError{Index: index, Offset: r.offset, Element: "programme",
+					Channel: name, Err: errors.New("invalid start time")}); err != nil {
+					return err
+				}
+
+				continue
+			}
+
+			start := r.start.Format(xmltvTimeLayout)
+			stop := ""
+
+			if j+1 < len(records) && records[j+1].start.After(r.start) {
+				stop = records[j+1].start.Format(xmltvTimeLayout)
+			}
+
+			if parser.Filter != nil && !parser.Filter(name, start, stop) {
+				continue
+			}
+
+			if parser.OnProgramme != nil {
+
+				p := &xmltv.XMLTVProgramme{Channel: name, Start: start, Stop: stop,
+					Title: []xmltv.XMLTVProgrammeTitle{{Value: r.title}}}
+
+				if err = parser.OnProgramme(p); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// doError returns the error in strict mode, otherwise fires OnError event
+func (parser *Parser) doError(e *xmltv.ElementError) error {
+
+	if parser.Strict {
+		return e
+	}
+
+	if parser.OnError != nil {
+		return parser.OnError(e)
+	}
+
+	return nil
+}
+
+func (parser *Parser) location() *time.Location {
+
+	if parser.Location != nil {
+		return parser.Location
+	}
+
+	return time.Local
+}
+
+// channelName returns the name of the file without extension. Names of the files
+// not marked as UTF-8 are decoded from cp866
+func channelName(f *zip.File) string {
+
+	name := path.Base(strings.Replace(f.Name, "\\", "/", -1))
+	name = strings.TrimSuffix(name, path.Ext(name))
+
+	if f.NonUTF8 || !utf8.ValidString(name) {
+		return decode([]byte(name), &cp866)
+	}
+
+	return name
+}
+
+// readChannel returns the programmes of the channel sorted by start time. The records read
+// before the error are returned with it
+func readChannel(cf *channelFiles, loc *time.Location) (records []*record, err error) {
+
+	if cf.ndx == nil || cf.pdt == nil {
+		return nil, errors.New("pair of .ndx and .pdt files is incomplete")
+	}
+
+	ndx, err := readFile(cf.ndx)
+
+	if err != nil {
+		return
+	}
+
+	pdt, err := readFile(cf.pdt)
+
+	if err != nil {
+		return
+	}
+
+	if !bytes.HasPrefix(pdt, []byte(pdtSignature)) {
+		return nil, fmt.Errorf("%s is not a JTV programme data file", cf.pdt.Name)
+	}
+
+	if len(ndx) < 2 {
+		return nil, fmt.Errorf("%w: %s is empty", xmltv.ErrTruncated, cf.ndx.Name)
+	}
+
+	count := int(binary.LittleEndian.Uint16(ndx))
+	records = make([]*record, 0, count)
+
+	for i := 0; i < count; i++ {
+
+		offset := 2 + i*ndxRecordLen
+
+		if offset+ndxRecordLen > len(ndx) {
+			err = fmt.Errorf("%w: %s contains %d of %d records", xmltv.ErrTruncated, cf.ndx.Name, i, count)
+			break
+		}
+
+		rec := ndx[offset : offset+ndxRecordLen]
+
+		title, ok := titleAt(pdt, int(binary.LittleEndian.Uint16(rec[10:])))
+
+		if !ok {
+			err = fmt.Errorf("%w: title #%d is out of %s", xmltv.ErrTruncated, i+1, cf.pdt.Name)
+			break
+		}
+
+		records = append(records, &record{offset: int64(offset),
+			start: timeOfFiletime(binary.LittleEndian.Uint64(rec[2:]), loc), title: title})
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].start.Before(records[j].start)
+	})
+
+	return
+}
+
+func readFile(f *zip.File) ([]byte, error) {
+
+	r, err := f.Open()
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer r.Close()
+
+	return ioutil.ReadAll(r)
+}
+
+// titleAt returns the title stored at the offset of .pdt file
+func titleAt(pdt []byte, offset int) (string, bool) {
+
+	if offset < len(pdtSignature) || offset+2 > len(pdt) {
+		return "", false
+	}
+
+	length := int(binary.LittleEndian.Uint16(pdt[offset:]))
+	offset += 2
+
+	if offset+length > len(pdt) {
+		return "", false
+	}
+
+	return strings.TrimSpace(decode(pdt[offset:offset+length], &cp1251)), true
+}
+
+// timeOfFiletime converts FILETIME to the time of the location. The zero time is returned for invalid values
+func timeOfFiletime(ft uint64, loc *time.Location) time.Time {
+
+	if ft <= filetimeUnixOffset || (ft-filetimeUnixOffset) > math.MaxInt64/100 {
+		return time.Time{}
+	}
+
+	t := time.Unix(0, int64(ft-filetimeUnixOffset)*100).UTC()
+
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+}
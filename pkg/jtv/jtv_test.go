@@ -0,0 +1,171 @@
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package jtv
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+
+	xmltv "go-tvguide/pkg/xmltv"
+)
+
+type testProgramme struct {
+	start time.Time
+	title []byte
+}
+
+func filetime(t time.Time) uint64 {
+	return uint64(t.UnixNano()/100) + filetimeUnixOffset
+}
+
+// testArchive returns JTV guide with the single channel. Name of the channel files is cp866 encoded
+func testArchive(t *testing.T, name string, programmes []testProgramme, brokenOffset bool) []byte {
+
+	var (
+		ndx, pdt bytes.Buffer
+		buf      bytes.Buffer
+	)
+
+	pdt.WriteString(pdtSignature + "\n\n\n")
+	binary.Write(&ndx, binary.LittleEndian, uint16(len(programmes)))
+
+	for _, p := range programmes {
+
+		offset := uint16(pdt.Len())
+
+		if brokenOffset {
+			offset = 0xfff0
+		}
+
+		binary.Write(&ndx, binary.LittleEndian, uint16(0))
+		binary.Write(&ndx, binary.LittleEndian, filetime(p.start))
+		binary.Write(&ndx, binary.LittleEndian, offset)
+
+		binary.Write(&pdt, binary.LittleEndian, uint16(len(p.title)))
+		pdt.Write(p.title)
+	}
+
+	zw := zip.NewWriter(&buf)
+
+	for ext, data := range map[string][]byte{".ndx": ndx.Bytes(), ".pdt": pdt.Bytes()} {
+
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: name + ext, NonUTF8: true, Method: zip.Deflate})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w.Write(data)
+	}
+
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestParse(t *testing.T) {
+
+	start := time.Date(2018, 10, 27, 6, 0, 0, 0, time.UTC)
+
+	data := testArchive(t, "\x8f\xa5\xe0\xa2\xeb\xa9", []testProgramme{
+		{start.Add(time.Hour), []byte("\xd4\xe8\xeb\xfc\xec \xab\xd2\xe5\xf1\xf2\xbb")},
+		{start, []byte("\xcd\xee\xe2\xee\xf1\xf2\xe8")},
+	}, false)
+
+	if !IsJTV(data) {
+		t.Fatal("IsJTV() = false")
+	}
+
+	var (
+		channels   []string
+		programmes []*xmltv.XMLTVProgramme
+	)
+
+	parser := &Parser{Strict: true, Location: time.UTC}
+	parser.OnChannel = func(ch *xmltv.XMLTVChannel) error {
+		channels = append(channels, ch.ID)
+		return nil
+	}
+	parser.OnProgramme = func(p *xmltv.XMLTVProgramme) error {
+		programmes = append(programmes, p)
+		return nil
+	}
+
+	if err := parser.Parse(data); err != nil {
+		t.Fatalf("Parse() = %v", err)
+	}
+
+	if len(channels) != 1 || channels[0] != "Первый" {
+		t.Errorf("Parse() read channels %q, want [Первый]", channels)
+	}
+
+	var tests = []struct {
+		title string
+		start string
+		stop  string
+	}{
+		{"Новости", "20181027060000 +0000", "20181027070000 +0000"},
+		{"Фильм «Тест»", "20181027070000 +0000", ""},
+	}
+
+	if len(programmes) != len(tests) {
+		t.Fatalf("Parse() read %d programmes, want %d", len(programmes), len(tests))
+	}
+
+	for i, test := range tests {
+
+		p := programmes[i]
+
+		if p.Channel != "Первый" || p.Title[0].Value != test.title || p.Start != test.start || p.Stop != test.stop {
+			t.Errorf("Parse() read %q %q %q-%q, want %q %q-%q", p.Channel, p.Title[0].Value, p.Start, p.Stop,
+				test.title, test.start, test.stop)
+		}
+	}
+}
+
+func TestParseBroken(t *testing.T) {
+
+	data := testArchive(t, "test", []testProgramme{{time.Now(), []byte("title")}}, true)
+
+	var errs []*xmltv.ElementError
+
+	parser := &Parser{}
+	parser.OnError = func(e *xmltv.ElementError) error {
+		errs = append(errs, e)
+		return nil
+	}
+
+	if err := parser.Parse(data); err != nil {
+		t.Fatalf("Parse() = %v", err)
+	}
+
+	if len(errs) != 1 || errs[0].Channel != "test" || !errors.Is(errs[0], xmltv.ErrTruncated) {
+		t.Errorf("Parse() reported %v", errs)
+	}
+
+	parser.Strict = true
+
+	if err := parser.Parse(data); err == nil {
+		t.Error("Parse() in strict mode = nil")
+	}
+}
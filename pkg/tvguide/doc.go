@@ -0,0 +1,35 @@
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+// Package tvguide reads IPTV playlists and their tv guides and queries them.
+//
+// The playlists and the tv guides are kept by the Store, the sqlite database opened by Open.
+// The content of the playlist or the guide is fetched by Fetch and read by LoadPlaylist and LoadGuide,
+// the stored content is reused while the source has not changed. The format of the content is detected
+// by its first SniffLen bytes, the new formats are added by RegisterPlaylistFormat and
+// RegisterGuideFormat. The channels of the guide are
+// matched with the playlist items by their display names, the GuideFilter keeps only the matched
+// channels and the programmes within the time window.
+//
+// The stored guide is queried by the Guide methods: ChannelGuideQuery returns the programmes of the
+// channel, ProgrammeDescription the details of the programme, NowNext the current and the next
+// programmes of all playlist channels, Search and ProgrammesByPerson find the programmes.
+//
+// The store, its playlists and guides and the memory backend are safe for concurrent use. The queries
+// see the content read earlier until the new one is read completely.
+//
+// The builds without cgo can use the memory backend returned by NewMemoryBackend instead of the store.
+package tvguide
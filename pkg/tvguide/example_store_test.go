@@ -0,0 +1,137 @@
+//go:build cgo
+// +build cgo
+
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package tvguide_test
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	tvguide "go-tvguide/pkg/tvguide"
+)
+
+func Example() {
+
+	store, err := tvguide.Open(":memory:", nil)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	defer store.Close()
+
+	playlist := store.Playlist()
+
+	if _, err = tvguide.LoadPlaylist(playlist, "playlist.m3u", []byte(examplePlaylist), ""); err != nil {
+		log.Fatal(err)
+	}
+
+	guide := store.Guide()
+	guide.SetPlaylist(playlist)
+
+	if _, err = tvguide.LoadGuide(guide, playlist.Guide(), guideData(), "", false, nil); err != nil {
+		log.Fatal(err)
+	}
+
+	groups, err := playlist.Groups()
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, group := range groups {
+
+		items, err := playlist.Channels(group)
+
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		for _, item := range items {
+
+			programmes, err := guide.ChannelGuideQuery(item.Name, &tvguide.GuideQuery{})
+
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			for _, p := range programmes {
+				fmt.Printf("%s: %s\n", item.Name, p.Title)
+			}
+		}
+	}
+
+	// Output:
+	// News: Evening news
+	// News: Weather
+	// Sport: Football
+}
+
+func ExampleGuideQuery() {
+
+	store, err := tvguide.Open(":memory:", nil)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	defer store.Close()
+
+	guide := store.Guide()
+
+	if _, err = tvguide.LoadGuide(guide, "guide.xml", guideData(), "", false, nil); err != nil {
+		log.Fatal(err)
+	}
+
+	// the category is matched regardless of the case and the accents
+	programmes, err := guide.ChannelGuideQuery("News", &tvguide.GuideQuery{
+		Category:  "meteo",
+		Languages: tvguide.ParseLanguages("de,en"),
+	})
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, p := range programmes {
+		fmt.Println(p.Title)
+	}
+
+	// Output:
+	// Weather
+}
+
+func ExampleLoadPlaylist() {
+
+	store, err := tvguide.Open(":memory:", nil)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	defer store.Close()
+
+	_, err = tvguide.LoadPlaylist(store.Playlist(), "playlist.txt", []byte("not a playlist"), "")
+
+	fmt.Println(errors.Is(err, tvguide.ErrUnknownFormat))
+
+	// Output:
+	// true
+}
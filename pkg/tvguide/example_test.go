@@ -0,0 +1,97 @@
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package tvguide_test
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	tvguide "go-tvguide/pkg/tvguide"
+)
+
+const examplePlaylist = `#EXTM3U url-tvg="guide.xml"
+#EXTINF:-1 tvg-name="News" group-title="News",News
+http://example.com/news
+#EXTINF:-1 tvg-name="Sport" group-title="Sport",Sport
+http://example.com/sport
+`
+
+// the year of the guide is replaced by the next one, the stop times of the past years are not kept
+const exampleGuide = `<?xml version="1.0" encoding="UTF-8"?>
+<tv>
+<channel id="1"><display-name lang="en">News</display-name></channel>
+<channel id="2"><display-name lang="en">Sport</display-name></channel>
+<programme start="YYYY1027180000 +0000" stop="YYYY1027190000 +0000" channel="1">
+<title lang="en">Evening news</title><title lang="de">Abendnachrichten</title><category lang="en">News</category>
+</programme>
+<programme start="YYYY1027190000 +0000" stop="YYYY1027200000 +0000" channel="1">
+<title lang="en">Weather</title><category lang="en">Météo</category>
+</programme>
+<programme start="YYYY1027180000 +0000" stop="YYYY1027200000 +0000" channel="2"><title lang="en">Football</title></programme>
+</tv>`
+
+func guideData() []byte {
+	return []byte(strings.Replace(exampleGuide, "YYYY", fmt.Sprint(time.Now().Year()+1), -1))
+}
+
+func ExampleParseLanguages() {
+
+	langs := tvguide.ParseLanguages(`de, en,""`)
+
+	fmt.Println(len(langs), langs)
+
+	// Output:
+	// 3 de,en,""
+}
+
+func ExampleNewMemoryBackend() {
+
+	b := tvguide.NewMemoryBackend()
+
+	if err := tvguide.ReadPlaylist(b, []byte(examplePlaylist), &tvguide.M3UPlaylistParser{}); err != nil {
+		log.Fatal(err)
+	}
+
+	if _, err := tvguide.ReadGuide(b, guideData(), tvguide.GuideParser(guideData(), true, 1)); err != nil {
+		log.Fatal(err)
+	}
+
+	groups, err := b.Groups()
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(groups)
+
+	programmes, err := b.ChannelGuideQuery("News", &tvguide.GuideQuery{Languages: tvguide.Languages{"de"}})
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, p := range programmes {
+		fmt.Println(p.Title)
+	}
+
+	// Output:
+	// [News Sport]
+	// Abendnachrichten
+	// Weather
+}
@@ -0,0 +1,112 @@
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package tvguide
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	loaders "go-tvguide/internal/pkg/loaders"
+)
+
+// ErrInvalidPath - the path is neither the existing file nor the URL
+var ErrInvalidPath = loaders.ErrInvalidPath
+
+// HTTPStatusError - the server has responded with the status other than 200 OK
+type HTTPStatusError = loaders.HTTPStatusError
+
+// ILoader - common interface of the playlist and the tv guide loaders
+type ILoader = loaders.ILoader
+
+// LoadStats contains the counters of the loading
+type LoadStats = loaders.LoadStats
+
+// FileLoader - the loader of the playlist or the tv guide from the file
+type FileLoader = loaders.FileLoader
+
+// HTTPLoader - the loader of the playlist or the tv guide from the remote server. The progress
+// of the download is reported through its events
+type HTTPLoader = loaders.HTTPLoader
+
+// Loader returns the loader for the path of the playlist or the tv guide, nil if the path is
+// neither the existing file nor the URL
+func Loader(path string) ILoader {
+	return loaders.Loader(path)
+}
+
+// Fetch returns the data of the playlist or the tv guide from the file or the URL
+func Fetch(path string) ([]byte, error) {
+
+	loader := Loader(path)
+
+	if loader == nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPath, path)
+	}
+
+	return loader.Load(path)
+}
+
+// SourceURL returns the address identifying the playlist or the guide in the database
+func SourceURL(path string) string {
+
+	if _, err := os.Stat(path); err == nil {
+		if abs, err := filepath.Abs(path); err == nil {
+			return abs
+		}
+	}
+
+	return path
+}
+
+// LoadPlaylist stores the playlist data fetched from the path. The format of the data is detected
+// if it is not specified, ErrUnknownFormat is returned if it is not supported. Unchanged is set if
+// the same playlist is already stored
+func LoadPlaylist(p *Playlist, path string, data []byte, format string) (unchanged bool, err error) {
+
+	if format == "" {
+		format = PlaylistFormatOf(data)
+	}
+
+	parser, err := PlaylistParserOf(format)
+
+	if err != nil {
+		return
+	}
+
+	return p.Load(SourceURL(path), data, parser)
+}
+
+// LoadGuide stores the tv guide data fetched from the path. The format of the data (XMLTV, JTV or
+// another registered one) is detected if it is not specified, ErrUnknownFormat is returned if it is
+// not supported. Strict stops the reading at the first malformed element, the nil filter keeps
+// the whole guide
+func LoadGuide(g *Guide, path string, data []byte, format string, strict bool, filter *GuideFilter) (*ImportReport, error) {
+
+	if format == "" {
+		format = GuideFormatOf(data)
+	}
+
+	parser, err := GuideParserOf(format, strict, runtime.NumCPU())
+
+	if err != nil {
+		return nil, err
+	}
+
+	return g.Load(SourceURL(path), data, parser, filter)
+}
@@ -0,0 +1,257 @@
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package tvguide
+
+import (
+	"io"
+	"time"
+
+	pl "go-tvguide/internal/pkg/playlists"
+)
+
+// Store - the database of playlists and tv guides
+type Store = pl.Store
+
+// Options - options of the store
+type Options = pl.Options
+
+// Open opens the database of playlists and tv guides, the database structure is created or upgraded
+// if needed. The empty dsn means the default database of the build, the nil options mean the defaults
+func Open(dsn string, opts *Options) (*Store, error) {
+	return pl.Open(dsn, opts)
+}
+
+// Playlist - the playlist of the store
+type Playlist = pl.Playlist
+
+// PlaylistItem contains info about tv channel (URL, name, etc)
+type PlaylistItem = pl.PlaylistItem
+
+// IPlaylistParser - common playlist parser interface
+type IPlaylistParser = pl.IPlaylistParser
+
+// M3UPlaylistParser - parser for m3u playlist format
+type M3UPlaylistParser = pl.M3UPlaylistParser
+
+// PlaylistParser returns the parser for the format of the playlist data or nil if the format is unknown
+func PlaylistParser(data []byte) IPlaylistParser {
+	return pl.PlaylistParser(data)
+}
+
+// PlaylistFormat describes the playlist format: the detection of the format by the first bytes
+// of the data and the constructor of its parser
+type PlaylistFormat = pl.PlaylistFormat
+
+// RegisterPlaylistFormat adds the playlist format to the detected ones, the formats are detected in
+// the order of registration. The format registered earlier with the same name is replaced
+func RegisterPlaylistFormat(f *PlaylistFormat) {
+	pl.RegisterPlaylistFormat(f)
+}
+
+// PlaylistFormats returns the names of the registered playlist formats
+func PlaylistFormats() []string {
+	return pl.PlaylistFormats()
+}
+
+// PlaylistFormatOf returns the name of the playlist format detected by the first bytes of the data,
+// empty if the format is unknown
+func PlaylistFormatOf(data []byte) string {
+	return pl.PlaylistFormatOf(data)
+}
+
+// PlaylistParserOf returns the parser of the playlist format with the name, ErrUnknownFormat
+// if the format is not registered
+func PlaylistParserOf(name string) (IPlaylistParser, error) {
+	return pl.PlaylistParserOf(name)
+}
+
+// Guide - the tv guide of the store
+type Guide = pl.Guide
+
+// IGuideParser - common tv guide parser interface
+type IGuideParser = pl.IGuideParser
+
+// GuideParser returns parser for the format of the tv guide data, XMLTV or JTV, or nil if the format is unknown.
+// Workers is used by XMLTV parser only
+func GuideParser(data []byte, strict bool, workers int) IGuideParser {
+	return pl.GuideParser(data, strict, workers)
+}
+
+// GuideFormat describes the tv guide format: the detection of the format by the first bytes
+// of the data and the constructor of its parser
+type GuideFormat = pl.GuideFormat
+
+// RegisterGuideFormat adds the tv guide format to the detected ones, the formats are detected in
+// the order of registration. The format registered earlier with the same name is replaced
+func RegisterGuideFormat(f *GuideFormat) {
+	pl.RegisterGuideFormat(f)
+}
+
+// GuideFormats returns the names of the registered tv guide formats
+func GuideFormats() []string {
+	return pl.GuideFormats()
+}
+
+// GuideFormatOf returns the name of the tv guide format detected by the first bytes of the data,
+// empty if the format is unknown
+func GuideFormatOf(data []byte) string {
+	return pl.GuideFormatOf(data)
+}
+
+// GuideParserOf returns the parser of the tv guide format with the name, ErrUnknownFormat
+// if the format is not registered
+func GuideParserOf(name string, strict bool, workers int) (IGuideParser, error) {
+	return pl.GuideParserOf(name, strict, workers)
+}
+
+// SniffLen - the number of the first bytes of the data the format is detected by
+const SniffLen = pl.SniffLen
+
+// Sniff returns the first bytes of the stream the format is detected by, the returned reader
+// reads the whole stream including them
+func Sniff(r io.Reader) (head []byte, stream io.Reader, err error) {
+	return pl.Sniff(r)
+}
+
+// ImportReport contains the result of the tv guide reading
+type ImportReport = pl.ImportReport
+
+// ImportStats contains the counters of the tv guide reading
+type ImportStats = pl.ImportStats
+
+// PlaylistStats contains the counters of the playlist reading
+type PlaylistStats = pl.PlaylistStats
+
+// GuideFilter restricts the content of the tv guide stored while reading to the channels matched
+// with the playlist items by their display names and to the programmes within the time window
+type GuideFilter = pl.GuideFilter
+
+// NewGuideFilter returns the filter for the playlist channels and the time window from
+// daysBack days before today to daysAhead days after today. Negative number of days means no limit
+func NewGuideFilter(p *Playlist, daysBack, daysAhead int, t time.Time) *GuideFilter {
+	return pl.NewGuideFilter(p, daysBack, daysAhead, t)
+}
+
+// GuideQuery - options of the channel guide query
+type GuideQuery = pl.GuideQuery
+
+// Languages - the languages of the texts in the order of preference
+type Languages = pl.Languages
+
+// ParseLanguages parses the comma separated list of the languages. The empty language may be
+// quoted, for example: ru,en,""
+func ParseLanguages(s string) Languages {
+	return pl.ParseLanguages(s)
+}
+
+// Programme contains info about tv programme
+type Programme = pl.Programme
+
+// ProgrammeDescription - description of the programme
+type ProgrammeDescription = pl.ProgrammeDescription
+
+// ProgrammeActor - actor
+type ProgrammeActor = pl.ProgrammeActor
+
+// ProgrammeRating - programme rating
+type ProgrammeRating = pl.ProgrammeRating
+
+// ChannelNowNext contains the current and the next programmes of the playlist channel
+type ChannelNowNext = pl.ChannelNowNext
+
+// SearchResult contains the programme found by the search
+type SearchResult = pl.SearchResult
+
+// PersonProgramme - the programme featuring the person
+type PersonProgramme = pl.PersonProgramme
+
+// CreditRoles - roles of the programme credits in the order of XMLTV
+var CreditRoles = pl.CreditRoles
+
+// GuideReport describes the coverage and the data quality of the tv guide
+type GuideReport = pl.GuideReport
+
+// ChannelReport describes the coverage and the data quality of the channel guide
+type ChannelReport = pl.ChannelReport
+
+// Interval - the time range
+type Interval = pl.Interval
+
+// ReportProgramme - the programme mentioned by the report
+type ReportProgramme = pl.ReportProgramme
+
+// RepairPolicy selects the fixes of the programmes applied after the tv guide is read
+type RepairPolicy = pl.RepairPolicy
+
+// OverlapPolicy - the repair of the overlapping programmes of the channel
+type OverlapPolicy = pl.OverlapPolicy
+
+// the repairs of the overlapping programmes
+const (
+	OverlapKeep  = pl.OverlapKeep
+	OverlapTrim  = pl.OverlapTrim
+	OverlapSplit = pl.OverlapSplit
+)
+
+// ParseOverlapPolicy returns the overlap policy by its name: keep, trim or split
+func ParseOverlapPolicy(s string) (OverlapPolicy, error) {
+	return pl.ParseOverlapPolicy(s)
+}
+
+// Repair describes the fix of the programme
+type Repair = pl.Repair
+
+// PruneReport contains the result of the database pruning
+type PruneReport = pl.PruneReport
+
+// IPlaylistBackend - common storage interface of the playlist
+type IPlaylistBackend = pl.IPlaylistBackend
+
+// IGuideBackend - common storage interface of the tv guide
+type IGuideBackend = pl.IGuideBackend
+
+// IBackend - common storage interface of the playlist and its tv guide
+type IBackend = pl.IBackend
+
+// NewMemoryBackend returns the empty backend keeping the playlist and the tv guide in memory
+func NewMemoryBackend() IBackend {
+	return pl.NewMemoryBackend()
+}
+
+// ReadPlaylist reads content of the playlist into the backend
+func ReadPlaylist(b IPlaylistBackend, data []byte, parser IPlaylistParser) error {
+	return pl.ReadPlaylist(b, data, parser)
+}
+
+// ReadGuide reads content of the tv guide into the backend
+func ReadGuide(b IGuideBackend, data []byte, parser IGuideParser) (*ImportReport, error) {
+	return pl.ReadGuide(b, data, parser)
+}
+
+// errors of the package
+var (
+	ErrUnknownFormat     = pl.ErrUnknownFormat
+	ErrNotFound          = pl.ErrNotFound
+	ErrInvalidArgument   = pl.ErrInvalidArgument
+	ErrNoPlaylist        = pl.ErrNoPlaylist
+	ErrSearchUnavailable = pl.ErrSearchUnavailable
+	ErrSchemaTooNew      = pl.ErrSchemaTooNew
+	ErrNoSQLite          = pl.ErrNoSQLite
+)
+
+// ParseError describes the position of the playlist or the tv guide data that cannot be parsed
+type ParseError = pl.ParseError
@@ -14,11 +14,17 @@
 // either express or implied. See the License for the specific
 // language governing permissions and limitations under the License.
 
-package playlists
+package xmltv
 
 import (
 	"bytes"
 	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
 )
 
 // Description of XMLTV guide format
@@ -88,6 +94,10 @@ type XMLTVProgramme struct {
 	Rating            []XMLTVProgrammeRating           `xml:"rating"`
 	StarRating        []XMLTVProgrammeStarRating       `xml:"star-rating"`
 	Review            []XMLTVProgrammeReview           `xml:"review"`
+
+	// parsed start and stop times
+	start, stop time.Time
+	timed       bool
 }
 
 // XMLTVProgrammeValue - the value of the element that contains it
@@ -269,6 +279,33 @@ type XMLTVProgrammeReview struct {
 	Value    string   `xml:",chardata"`
 }
 
+// ErrTruncated - the guide data ends unexpectedly
+var ErrTruncated = errors.New("XMLTVParser: unexpected end of the guide")
+
+// ElementError describes the element of the guide that cannot be read
+type ElementError struct {
+	Index   int
+	Offset  int64
+	Element string
+	Channel string
+	Err     error
+}
+
+// Error returns text of the error
+func (e *ElementError) Error() string {
+
+	if e.Channel != "" {
+		return fmt.Sprintf("XMLTVParser: %s #%d (channel %q, offset %d): %v", e.Element, e.Index, e.Channel, e.Offset, e.Err)
+	}
+
+	return fmt.Sprintf("XMLTVParser: %s #%d (offset %d): %v", e.Element, e.Index, e.Offset, e.Err)
+}
+
+// Unwrap returns the reason of the error
+func (e *ElementError) Unwrap() error {
+	return e.Err
+}
+
 // OnHeadEvent an event that fires when guide header is read
 type OnHeadEvent func(h *XMLTVHead) error
 
@@ -278,35 +315,107 @@ type OnChannelEvent func(ch *XMLTVChannel) error
 // OnProgrammeEvent an event that fires when programme info is read
 type OnProgrammeEvent func(p *XMLTVProgramme) error
 
-// XMLTVParser is parser of tv guide with xmltv format (github.com/xmltv)
-type XMLTVParser struct {
+// OnErrorEvent an event that fires when the parser skips a malformed element in lenient mode
+type OnErrorEvent func(e *ElementError) error
+
+// ProgrammeFilter decides by the attributes of the programme whether it should be read.
+// Rejected programmes are skipped without decoding
+type ProgrammeFilter func(channel, start, stop string) bool
+
+// GuideEvents - the events of tv guide parsers
+type GuideEvents struct {
 	OnHead      OnHeadEvent
 	OnChannel   OnChannelEvent
 	OnProgramme OnProgrammeEvent
+	OnError     OnErrorEvent
+
+	// Filter is called after all preceding channels have been passed to OnChannel
+	Filter ProgrammeFilter
+}
+
+// Events returns the events of the parser
+func (events *GuideEvents) Events() *GuideEvents {
+	return events
+}
+
+// XMLTVParser is parser of tv guide with xmltv format (github.com/xmltv)
+type XMLTVParser struct {
+	GuideEvents
+
+	// Strict mode stops parsing on the first malformed element or on truncated data.
+	// Otherwise malformed elements are skipped and reported through OnError
+	Strict bool
+
+	// Workers is the number of goroutines decoding channels and programmes.
+	// Values less than 2 mean the sequential decoding. The events fire on the goroutine
+	// that calls Parse in the order of the elements in the guide in both cases
+	Workers int
 }
 
-func (parser *XMLTVParser) doHead(h *XMLTVHead) error {
+// pendingFactor limits the number of elements read ahead of the event handlers per worker
+const pendingFactor = 16
+
+var errCanceled = errors.New("XMLTVParser: parsing is canceled")
+
+// element is the unit of the parsing pipeline
+type element struct {
+	seq       int
+	name      string
+	index     int
+	offset    int64
+	channel   string
+	data      []byte
+	head      *XMLTVHead
+	ch        *XMLTVChannel
+	programme *XMLTVProgramme
+	err       *ElementError
+}
 
-	if parser.OnHead != nil {
-		return parser.OnHead(h)
+func (events *GuideEvents) doHead(h *XMLTVHead) error {
+
+	if events.OnHead != nil {
+		return events.OnHead(h)
 	}
 
 	return nil
 }
 
-func (parser *XMLTVParser) doChannel(ch *XMLTVChannel) error {
+func (events *GuideEvents) doChannel(ch *XMLTVChannel) error {
 
-	if parser.OnChannel != nil {
-		return parser.OnChannel(ch)
+	if events.OnChannel != nil {
+		return events.OnChannel(ch)
 	}
 
 	return nil
 }
 
-func (parser *XMLTVParser) doProgramme(p *XMLTVProgramme) error {
+func (events *GuideEvents) doProgramme(p *XMLTVProgramme) error {
 
-	if parser.OnProgramme != nil {
-		return parser.OnProgramme(p)
+	if events.OnProgramme != nil {
+		return events.OnProgramme(p)
+	}
+
+	return nil
+}
+
+func (events *GuideEvents) accept(elem *xml.StartElement) bool {
+
+	if events.Filter != nil {
+		return events.Filter(attr(elem, "channel"), attr(elem, "start"), attr(elem, "stop"))
+	}
+
+	return true
+}
+
+// doError returns the error in strict mode, otherwise fires OnError event
+func (parser *XMLTVParser) doError(e *ElementError) error {
+
+	if parser.Strict {
+		return e
+	}
+
+	if parser.OnError != nil {
+		return parser.OnError(e)
 	}
 
 	return nil
@@ -315,76 +424,190 @@ func (parser *XMLTVParser) doProgramme(p *XMLTVProgramme) error {
 // Parse parses XMLTV guide data
 func (parser *XMLTVParser) Parse(data []byte) error {
 
-	err := parser.parseHeader(data)
+	if parser.Workers < 2 {
 
-	if err != nil {
-		return err
-	}
+		return parser.tokenize(data, func(e *element) error {
 
-	err = parser.parseTVListing(data)
+			parser.decode(e)
+			return parser.handle(e)
+		}, nil)
+	}
 
-	return err
+	return parser.parseParallel(data)
 }
 
-func (parser *XMLTVParser) parseHeader(data []byte) (err error) {
+// parseParallel runs the pipeline of the tokenizer goroutine, the pool of decoding workers
+// and the writer that fires the events in the original order on the calling goroutine
+func (parser *XMLTVParser) parseParallel(data []byte) (err error) {
 
-	r := bytes.NewReader(data)
+	var (
+		jobs    = make(chan *element, parser.Workers)
+		results = make(chan *element, parser.Workers)
+		slots   = make(chan struct{}, parser.Workers*pendingFactor)
+		done    = make(chan struct{})
 
-	decoder := xml.NewDecoder(r)
-	decoder.Strict = false
+		mu       sync.Mutex
+		cond     = sync.NewCond(&mu)
+		handled  = -1
+		stopped  bool
+		tokenErr error
+		wg       sync.WaitGroup
+	)
 
-	var ename string
-	var token xml.Token
+	// wait blocks the tokenizer until the writer handles the element with the specified sequence number
+	wait := func(seq int) bool {
 
-	for {
-		token, _ = decoder.Token()
+		mu.Lock()
+		defer mu.Unlock()
 
-		if token == nil {
-			return
+		for handled < seq && !stopped {
+			cond.Wait()
 		}
 
-		switch elem := token.(type) {
-		case xml.StartElement:
+		return !stopped
+	}
 
-			ename = elem.Name.Local
+	go func() {
 
-			if ename == "tv" {
+		defer close(jobs)
 
-				var h XMLTVHead
+		tokenErr = parser.tokenize(data, func(e *element) error {
 
-				err = decoder.DecodeElement(&h, &elem)
+			select {
+			case slots <- struct{}{}:
+			case <-done:
+				return errCanceled
+			}
 
-				if err != nil {
-					return
-				}
+			select {
+			case jobs <- e:
+				return nil
+			case <-done:
+				return errCanceled
+			}
+		}, wait)
+	}()
+
+	for i := 0; i < parser.Workers; i++ {
 
-				err = parser.doHead(&h)
+		wg.Add(1)
 
-				if err != nil {
+		go func() {
+
+			defer wg.Done()
+
+			for e := range jobs {
+
+				parser.decode(e)
+
+				select {
+				case results <- e:
+				case <-done:
 					return
 				}
 			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	pending := make(map[int]*element)
+	next := 0
+
+	for e := range results {
+
+		pending[e.seq] = e
+
+		for e, ok := pending[next]; ok && err == nil; e, ok = pending[next] {
+
+			delete(pending, next)
+			next++
+			<-slots
+
+			err = parser.handle(e)
+
+			if e.name == "channel" {
+				mu.Lock()
+				handled = e.seq
+				cond.Broadcast()
+				mu.Unlock()
+			}
+		}
 
-		default:
+		if err != nil {
+			break
 		}
 	}
+
+	close(done)
+
+	mu.Lock()
+	stopped = true
+	cond.Broadcast()
+	mu.Unlock()
+
+	for range results {
+	}
+
+	if err == nil && tokenErr != errCanceled {
+		err = tokenErr
+	}
+
+	return
 }
 
-func (parser *XMLTVParser) parseTVListing(data []byte) (err error) {
+// tokenize reads the guide and emits its elements undecoded. The emitting stops after
+// the first broken element. wait, if specified, is called before filtering of the programme
+// with the sequence number of the last channel
+func (parser *XMLTVParser) tokenize(data []byte, emit func(e *element) error, wait func(seq int) bool) error {
 
 	r := bytes.NewReader(data)
 
 	decoder := xml.NewDecoder(r)
 	decoder.Strict = false
 
-	var ename string
-	var token xml.Token
+	var (
+		index       = map[string]int{}
+		seq         int
+		lastChannel = -1
+		offset      int64
+		closed      bool
+		token       xml.Token
+		err         error
+		ename       string
+	)
+
+	newElement := func(name string) *element {
+
+		e := &element{seq: seq, name: name, index: index[name], offset: offset}
+		seq++
+
+		return e
+	}
 
 	for {
-		token, _ = decoder.Token()
+		offset = decoder.InputOffset()
+		token, err = decoder.Token()
 
-		if token == nil {
-			return
+		if err == io.EOF && (closed || len(data) == 0) {
+			return nil
+		}
+
+		if err != nil {
+
+			if err == io.EOF {
+				err = ErrTruncated
+			} else {
+				err = fmt.Errorf("%w: %v", ErrTruncated, err)
+			}
+
+			e := newElement("tv")
+			e.err = &ElementError{Index: index["channel"] + index["programme"], Offset: offset, Element: "tv", Err: err}
+
+			return emit(e)
 		}
 
 		switch elem := token.(type) {
@@ -394,41 +617,194 @@ func (parser *XMLTVParser) parseTVListing(data []byte) (err error) {
 
 			switch ename {
 
-			case "channel":
-
-				var c XMLTVChannel
+			case "tv":
 
-				err = decoder.DecodeElement(&c, &elem)
+				e := newElement(ename)
+				e.head = headOf(&elem)
 
-				if err != nil {
-					return
+				if err = emit(e); err != nil {
+					return err
 				}
 
-				err = parser.doChannel(&c)
+			case "channel", "programme":
 
-				if err != nil {
-					return
-				}
+				index[ename]++
 
-			case "programme":
+				if ename == "programme" && parser.Filter != nil {
 
-				var p XMLTVProgramme
+					if wait != nil && !wait(lastChannel) {
+						return errCanceled
+					}
 
-				err = decoder.DecodeElement(&p, &elem)
+					if !parser.accept(&elem) {
 
-				if err != nil {
-					return
+						if err = decoder.Skip(); err != nil {
+							return emit(parser.brokenElement(newElement(ename), &elem, err))
+						}
+
+						continue
+					}
 				}
 
-				err = parser.doProgramme(&p)
+				e := newElement(ename)
 
-				if err != nil {
-					return
+				if err = decoder.Skip(); err != nil {
+					return emit(parser.brokenElement(e, &elem, err))
+				}
+
+				e.data = data[offset:decoder.InputOffset()]
+
+				if ename == "programme" {
+					e.channel = attr(&elem, "channel")
+				} else {
+					lastChannel = e.seq
+				}
+
+				if err = emit(e); err != nil {
+					return err
 				}
+			}
+
+		case xml.EndElement:
 
+			if elem.Name.Local == "tv" {
+				closed = true
 			}
 
 		default:
 		}
 	}
 }
+
+// brokenElement marks the element that breaks the data stream, so the rest of the guide
+// is treated as truncated
+func (parser *XMLTVParser) brokenElement(e *element, elem *xml.StartElement, err error) *element {
+
+	if _, ok := err.(*xml.SyntaxError); ok {
+		err = fmt.Errorf("%w: %v", ErrTruncated, err)
+	}
+
+	e.err = &ElementError{Index: e.index, Offset: e.offset, Element: e.name, Channel: attr(elem, "channel"), Err: err}
+
+	return e
+}
+
+// decode decodes the data of the element, checks and normalizes it
+func (parser *XMLTVParser) decode(e *element) {
+
+	if e.data == nil {
+		return
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(e.data))
+	decoder.Strict = false
+
+	var err error
+
+	switch e.name {
+	case "channel":
+
+		var c XMLTVChannel
+
+		if err = decoder.Decode(&c); err == nil {
+			normalizeChannel(&c)
+			e.ch = &c
+		}
+
+	case "programme":
+
+		var p XMLTVProgramme
+
+		if err = decoder.Decode(&p); err == nil {
+			if err = validateProgramme(&p); err == nil {
+				normalizeProgramme(&p)
+				e.programme = &p
+			}
+		}
+	}
+
+	if err != nil {
+		e.err = &ElementError{Index: e.index, Offset: e.offset, Element: e.name, Channel: e.channel, Err: err}
+	}
+
+	e.data = nil
+}
+
+// handle fires the event of the decoded element
+func (parser *XMLTVParser) handle(e *element) error {
+
+	switch {
+	case e.err != nil:
+		return parser.doError(e.err)
+	case e.head != nil:
+		return parser.doHead(e.head)
+	case e.ch != nil:
+		return parser.doChannel(e.ch)
+	case e.programme != nil:
+		return parser.doProgramme(e.programme)
+	}
+
+	return nil
+}
+
+func headOf(elem *xml.StartElement) *XMLTVHead {
+
+	return &XMLTVHead{
+		XMLName:           elem.Name,
+		GeneratorInfoName: attr(elem, "generator-info-name"),
+		GeneratorInfoURL:  attr(elem, "generator-info-url"),
+		SourceInfoURL:     attr(elem, "source-info-url"),
+		SourceInfoName:    attr(elem, "source-info-name"),
+		SourceDataURL:     attr(elem, "source-data-url"),
+	}
+}
+
+func attr(elem *xml.StartElement, name string) string {
+
+	for _, a := range elem.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+
+	return ""
+}
+
+// validateProgramme checks that start and stop times of the programme can be parsed
+func validateProgramme(p *XMLTVProgramme) error {
+
+	_, _, err := p.Times()
+	return err
+}
+
+// normalizeChannel trims spaces around the channel id and names
+func normalizeChannel(c *XMLTVChannel) {
+
+	c.ID = strings.TrimSpace(c.ID)
+
+	for i := range c.DisplayName {
+		c.DisplayName[i].Value = strings.TrimSpace(c.DisplayName[i].Value)
+	}
+}
+
+// normalizeProgramme trims spaces around the channel id and the texts of the programme
+func normalizeProgramme(p *XMLTVProgramme) {
+
+	p.Channel = strings.TrimSpace(p.Channel)
+
+	for i := range p.Title {
+		p.Title[i].Value = strings.TrimSpace(p.Title[i].Value)
+	}
+
+	for i := range p.SubTitle {
+		p.SubTitle[i].Value = strings.TrimSpace(p.SubTitle[i].Value)
+	}
+
+	for i := range p.Desc {
+		p.Desc[i].Value = strings.TrimSpace(p.Desc[i].Value)
+	}
+
+	for i := range p.Categories {
+		p.Categories[i].Value = strings.TrimSpace(p.Categories[i].Value)
+	}
+}
@@ -0,0 +1,242 @@
+// IPTV guide viewer
+//
+// Copyright 2018 Vitaly Pelekhaty
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+
+package xmltv
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testGuide = `<?xml version="1.0" encoding="UTF-8"?>
+<tv generator-info-name="test">
+<channel id="1"><display-name lang="ru">One</display-name></channel>
+<programme start="20181027030000 +0300" stop="20181027040000 +0300" channel="1"><title lang="ru">First</title></programme>
+<programme start="yesterday" stop="20181027050000 +0300" channel="1"><title lang="ru">Broken</title></programme>
+<programme start="20181027050000 +0300" stop="20181027060000 +0300" channel="1"><title lang="ru">Third</title></programme>
+`
+
+type parseResult struct {
+	head       string
+	channels   int
+	programmes []string
+	errors     []*ElementError
+}
+
+func parse(parser *XMLTVParser, data string) (*parseResult, error) {
+
+	res := &parseResult{}
+
+	parser.OnHead = func(h *XMLTVHead) error {
+		res.head = h.GeneratorInfoName
+		return nil
+	}
+
+	onChannel := parser.OnChannel
+	parser.OnChannel = func(ch *XMLTVChannel) error {
+
+		res.channels++
+
+		if onChannel != nil {
+			return onChannel(ch)
+		}
+
+		return nil
+	}
+
+	parser.OnProgramme = func(p *XMLTVProgramme) error {
+		res.programmes = append(res.programmes, p.Title[0].Value)
+		return nil
+	}
+
+	parser.OnError = func(e *ElementError) error {
+		res.errors = append(res.errors, e)
+		return nil
+	}
+
+	return res, parser.Parse([]byte(data))
+}
+
+func TestParseLenient(t *testing.T) {
+
+	res, err := parse(&XMLTVParser{}, testGuide+"</tv>")
+
+	if err != nil {
+		t.Fatalf("Parse() = %v", err)
+	}
+
+	if res.head != "test" || res.channels != 1 || len(res.programmes) != 2 {
+		t.Errorf("Parse() = %q, %d channels, %d programmes", res.head, res.channels, len(res.programmes))
+	}
+
+	if len(res.errors) != 1 {
+		t.Fatalf("Parse() reported %d errors, want 1", len(res.errors))
+	}
+
+	e := res.errors[0]
+
+	if e.Element != "programme" || e.Index != 2 || e.Channel != "1" || errors.Is(e, ErrTruncated) {
+		t.Errorf("Parse() reported %v", e)
+	}
+}
+
+func TestParseLenientTruncated(t *testing.T) {
+
+	res, err := parse(&XMLTVParser{}, testGuide+`<programme start="2018`)
+
+	if err != nil {
+		t.Fatalf("Parse() = %v", err)
+	}
+
+	if len(res.programmes) != 2 {
+		t.Errorf("Parse() read %d programmes, want 2", len(res.programmes))
+	}
+
+	if len(res.errors) != 2 || !errors.Is(res.errors[1], ErrTruncated) {
+		t.Errorf("Parse() reported %v, want truncation", res.errors)
+	}
+}
+
+func TestParseLenientBroken(t *testing.T) {
+
+	res, err := parse(&XMLTVParser{}, testGuide+`<programme start="20181027060000 +0300" channel="1">`+
+		`<title lang="ru">Fourth</title><desc>a < b</desc></programme>`+
+		`<programme start="20181027070000 +0300" channel="1"><title lang="ru">Fifth</title></programme></tv>`)
+
+	if err != nil {
+		t.Fatalf("Parse() = %v", err)
+	}
+
+	if len(res.programmes) != 2 {
+		t.Errorf("Parse() read %d programmes, want 2", len(res.programmes))
+	}
+
+	if len(res.errors) != 2 || res.errors[1].Index != 4 || !errors.Is(res.errors[1], ErrTruncated) {
+		t.Errorf("Parse() reported %v, want the broken programme once", res.errors)
+	}
+}
+
+func TestParseStrict(t *testing.T) {
+
+	var tests = []struct {
+		input     string
+		truncated bool
+	}{
+		{testGuide + "</tv>", false},
+		{`<tv><programme start="20181027030000" channel="1"><title>First</title></programme>`, true},
+	}
+
+	for _, test := range tests {
+
+		_, err := parse(&XMLTVParser{Strict: true}, test.input)
+
+		var e *ElementError
+
+		if !errors.As(err, &e) {
+			t.Errorf("Parse() = %v, want *ElementError", err)
+			continue
+		}
+
+		if errors.Is(err, ErrTruncated) != test.truncated {
+			t.Errorf("Parse() = %v, truncated = %v", err, !test.truncated)
+		}
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+
+	parser := &XMLTVParser{}
+	parser.Filter = func(channel, start, stop string) bool {
+		return start != "20181027030000 +0300"
+	}
+
+	res, err := parse(parser, testGuide+"</tv>")
+
+	if err != nil {
+		t.Fatalf("Parse() = %v", err)
+	}
+
+	if len(res.programmes) != 1 || res.programmes[0] != "Third" {
+		t.Errorf("Parse() read %v, want [Third]", res.programmes)
+	}
+}
+
+func TestParseParallel(t *testing.T) {
+
+	var b strings.Builder
+
+	b.WriteString(`<tv generator-info-name="test">`)
+
+	for c := 0; c < 10; c++ {
+		fmt.Fprintf(&b, `<channel id="%d"><display-name> Channel %d </display-name></channel>`, c, c)
+	}
+
+	for i := 0; i < 1000; i++ {
+
+		start := fmt.Sprintf("20181027%02d0000 +0300", i%24)
+
+		if i%97 == 1 {
+			start = "later"
+		}
+
+		fmt.Fprintf(&b, `<programme start="%s" channel="%d"><title> Programme %d </title></programme>`, start, i%10, i)
+	}
+
+	var tests = []struct {
+		input  string
+		strict bool
+	}{
+		{b.String() + "</tv>", false},
+		{b.String() + `<programme start="2018`, false},
+		{b.String() + "</tv>", true},
+	}
+
+	for _, test := range tests {
+
+		var results [2]*parseResult
+		var errs [2]error
+
+		for i, workers := range [2]int{1, 8} {
+
+			channels := map[string]bool{}
+
+			parser := &XMLTVParser{Strict: test.strict, Workers: workers}
+			parser.Filter = func(channel, start, stop string) bool {
+				return channels[channel] && channel != "3"
+			}
+
+			parser.OnChannel = func(ch *XMLTVChannel) error {
+				channels[ch.ID] = true
+				return nil
+			}
+
+			results[i], errs[i] = parse(parser, test.input)
+		}
+
+		if results[0].programmes[0] != "Programme 0" {
+			t.Errorf("Parse() read %q, want %q", results[0].programmes[0], "Programme 0")
+		}
+
+		if !reflect.DeepEqual(results[0], results[1]) || fmt.Sprint(errs[0]) != fmt.Sprint(errs[1]) {
+			t.Errorf("Parse() with workers = %d programmes, %d errors (%v), sequentially = %d programmes, %d errors (%v)",
+				len(results[1].programmes), len(results[1].errors), errs[1],
+				len(results[0].programmes), len(results[0].errors), errs[0])
+		}
+	}
+}
@@ -14,7 +14,7 @@
 // either express or implied. See the License for the specific
 // language governing permissions and limitations under the License.
 
-package playlists
+package xmltv
 
 import (
 	"fmt"
@@ -46,3 +46,22 @@ func TimeOfProgramme(st string) (time.Time, error) {
 
 	return et, fmt.Errorf(`string "%q" parsing error`, st)
 }
+
+// Times returns parsed start and stop times of the programme
+func (p *XMLTVProgramme) Times() (start, stop time.Time, err error) {
+
+	if !p.timed {
+
+		if p.start, err = TimeOfProgramme(p.Start); err != nil {
+			return start, stop, fmt.Errorf("start time: %v", err)
+		}
+
+		if p.stop, err = TimeOfProgramme(p.Stop); err != nil {
+			return start, stop, fmt.Errorf("stop time: %v", err)
+		}
+
+		p.timed = true
+	}
+
+	return p.start, p.stop, nil
+}
@@ -14,7 +14,7 @@
 // either express or implied. See the License for the specific
 // language governing permissions and limitations under the License.
 
-package playlists
+package xmltv
 
 import (
 	"testing"